@@ -1,3 +1,9 @@
 module github.com/tullo/password
 
 go 1.15
+
+require (
+	github.com/BurntSushi/toml v1.3.2
+	golang.org/x/crypto v0.0.0-20211117183948-ae814b36b871
+	gopkg.in/yaml.v3 v3.0.1
+)
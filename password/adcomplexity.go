@@ -0,0 +1,208 @@
+package password
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ADSymbols are the characters Active Directory counts as non-alphanumeric.
+// Other symbols, such as currency signs, belong to no category.
+const ADSymbols = "~!@#$%^&*_-+=`|\\(){}[]:;\"'<>,.?/"
+
+// ADCategory is a set of Active Directory complexity categories.
+type ADCategory uint8
+
+// Active Directory complexity categories.
+const (
+	// ADUpper are uppercase letters, including those with diacritics,
+	// Greek and Cyrillic.
+	ADUpper ADCategory = 1 << iota
+
+	// ADLower are lowercase letters, including those with diacritics,
+	// Greek and Cyrillic.
+	ADLower
+
+	// ADDigit are the digits 0 through 9.
+	ADDigit
+
+	// ADSymbol are the characters of ADSymbols.
+	ADSymbol
+
+	// ADOtherLetter are letters without case, as in most Asian scripts.
+	ADOtherLetter
+)
+
+// ADComplexityMinCategories is the number of categories a complex password
+// must contain.
+const ADComplexityMinCategories = 3
+
+var adSymbols = NewCharset(ADSymbols)
+
+// displayNameDelimiters split a displayName into tokens.
+const displayNameDelimiters = ",.-_ #\t"
+
+// Count returns the number of categories in c.
+func (c ADCategory) Count() int {
+	n := 0
+	for ; c != 0; c &= c - 1 {
+		n++
+	}
+	return n
+}
+
+// ADCategories returns the complexity categories of the characters in
+// password. ASCII characters are classified with the package's default
+// character sets, other characters by their Unicode case.
+func ADCategories(password string) ADCategory {
+	var c ADCategory
+	for _, r := range password {
+		switch {
+		case defaultUpperLetters.Contains(r):
+			c |= ADUpper
+		case defaultLowerLetters.Contains(r):
+			c |= ADLower
+		case defaultDigits.Contains(r):
+			c |= ADDigit
+		case adSymbols.Contains(r):
+			c |= ADSymbol
+		case r < utf8.RuneSelf:
+		case unicode.IsUpper(r):
+			c |= ADUpper
+		case unicode.IsLower(r):
+			c |= ADLower
+		case unicode.IsLetter(r):
+			c |= ADOtherLetter
+		}
+	}
+	return c
+}
+
+// DisplayNameTokens splits displayName at commas, periods, dashes,
+// underscores, spaces, pound signs and tabs, and returns the tokens of three
+// or more characters, which a complex password must not contain.
+func DisplayNameTokens(displayName string) []string {
+	var tokens []string
+	for _, token := range strings.FieldsFunc(displayName, func(r rune) bool {
+		return strings.ContainsRune(displayNameDelimiters, r)
+	}) {
+		if utf8.RuneCountInString(token) >= 3 {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+// ADComplexityPolicy emulates the Active Directory "Password must meet
+// complexity requirements" setting for one account: a password must contain
+// characters of at least three of the five categories, and must contain
+// neither the sAMAccountName nor any displayName token, ignoring case. As in
+// Active Directory, names shorter than three characters are not checked.
+type ADComplexityPolicy struct {
+	SAMAccountName string
+	DisplayName    string
+
+	// MinLength is the minimum password length of the domain policy, which
+	// Active Directory enforces separately from complexity. Zero disables
+	// the check.
+	MinLength int
+}
+
+// Validate checks password against the policy and returns a
+// *ValidationError for the first violated rule: "min_length",
+// "categories", "sam_account_name" or "display_name". ADComplexityPolicy
+// implements Validator.
+func (p *ADComplexityPolicy) Validate(password string) error {
+	if utf8.RuneCountInString(password) < p.MinLength {
+		return &ValidationError{Rule: "min_length", Message: fmt.Sprintf("must be at least %d characters long", p.MinLength)}
+	}
+
+	if n := ADCategories(password).Count(); n < ADComplexityMinCategories {
+		return &ValidationError{
+			Rule:    "categories",
+			Message: fmt.Sprintf("contains %d of the %d required character categories", n, ADComplexityMinCategories),
+		}
+	}
+
+	lower := strings.ToLower(password)
+	if name := p.SAMAccountName; utf8.RuneCountInString(name) >= 3 && strings.Contains(lower, strings.ToLower(name)) {
+		return &ValidationError{Rule: "sam_account_name", Message: "contains the account name"}
+	}
+	for _, token := range DisplayNameTokens(p.DisplayName) {
+		if strings.Contains(lower, strings.ToLower(token)) {
+			return &ValidationError{Rule: "display_name", Message: "contains a part of the display name"}
+		}
+	}
+
+	return nil
+}
+
+// Policy returns a Policy whose passwords always pass the complexity check:
+// it requires an uppercase letter, a lowercase letter, a digit and a symbol
+// of ADSymbols, and blocks the names. The password length is the larger of
+// MinLength and DefaultPolicyLength.
+func (p *ADComplexityPolicy) Policy() *Policy {
+	length := DefaultPolicyLength
+	if p.MinLength > length {
+		length = p.MinLength
+	}
+
+	var blocklist []string
+	if utf8.RuneCountInString(p.SAMAccountName) >= 3 {
+		blocklist = append(blocklist, p.SAMAccountName)
+	}
+	blocklist = append(blocklist, DisplayNameTokens(p.DisplayName)...)
+
+	return &Policy{
+		Length:    length,
+		MinLength: p.MinLength,
+		Blocklist: blocklist,
+		Classes: []ClassRule{
+			{Name: ClassUpper, Min: 1},
+			{Name: ClassLower, Min: 1},
+			{Name: ClassDigit, Min: 1},
+			{Name: ClassSymbol, Chars: ADSymbols, Min: 1},
+		},
+	}
+}
+
+// ADComplexityGenerator generates passwords guaranteed to pass an
+// ADComplexityPolicy. It is safe for concurrent use.
+type ADComplexityGenerator struct {
+	policy *ADComplexityPolicy
+	gen    *PolicyGenerator
+}
+
+// NewADComplexityGenerator creates an ADComplexityGenerator for p, drawing
+// randomness from reader, or rand.Reader if reader is nil.
+func NewADComplexityGenerator(p *ADComplexityPolicy, reader io.Reader) (*ADComplexityGenerator, error) {
+	gen, err := NewPolicyGenerator(p.Policy(), reader)
+	if err != nil {
+		return nil, err
+	}
+	return &ADComplexityGenerator{policy: p, gen: gen}, nil
+}
+
+// Generate generates a password that passes the complexity check. Like
+// ValidatingGenerator, it gives up with an error wrapping the last rejection
+// after 100 rejected candidates in a row.
+func (g *ADComplexityGenerator) Generate() (string, error) {
+	return g.GenerateContext(context.Background())
+}
+
+// GenerateContext is the same as Generate, but stops once ctx is done.
+func (g *ADComplexityGenerator) GenerateContext(ctx context.Context) (string, error) {
+	// The policy already blocks the names; checking again keeps the
+	// guarantee even if the two ever disagree on case folding, and the
+	// shared retry loop turns a lasting disagreement into an error.
+	return (&ValidatingGenerator{validator: g.policy}).generate(ctx, g.gen.GenerateContext)
+}
+
+// Validate checks password against the policy. ADComplexityGenerator
+// implements Validator.
+func (g *ADComplexityGenerator) Validate(password string) error {
+	return g.policy.Validate(password)
+}
@@ -0,0 +1,179 @@
+package password
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestADCategories(t *testing.T) {
+	t.Parallel()
+
+	var TestCases = []struct {
+		Name     string
+		Password string
+		Expected ADCategory
+	}{
+		{Name: "empty", Password: "", Expected: 0},
+		{Name: "ascii", Password: "aB3;", Expected: ADLower | ADUpper | ADDigit | ADSymbol},
+		{Name: "diacritics", Password: "éÉ", Expected: ADLower | ADUpper},
+		{Name: "greek and cyrillic", Password: "ΩжД", Expected: ADLower | ADUpper},
+		{Name: "other letters", Password: "漢字かな", Expected: ADOtherLetter},
+		{Name: "currency", Password: "€£ ", Expected: 0},
+		{Name: "non-ascii digits", Password: "٣", Expected: 0},
+	}
+
+	for _, tc := range TestCases {
+		tc := tc
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			if res := ADCategories(tc.Password); res != tc.Expected {
+				t.Errorf("expected %05b to be %05b", res, tc.Expected)
+			}
+		})
+	}
+}
+
+func TestDisplayNameTokens(t *testing.T) {
+	t.Parallel()
+
+	var TestCases = []struct {
+		Name        string
+		DisplayName string
+		Expected    []string
+	}{
+		{Name: "documentation", DisplayName: "Erin M. Hagens", Expected: []string{"Erin", "Hagens"}},
+		{Name: "every delimiter", DisplayName: "Ann,Bob.Cy-Dee_Eve Fay#Gus\tHal", Expected: []string{"Ann", "Bob", "Dee", "Eve", "Fay", "Gus", "Hal"}},
+		{Name: "repeated delimiters", DisplayName: "  Smith,, John -- Jr.", Expected: []string{"Smith", "John"}},
+		{Name: "other punctuation", DisplayName: "O'Neil (Sales)", Expected: []string{"O'Neil", "(Sales)"}},
+		{Name: "runes", DisplayName: "Zoë Łoś", Expected: []string{"Zoë", "Łoś"}},
+		{Name: "short", DisplayName: "Al B", Expected: nil},
+		{Name: "empty", DisplayName: "", Expected: nil},
+	}
+
+	for _, tc := range TestCases {
+		tc := tc
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			if res := DisplayNameTokens(tc.DisplayName); !reflect.DeepEqual(res, tc.Expected) {
+				t.Errorf("expected %q to be %q", res, tc.Expected)
+			}
+		})
+	}
+}
+
+func TestADComplexityPolicy_Validate(t *testing.T) {
+	t.Parallel()
+
+	p := &ADComplexityPolicy{
+		SAMAccountName: "ehagens",
+		DisplayName:    "Erin M. Hagens",
+		MinLength:      8,
+	}
+
+	var TestCases = []struct {
+		Name     string
+		Password string
+		Rule     string
+	}{
+		{Name: "three categories", Password: "Correct9horse"},
+		{Name: "unicode letters count", Password: "漢字correct9"},
+		{Name: "too short", Password: "aB3;", Rule: "min_length"},
+		{Name: "two categories", Password: "correcthorse9", Rule: "categories"},
+		{Name: "currency is no symbol", Password: "correcthorse€", Rule: "categories"},
+		{Name: "account name", Password: "x9EHAGENSx", Rule: "sam_account_name"},
+		{Name: "display name token", Password: "Battery9erin", Rule: "display_name"},
+		{Name: "short token ignored", Password: "Battery9m"},
+		{Name: "display name across delimiter", Password: "Battery9ErinHagens", Rule: "display_name"},
+	}
+
+	for _, tc := range TestCases {
+		tc := tc
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			err := p.Validate(tc.Password)
+			if tc.Rule == "" {
+				if err != nil {
+					t.Errorf("expected %q to be valid: %v", tc.Password, err)
+				}
+				return
+			}
+
+			var ve *ValidationError
+			if !errors.As(err, &ve) {
+				t.Fatalf("expected %v to be a *ValidationError", err)
+			}
+			if ve.Rule != tc.Rule {
+				t.Errorf("expected %q to be %q", ve.Rule, tc.Rule)
+			}
+		})
+	}
+}
+
+func TestADComplexityPolicy_shortAccountName(t *testing.T) {
+	t.Parallel()
+
+	p := &ADComplexityPolicy{SAMAccountName: "ab"}
+	if err := p.Validate("xabX9"); err != nil {
+		t.Errorf("expected names shorter than three characters to be ignored: %v", err)
+	}
+}
+
+func TestADComplexityGenerator_Generate(t *testing.T) {
+	t.Parallel()
+
+	p := &ADComplexityPolicy{
+		SAMAccountName: "abc",
+		DisplayName:    "Abc-Def Ghi",
+		MinLength:      20,
+	}
+
+	gen, err := NewADComplexityGenerator(p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 200; i++ {
+		res, err := gen.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res) != 20 {
+			t.Errorf("%q has length %d, want 20", res, len(res))
+		}
+		if err := gen.Validate(res); err != nil {
+			t.Errorf("%q: %v", res, err)
+		}
+		if ADCategories(res) != ADUpper|ADLower|ADDigit|ADSymbol {
+			t.Errorf("%q misses an ASCII category", res)
+		}
+		if strings.Contains(strings.ToLower(res), "abc") {
+			t.Errorf("%q contains the account name", res)
+		}
+	}
+}
+
+func TestADComplexityGenerator_Generate_disagreement(t *testing.T) {
+	t.Parallel()
+
+	gen, err := NewADComplexityGenerator(&ADComplexityPolicy{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A policy the underlying generator does not know about rejects every
+	// candidate, which must end in an error rather than an endless loop.
+	gen.policy = &ADComplexityPolicy{MinLength: DefaultPolicyLength + 1}
+
+	var ve *ValidationError
+	if _, err := gen.Generate(); !errors.As(err, &ve) || ve.Rule != "min_length" {
+		t.Errorf("expected %v to wrap the rejection", err)
+	}
+}
@@ -0,0 +1,93 @@
+package password
+
+import (
+	"sort"
+	"unicode/utf8"
+)
+
+// Charset is an immutable set of characters. Membership tests never allocate,
+// which makes a Charset cheap to consult in hot loops such as policy retries.
+// A Charset is safe for concurrent use.
+type Charset struct {
+	chars string
+	runes []rune // in the order given, duplicates included
+	ascii [2]uint64
+	other []rune // sorted, deduplicated non-ASCII runes
+}
+
+// NewCharset creates a Charset holding the characters of chars.
+func NewCharset(chars string) *Charset {
+	c := &Charset{
+		chars: chars,
+		runes: []rune(chars),
+	}
+
+	for _, r := range c.runes {
+		if r >= 0 && r < utf8.RuneSelf {
+			c.ascii[r>>6] |= 1 << uint(r&63)
+			continue
+		}
+		c.other = append(c.other, r)
+	}
+
+	sort.Slice(c.other, func(i, j int) bool { return c.other[i] < c.other[j] })
+	n := 0
+	for i, r := range c.other {
+		if i == 0 || r != c.other[n-1] {
+			c.other[n] = r
+			n++
+		}
+	}
+	c.other = c.other[:n]
+
+	return c
+}
+
+// Contains reports whether r is in the set.
+func (c *Charset) Contains(r rune) bool {
+	if r >= 0 && r < utf8.RuneSelf {
+		return c.ascii[r>>6]&(1<<uint(r&63)) != 0
+	}
+
+	i := sort.Search(len(c.other), func(i int) bool { return c.other[i] >= r })
+	return i < len(c.other) && c.other[i] == r
+}
+
+// ContainsAny reports whether any character of s is in the set.
+func (c *Charset) ContainsAny(s string) bool {
+	for _, r := range s {
+		if c.Contains(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// Count returns the number of characters of s that are in the set.
+func (c *Charset) Count(s string) int {
+	n := 0
+	for _, r := range s {
+		if c.Contains(r) {
+			n++
+		}
+	}
+	return n
+}
+
+// Len returns the number of characters the set was created with.
+func (c *Charset) Len() int {
+	return len(c.runes)
+}
+
+// runesOrNil returns the runes of c, or nil if c is nil.
+func (c *Charset) runesOrNil() []rune {
+	if c == nil {
+		return nil
+	}
+	return c.runes
+}
+
+// String returns the characters the set was created with.
+func (c *Charset) String() string {
+	return c.chars
+}
@@ -0,0 +1,120 @@
+package password
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestCharset(t *testing.T) {
+	t.Parallel()
+
+	c := NewCharset("ab€c€")
+
+	if got, want := c.Len(), 5; got != want {
+		t.Errorf("Len() = %d, want %d", got, want)
+	}
+
+	if got, want := c.String(), "ab€c€"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	for _, r := range "abc€" {
+		if !c.Contains(r) {
+			t.Errorf("expected %q to be in the set", r)
+		}
+	}
+
+	for _, r := range "dA$£\x00" {
+		if c.Contains(r) {
+			t.Errorf("expected %q not to be in the set", r)
+		}
+	}
+
+	if c.Contains(-1) {
+		t.Errorf("expected invalid rune not to be in the set")
+	}
+}
+
+func TestCharset_ContainsAny(t *testing.T) {
+	t.Parallel()
+
+	var TestCases = []struct {
+		Name           string
+		InputString    string
+		ExpectedOutput bool
+	}{
+		{
+			Name:           "empty",
+			InputString:    "",
+			ExpectedOutput: false,
+		},
+		{
+			Name:           "ASCII member",
+			InputString:    "xyzb",
+			ExpectedOutput: true,
+		},
+		{
+			Name:           "non-ASCII member",
+			InputString:    "xyz€",
+			ExpectedOutput: true,
+		},
+		{
+			Name:           "no member",
+			InputString:    "xyz£",
+			ExpectedOutput: false,
+		},
+	}
+
+	c := NewCharset("ab€")
+	for _, tc := range TestCases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			res := c.ContainsAny(tc.InputString)
+
+			if res != tc.ExpectedOutput {
+				t.Errorf("Testcase %s failed. want - %t, got - %t", tc.Name, tc.ExpectedOutput, res)
+			}
+		})
+	}
+}
+
+func TestCharset_Count(t *testing.T) {
+	t.Parallel()
+
+	c := NewCharset("ab€")
+	if got, want := c.Count("a€xba€"), 5; got != want {
+		t.Errorf("Count() = %d, want %d", got, want)
+	}
+}
+
+func TestCharset_allocs(t *testing.T) {
+	c := NewCharset(Symbols + "€£¥")
+	allocs := testing.AllocsPerRun(100, func() {
+		c.ContainsAny("maryhadalittlelamb¥")
+		c.Count("maryhadalittlelamb¥")
+	})
+	if allocs != 0 {
+		t.Errorf("expected no allocations, got %v", allocs)
+	}
+}
+
+func BenchmarkCharset_ContainsAny(b *testing.B) {
+	const s = "maryhadalittlelambMARYHADALITTLELAMB0$"
+
+	b.Run("charset", func(b *testing.B) {
+		c := NewCharset(Symbols)
+
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			c.ContainsAny(s)
+		}
+	})
+
+	b.Run("regexp", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			regexp.MustCompile(`[^a-zA-Z0-9]+`).MatchString(s)
+		}
+	})
+}
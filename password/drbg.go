@@ -0,0 +1,154 @@
+package password
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"sync"
+)
+
+const (
+	// MinDRBGSeedSize is the minimum seed size in bytes accepted by NewDRBG.
+	// It matches the 256-bit security strength of HMAC_DRBG with SHA-256.
+	MinDRBGSeedSize = 32
+
+	// DRBGReseedInterval is the number of requests after which a DRBG must be
+	// reseeded, as specified by NIST SP 800-90A for HMAC_DRBG.
+	DRBGReseedInterval = 1 << 48
+
+	// drbgMaxRequestSize is the maximum number of bytes produced by a single
+	// generate request (2^19 bits).
+	drbgMaxRequestSize = 1 << 16
+)
+
+var (
+	// ErrDRBGSeedTooShort is the error returned when the seed given to a DRBG
+	// is shorter than MinDRBGSeedSize.
+	ErrDRBGSeedTooShort = errors.New("drbg seed must be at least 32 bytes")
+
+	// ErrDRBGReseedRequired is the error returned by DRBG.Read once the reseed
+	// interval has been reached.
+	ErrDRBGReseedRequired = errors.New("drbg must be reseeded")
+)
+
+// DRBG is a deterministic random bit generator implementing HMAC_DRBG with
+// SHA-256 from NIST SP 800-90A, without prediction resistance. It implements
+// io.Reader and can be passed as GeneratorInput.Reader to make generation
+// reproducible: the same seed and personalization always yield the same
+// passwords.
+//
+// The output is only as secret as the seed. Do not use a DRBG in production
+// unless the seed comes from a cryptographically secure source and is kept
+// secret; for test fixtures any fixed seed will do.
+//
+// A DRBG is safe for concurrent use, but concurrent readers observe the
+// output stream in an unspecified order.
+type DRBG struct {
+	mu      sync.Mutex
+	k       []byte
+	v       []byte
+	counter uint64
+}
+
+// NewDRBG instantiates a DRBG from seed, which plays the role of the entropy
+// input and nonce, and an optional personalization string.
+func NewDRBG(seed, personalization []byte) (*DRBG, error) {
+	if len(seed) < MinDRBGSeedSize {
+		return nil, ErrDRBGSeedTooShort
+	}
+
+	d := &DRBG{
+		k: make([]byte, sha256.Size),
+		v: make([]byte, sha256.Size),
+	}
+	for i := range d.v {
+		d.v[i] = 0x01
+	}
+
+	d.update(seed, personalization)
+	d.counter = 1
+	return d, nil
+}
+
+// Reseed mixes fresh entropy and optional additional input into the state and
+// resets the reseed counter.
+func (d *DRBG) Reseed(entropy, additional []byte) error {
+	if len(entropy) < MinDRBGSeedSize {
+		return ErrDRBGSeedTooShort
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.update(entropy, additional)
+	d.counter = 1
+	return nil
+}
+
+// Read fills p with deterministic pseudorandom bytes. Requests larger than
+// 64 KiB are split into several generate calls. Read only fails with
+// ErrDRBGReseedRequired once the reseed interval is exhausted.
+func (d *DRBG) Read(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	n := 0
+	for n < len(p) {
+		end := n + drbgMaxRequestSize
+		if end > len(p) {
+			end = len(p)
+		}
+
+		if err := d.generate(p[n:end]); err != nil {
+			return n, err
+		}
+		n = end
+	}
+	return n, nil
+}
+
+// generate implements the HMAC_DRBG generate function without additional
+// input.
+func (d *DRBG) generate(p []byte) error {
+	if d.counter > DRBGReseedInterval {
+		return ErrDRBGReseedRequired
+	}
+
+	for n := 0; n < len(p); {
+		d.v = mac(d.k, d.v)
+		n += copy(p[n:], d.v)
+	}
+
+	d.update()
+	d.counter++
+	return nil
+}
+
+// update implements the HMAC_DRBG update function. The provided data is the
+// concatenation of the given slices.
+func (d *DRBG) update(data ...[]byte) {
+	empty := true
+	for _, b := range data {
+		if len(b) > 0 {
+			empty = false
+		}
+	}
+
+	d.k = mac(d.k, append([][]byte{d.v, {0x00}}, data...)...)
+	d.v = mac(d.k, d.v)
+	if empty {
+		return
+	}
+
+	d.k = mac(d.k, append([][]byte{d.v, {0x01}}, data...)...)
+	d.v = mac(d.k, d.v)
+}
+
+// mac computes HMAC-SHA256 of the concatenation of parts under key.
+func mac(key []byte, parts ...[]byte) []byte {
+	m := hmac.New(sha256.New, key)
+	for _, p := range parts {
+		m.Write(p)
+	}
+	return m.Sum(nil)
+}
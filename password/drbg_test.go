@@ -0,0 +1,191 @@
+package password
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func testDecodeHex(tb testing.TB, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return b
+}
+
+func TestDRBG_NIST(t *testing.T) {
+	t.Parallel()
+
+	// HMAC_DRBG.rsp from the NIST CAVP test vectors, [SHA-256],
+	// [PredictionResistance = False], no personalization or additional input,
+	// COUNT = 0. The returned bits are those of the second generate call.
+	entropy := testDecodeHex(t, "ca851911349384bffe89de1cbdc46e6831e44d34a4fb935ee285dd14b71a7488")
+	nonce := testDecodeHex(t, "659ba96c601dc69fc902940805ec0ca8")
+	expected := testDecodeHex(t, "e528e9abf2dece54d47c7e75e5fe302149f817ea9fb4bee6f4199697d04d5b89"+
+		"d54fbb978a15b5c443c9ec21036d2460b6f73ebad0dc2aba6e624abf07745bc1"+
+		"07694bb7547bb0995f70de25d6b29e2d3011bb19d27676c07162c8b5ccde0668"+
+		"961df86803482cb37ed6d5c0bb8d50cf1f50d476aa0458bdaba806f48be9dcb8")
+
+	d, err := NewDRBG(append(entropy, nonce...), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := make([]byte, len(expected))
+	for i := 0; i < 2; i++ {
+		if _, err := d.Read(out); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if !bytes.Equal(out, expected) {
+		t.Errorf("got %x, want %x", out, expected)
+	}
+}
+
+func TestDRBG_Generate(t *testing.T) {
+	t.Parallel()
+
+	// These outputs are pinned: changing them breaks every caller that
+	// re-derives passwords from a stored seed.
+	var TestCases = []struct {
+		Name            string
+		Seed            string
+		Personalization string
+		Expected        []string
+	}{
+		{
+			Name: "zero seed",
+			Seed: "00000000000000000000000000000000",
+			Expected: []string{
+				"MgEd8mKIzGv!?B7x",
+				"P09h|YLbIFH+#y].f<Mj(EUn}cCQZDB8pKwNX3mOskr6d,JelqR5Wu72{xz4Vo1A",
+				"jupigtvd",
+			},
+		},
+		{
+			Name: "text seed",
+			Seed: "correct horse battery staple, v1",
+			Expected: []string{
+				"mTCVg0pFf!d7cS%B",
+				"HxtBF_C(z:{ru</q3EnDU1c7JMyeW65NPob2QhsZf0aVT?8mR.v94kKOY#AL|dip",
+				"gxzelzaj",
+			},
+		},
+	}
+
+	for _, tc := range TestCases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			d, err := NewDRBG([]byte(tc.Seed), []byte(tc.Personalization))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			gen, err := NewStatefulGenerator(&GeneratorInput{Reader: d})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []string{
+				gen.MustGenerate(16, 2, 2, true, false),
+				gen.MustGenerate(64, 10, 10, true, false),
+				gen.MustGenerate(8, 0, 0, false, true),
+			}
+
+			for i := range got {
+				if got[i] != tc.Expected[i] {
+					t.Errorf("password %d: got %q, want %q", i, got[i], tc.Expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDRBG_Personalization(t *testing.T) {
+	t.Parallel()
+
+	seed := bytes.Repeat([]byte{0xaa}, MinDRBGSeedSize)
+	d, err := NewDRBG(seed, []byte("fixtures"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gen, err := NewStatefulGenerator(&GeneratorInput{Reader: d})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := gen.MustGenerate(24, 4, 4, true, false), "B3pW=4l`CRuK9yJOs\\Xe\"N6Z"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestDRBG_Reseed(t *testing.T) {
+	t.Parallel()
+
+	seed := bytes.Repeat([]byte{0x01}, MinDRBGSeedSize)
+	a, err := NewDRBG(seed, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewDRBG(seed, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Reseed(bytes.Repeat([]byte{0x02}, MinDRBGSeedSize), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	outA, outB := make([]byte, 32), make([]byte, 32)
+	if _, err := a.Read(outA); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Read(outB); err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Equal(outA, outB) {
+		t.Errorf("reseeding did not change the output")
+	}
+
+	if err := b.Reseed(nil, nil); err != ErrDRBGSeedTooShort {
+		t.Errorf("expected %q to be %q", err, ErrDRBGSeedTooShort)
+	}
+
+	b.counter = DRBGReseedInterval + 1
+	if _, err := b.Read(outB); err != ErrDRBGReseedRequired {
+		t.Errorf("expected %q to be %q", err, ErrDRBGReseedRequired)
+	}
+}
+
+func TestDRBG_largeRead(t *testing.T) {
+	t.Parallel()
+
+	d, err := NewDRBG(make([]byte, MinDRBGSeedSize), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := make([]byte, 3*drbgMaxRequestSize+1)
+	n, err := d.Read(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != len(out) {
+		t.Errorf("read %d bytes, want %d", n, len(out))
+	}
+}
+
+func TestNewDRBG_shortSeed(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewDRBG(make([]byte, MinDRBGSeedSize-1), nil); err != ErrDRBGSeedTooShort {
+		t.Errorf("expected %q to be %q", err, ErrDRBGSeedTooShort)
+	}
+}
@@ -0,0 +1,132 @@
+package password
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ParameterError is the error returned for an invalid argument, such as a
+// negative number of digits, or for a generator without characters to draw
+// from. Where a sentinel error describes the same problem, such as
+// ErrNegativeCount, the ParameterError wraps it, so that both errors.As and
+// errors.Is work.
+type ParameterError struct {
+	Field  string      // name of the argument or GeneratorInput field, or fields joined by "/"
+	Value  interface{} // the rejected value
+	Reason string
+
+	err error
+}
+
+// Error implements error.
+func (e *ParameterError) Error() string {
+	return fmt.Sprintf("invalid %s %#v: %s", e.Field, e.Value, e.Reason)
+}
+
+// Unwrap returns the sentinel error wrapped by e, if any.
+func (e *ParameterError) Unwrap() error {
+	return e.err
+}
+
+// checkCounts validates the length and counts passed to the generators.
+func checkCounts(length, numDigits, numSymbols int) error {
+	switch {
+	case length <= 0:
+		err := &ParameterError{Field: "length", Value: length, Reason: "must be positive"}
+		if length < 0 {
+			err.err = ErrNegativeCount
+		}
+		return err
+	case numDigits < 0:
+		return &ParameterError{Field: "numDigits", Value: numDigits, Reason: "must not be negative", err: ErrNegativeCount}
+	case numSymbols < 0:
+		return &ParameterError{Field: "numSymbols", Value: numSymbols, Reason: "must not be negative", err: ErrNegativeCount}
+	}
+	return nil
+}
+
+// checkSet returns a ParameterError for field if n characters are to be drawn
+// from an empty set.
+func checkSet(field string, set []rune, n int) error {
+	if n > 0 && len(set) == 0 {
+		return &ParameterError{Field: field, Value: "", Reason: "must not be empty"}
+	}
+	return nil
+}
+
+// CountError is the error returned when more characters are requested than
+// are available: more digits and symbols than the total length, or, without
+// repeats, more characters of a class than it has distinct characters. It
+// wraps the matching sentinel error, such as ErrLettersExceedsAvailable, so
+// that errors.Is keeps working.
+type CountError struct {
+	Class     string // "letters", "digits", "symbols" or "digits and symbols"
+	Requested int
+	Available int
+
+	err error
+}
+
+// Error implements error.
+func (e *CountError) Error() string {
+	return fmt.Sprintf("%v: requested %d, available %d", e.err, e.Requested, e.Available)
+}
+
+// Unwrap returns the sentinel error wrapped by e.
+func (e *CountError) Unwrap() error {
+	return e.err
+}
+
+// exceedsAvailable returns the CountError for class, which must be one of
+// "letters", "digits" or "symbols".
+func exceedsAvailable(class string, requested, available int) error {
+	err := ErrLettersExceedsAvailable
+	switch class {
+	case "digits":
+		err = ErrDigitsExceedsAvailable
+	case "symbols":
+		err = ErrSymbolsExceedsAvailable
+	}
+	return &CountError{Class: class, Requested: requested, Available: available, err: err}
+}
+
+// exceedsTotalLength returns the CountError for digits and symbols that do not
+// fit into length.
+func exceedsTotalLength(length, numDigits, numSymbols int) error {
+	requested := numDigits + numSymbols
+	if requested < numDigits {
+		requested = int(^uint(0) >> 1) // saturate on overflow
+	}
+	return &CountError{Class: "digits and symbols", Requested: requested, Available: length, err: ErrExceedsTotalLength}
+}
+
+// ReaderError is the error returned when the random source fails. Phase is
+// the step of generation that was reading: "letters", "digits" or "symbols"
+// while picking characters of that class, "characters" while picking for a
+// policy, "insert" while placing characters at random positions, "token" or
+// "code" while picking the characters of a token or recovery code, "salt"
+// while salting a hash and "secret" while creating an OTP key.
+type ReaderError struct {
+	Phase string
+	Err   error
+}
+
+// Error implements error.
+func (e *ReaderError) Error() string {
+	return fmt.Sprintf("reading randomness for %s: %v", e.Phase, e.Err)
+}
+
+// Unwrap returns the error of the reader.
+func (e *ReaderError) Unwrap() error {
+	return e.Err
+}
+
+// readerError wraps an error of the random source in a ReaderError for phase.
+// Errors of a done context are returned unchanged.
+func readerError(err error, phase string) error {
+	var ce *contextError
+	if errors.As(err, &ce) {
+		return err
+	}
+	return &ReaderError{Phase: phase, Err: err}
+}
@@ -0,0 +1,240 @@
+package password
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+// shortReader reads its bytes and then fails with io.ErrUnexpectedEOF.
+type shortReader struct {
+	data []byte
+}
+
+func (r *shortReader) Read(data []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	n := copy(data, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestGenerator_Generate_CountError(t *testing.T) {
+	t.Parallel()
+
+	gen, err := NewStatefulGenerator(&GeneratorInput{
+		LowerLetters: "abcc",
+		Symbols:      "!a",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var TestCases = []struct {
+		Name       string
+		Length     int
+		NumDigits  int
+		NumSymbols int
+		Expected   CountError
+	}{
+		{
+			Name: "total length", Length: 4, NumDigits: 3, NumSymbols: 2,
+			Expected: CountError{Class: "digits and symbols", Requested: 5, Available: 4, err: ErrExceedsTotalLength},
+		},
+		{
+			Name: "letters", Length: 4,
+			Expected: CountError{Class: "letters", Requested: 4, Available: 3, err: ErrLettersExceedsAvailable},
+		},
+		{
+			Name: "digits", Length: 11, NumDigits: 11,
+			Expected: CountError{Class: "digits", Requested: 11, Available: 10, err: ErrDigitsExceedsAvailable},
+		},
+		{
+			Name: "symbols", Length: 3, NumSymbols: 3,
+			Expected: CountError{Class: "symbols", Requested: 3, Available: 2, err: ErrSymbolsExceedsAvailable},
+		},
+		{
+			Name: "symbols used up by letters", Length: 5, NumSymbols: 2,
+			Expected: CountError{Class: "symbols", Requested: 2, Available: 1, err: ErrSymbolsExceedsAvailable},
+		},
+	}
+
+	for _, tc := range TestCases {
+		tc := tc
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := gen.Generate(tc.Length, tc.NumDigits, tc.NumSymbols, false, false)
+
+			var ce *CountError
+			if !errors.As(err, &ce) {
+				t.Fatalf("expected %q to be a *CountError", err)
+			}
+			if *ce != tc.Expected {
+				t.Errorf("expected %#v to be %#v", *ce, tc.Expected)
+			}
+			if !errors.Is(err, tc.Expected.err) {
+				t.Errorf("expected %q to be %q", err, tc.Expected.err)
+			}
+		})
+	}
+}
+
+func TestGenerator_Generate_ReaderError(t *testing.T) {
+	t.Parallel()
+
+	var TestCases = []struct {
+		Name       string
+		Data       []byte
+		Length     int
+		NumDigits  int
+		NumSymbols int
+		Phase      string
+	}{
+		{Name: "letters", Length: 2, Phase: "letters"},
+		{Name: "digits", Data: []byte{0}, Length: 2, NumDigits: 1, Phase: "digits"},
+		{Name: "symbols", Data: []byte{0, 0}, Length: 3, NumDigits: 1, NumSymbols: 1, Phase: "symbols"},
+		{Name: "insert", Data: []byte{0, 0}, Length: 2, Phase: "insert"},
+	}
+
+	for _, tc := range TestCases {
+		tc := tc
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			gen, err := NewStatefulGenerator(&GeneratorInput{Reader: &shortReader{data: tc.Data}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = gen.Generate(tc.Length, tc.NumDigits, tc.NumSymbols, false, true)
+
+			var re *ReaderError
+			if !errors.As(err, &re) {
+				t.Fatalf("expected %q to be a *ReaderError", err)
+			}
+			if re.Phase != tc.Phase {
+				t.Errorf("expected %q to be %q", re.Phase, tc.Phase)
+			}
+			if !errors.Is(err, io.ErrUnexpectedEOF) {
+				t.Errorf("expected %q to be %q", err, io.ErrUnexpectedEOF)
+			}
+		})
+	}
+}
+
+func TestReaderError_phases(t *testing.T) {
+	t.Parallel()
+
+	var TestCases = []struct {
+		Name     string
+		Data     []byte
+		Generate func(r io.Reader) error
+		Phase    string
+	}{
+		{
+			Name: "token",
+			Generate: func(r io.Reader) error {
+				gen, err := NewTokenGenerator(&TokenInput{Reader: r})
+				if err != nil {
+					return err
+				}
+				_, err = gen.Generate()
+				return err
+			},
+			Phase: "token",
+		},
+		{
+			Name: "pin",
+			Generate: func(r io.Reader) error {
+				gen, err := NewPINGenerator(&PINInput{Reader: r})
+				if err != nil {
+					return err
+				}
+				_, err = gen.Generate()
+				return err
+			},
+			Phase: "digits",
+		},
+		{
+			Name: "recovery code",
+			Generate: func(r io.Reader) error {
+				gen, err := NewRecoveryCodeGenerator(&RecoveryCodeInput{Reader: r})
+				if err != nil {
+					return err
+				}
+				_, err = gen.Generate()
+				return err
+			},
+			Phase: "code",
+		},
+		{
+			Name: "recovery code hash",
+			Generate: func(r io.Reader) error {
+				gen, err := NewRecoveryCodeGenerator(&RecoveryCodeInput{Reader: r})
+				if err != nil {
+					return err
+				}
+				_, err = gen.Hash("x7k2-9mqa")
+				return err
+			},
+			Phase: "salt",
+		},
+		{
+			Name: "otp key",
+			Generate: func(r io.Reader) error {
+				_, err := GenerateOTPKey(&OTPInput{Issuer: "Acme", Account: "jsmith", Reader: r})
+				return err
+			},
+			Phase: "secret",
+		},
+		{
+			Name: "policy",
+			Generate: func(r io.Reader) error {
+				gen, err := NewPolicyGenerator(testPolicy, r)
+				if err != nil {
+					return err
+				}
+				_, err = gen.Generate()
+				return err
+			},
+			Phase: "characters",
+		},
+		{
+			Name: "vault",
+			Generate: func(r io.Reader) error {
+				gen, err := NewVaultGenerator(&VaultPolicy{Length: 2, Rules: []VaultCharsetRule{{Charset: "ab"}}}, r)
+				if err != nil {
+					return err
+				}
+				_, err = gen.Generate()
+				return err
+			},
+			Phase: "characters",
+		},
+	}
+
+	for _, tc := range TestCases {
+		tc := tc
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.Generate(&shortReader{data: tc.Data})
+
+			var re *ReaderError
+			if !errors.As(err, &re) {
+				t.Fatalf("expected %q to be a *ReaderError", err)
+			}
+			if re.Phase != tc.Phase {
+				t.Errorf("expected %q to be %q", re.Phase, tc.Phase)
+			}
+			if !errors.Is(err, io.ErrUnexpectedEOF) {
+				t.Errorf("expected %q to be %q", err, io.ErrUnexpectedEOF)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package password
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sync"
+)
+
+// Built-time checks that the generators implement the interface.
+var _ Generator = (*FakeGenerator)(nil)
+
+// FakeGenerator is a deterministic Generator for tests. Unlike
+// MockPasswordGenerator it honours its arguments: every call runs a
+// StatefulGenerator with the default character sets, reading from a DRBG
+// seeded with the fake's seed, the number of earlier calls and the
+// arguments. Passwords therefore have the requested length and class counts,
+// invalid arguments yield the same Err* values, and two fakes with the same
+// seed return the same passwords for the same sequence of calls.
+//
+// FakeGenerator is safe for concurrent use, but concurrent calls are numbered
+// in no particular order. It must never be used to generate real passwords.
+type FakeGenerator struct {
+	seed string
+
+	mu    sync.Mutex
+	calls uint64
+}
+
+// NewFakeGenerator creates a FakeGenerator for seed.
+func NewFakeGenerator(seed string) *FakeGenerator {
+	return &FakeGenerator{seed: seed}
+}
+
+// generator returns a StatefulGenerator for the next call with args.
+func (g *FakeGenerator) generator(method string, args ...interface{}) *StatefulGenerator {
+	g.mu.Lock()
+	n := g.calls
+	g.calls++
+	g.mu.Unlock()
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%q %d %s%v", g.seed, n, method, args)
+
+	drbg, err := NewDRBG(h.Sum(nil), []byte("fake generator"))
+	if err != nil {
+		panic(err) // the seed is a SHA-256 hash and always long enough
+	}
+
+	gen, err := NewStatefulGenerator(&GeneratorInput{Reader: drbg})
+	if err != nil {
+		panic(err) // the default character sets are valid
+	}
+	return gen
+}
+
+// Generate generates a deterministic password. See StatefulGenerator.Generate
+// for the arguments.
+func (g *FakeGenerator) Generate(length, numDigits, numSymbols int, includeUpper, allowRepeat bool) (string, error) {
+	return g.generator("Generate", length, numDigits, numSymbols, includeUpper, allowRepeat).
+		Generate(length, numDigits, numSymbols, includeUpper, allowRepeat)
+}
+
+// GenerateWithPolicy generates a deterministic password. See
+// StatefulGenerator.GenerateWithPolicy for the arguments.
+func (g *FakeGenerator) GenerateWithPolicy(length, numDigits, numSymbols int, includeUpper, allowRepeat, needsLower, needsUpper, needsDigit, needsSymbol bool) (string, error) {
+	return g.generator("GenerateWithPolicy", length, numDigits, numSymbols, includeUpper, allowRepeat, needsLower, needsUpper, needsDigit, needsSymbol).
+		GenerateWithPolicy(length, numDigits, numSymbols, includeUpper, allowRepeat, needsLower, needsUpper, needsDigit, needsSymbol)
+}
+
+// MustGenerate is the same as Generate, but panics on error.
+func (g *FakeGenerator) MustGenerate(length, numDigits, numSymbols int, includeUpper, allowRepeat bool) string {
+	res, err := g.Generate(length, numDigits, numSymbols, includeUpper, allowRepeat)
+	if err != nil {
+		panic(err)
+	}
+	return res
+}
+
+// GenerateContext is the same as Generate, but stops once ctx is done.
+func (g *FakeGenerator) GenerateContext(ctx context.Context, length, numDigits, numSymbols int, includeUpper, allowRepeat bool) (string, error) {
+	return g.generator("Generate", length, numDigits, numSymbols, includeUpper, allowRepeat).
+		GenerateContext(ctx, length, numDigits, numSymbols, includeUpper, allowRepeat)
+}
+
+// GenerateWithPolicyContext is the same as GenerateWithPolicy, but stops
+// once ctx is done.
+func (g *FakeGenerator) GenerateWithPolicyContext(ctx context.Context, length, numDigits, numSymbols int, includeUpper, allowRepeat, needsLower, needsUpper, needsDigit, needsSymbol bool) (string, error) {
+	return g.generator("GenerateWithPolicy", length, numDigits, numSymbols, includeUpper, allowRepeat, needsLower, needsUpper, needsDigit, needsSymbol).
+		GenerateWithPolicyContext(ctx, length, numDigits, numSymbols, includeUpper, allowRepeat, needsLower, needsUpper, needsDigit, needsSymbol)
+}
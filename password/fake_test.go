@@ -0,0 +1,148 @@
+package password
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestFakeGenerator_Generate(t *testing.T) {
+	t.Parallel()
+
+	var TestCases = []struct {
+		Name         string
+		Length       int
+		NumDigits    int
+		NumSymbols   int
+		IncludeUpper bool
+		AllowRepeat  bool
+	}{
+		{Name: "letters", Length: 8},
+		{Name: "all classes", Length: 64, NumDigits: 10, NumSymbols: 10, IncludeUpper: true, AllowRepeat: true},
+		{Name: "no repeats", Length: 52, NumDigits: 10, NumSymbols: 10, IncludeUpper: true},
+		{Name: "repeats", Length: 100, NumDigits: 40, NumSymbols: 40, AllowRepeat: true},
+	}
+
+	gen := NewFakeGenerator("fixtures")
+
+	for _, tc := range TestCases {
+		tc := tc
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			res, err := gen.Generate(tc.Length, tc.NumDigits, tc.NumSymbols, tc.IncludeUpper, tc.AllowRepeat)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(res) != tc.Length {
+				t.Errorf("%q has length %d, want %d", res, len(res), tc.Length)
+			}
+			if n := defaultDigits.Count(res); n != tc.NumDigits {
+				t.Errorf("%q has %d digits, want %d", res, n, tc.NumDigits)
+			}
+			if n := defaultSymbols.Count(res); n != tc.NumSymbols {
+				t.Errorf("%q has %d symbols, want %d", res, n, tc.NumSymbols)
+			}
+			if !tc.IncludeUpper && ContainsUpper(res) {
+				t.Errorf("%q contains uppercase letters", res)
+			}
+			if !tc.AllowRepeat && testHasDuplicates(t, res) {
+				t.Errorf("%q contains duplicates", res)
+			}
+		})
+	}
+}
+
+func TestFakeGenerator_errors(t *testing.T) {
+	t.Parallel()
+
+	var TestCases = []struct {
+		Name       string
+		Length     int
+		NumDigits  int
+		NumSymbols int
+	}{
+		{Name: "exceeds length", Length: 1, NumDigits: 2},
+		{Name: "exceeds letters", Length: 1000},
+		{Name: "exceeds digits", Length: 52, NumDigits: 11},
+		{Name: "exceeds symbols", Length: 52, NumSymbols: 31},
+	}
+
+	fake := NewFakeGenerator("fixtures")
+	gen, err := NewStatefulGenerator(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range TestCases {
+		tc := tc
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			_, expected := gen.Generate(tc.Length, tc.NumDigits, tc.NumSymbols, true, false)
+			if expected == nil {
+				t.Fatal("expected the arguments to be invalid")
+			}
+
+			if _, err := fake.Generate(tc.Length, tc.NumDigits, tc.NumSymbols, true, false); !reflect.DeepEqual(err, expected) {
+				t.Errorf("expected %q to be %q", err, expected)
+			}
+			if _, err := fake.GenerateWithPolicy(tc.Length, tc.NumDigits, tc.NumSymbols, true, false, false, false, false, false); !reflect.DeepEqual(err, expected) {
+				t.Errorf("expected %q to be %q", err, expected)
+			}
+		})
+	}
+}
+
+func TestFakeGenerator_deterministic(t *testing.T) {
+	t.Parallel()
+
+	a, b := NewFakeGenerator("seed"), NewFakeGenerator("seed")
+	var seen []string
+	for i := 0; i < 10; i++ {
+		resA := a.MustGenerate(16, 2, 2, true, false)
+		resB, err := b.GenerateContext(context.Background(), 16, 2, 2, true, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if resA != resB {
+			t.Errorf("expected %q to be %q", resA, resB)
+		}
+		for _, s := range seen {
+			if s == resA {
+				t.Errorf("expected successive calls to differ, got %q twice", resA)
+			}
+		}
+		seen = append(seen, resA)
+	}
+
+	if res := NewFakeGenerator("other").MustGenerate(16, 2, 2, true, false); res == seen[0] {
+		t.Errorf("expected a different seed to give a different password than %q", res)
+	}
+}
+
+func TestFakeGenerator_GenerateWithPolicy(t *testing.T) {
+	t.Parallel()
+
+	gen := NewFakeGenerator("policy")
+	for i := 0; i < 20; i++ {
+		res, err := gen.GenerateWithPolicy(8, 1, 1, true, true, true, true, true, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ContainsLower(res) || !ContainsUpper(res) || !ContainsDigit(res) || !ContainsSymbol(res) {
+			t.Errorf("%q misses a required class", res)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := gen.GenerateWithPolicyContext(ctx, 8, 1, 1, true, true, true, true, true, true); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %q to be %q", err, context.Canceled)
+	}
+}
@@ -0,0 +1,188 @@
+//go:build go1.18
+// +build go1.18
+
+package password
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// fuzzMaxLength bounds the length of passwords the fuzz targets generate with
+// repeats allowed, which would otherwise allocate arbitrarily much memory.
+const fuzzMaxLength = 4096
+
+// fuzzErrors are the sentinel errors the generators are documented to wrap
+// or return, besides *ParameterError.
+var fuzzErrors = []error{
+	ErrExceedsTotalLength,
+	ErrLettersExceedsAvailable,
+	ErrDigitsExceedsAvailable,
+	ErrSymbolsExceedsAvailable,
+	ErrPolicyUnsatisfiable,
+}
+
+func fuzzCheckError(t *testing.T, err error) {
+	t.Helper()
+
+	var pe *ParameterError
+	if errors.As(err, &pe) {
+		return
+	}
+	var ce *CountError
+	if errors.As(err, &ce) && ce.Requested <= ce.Available {
+		t.Fatalf("%q requests no more than is available", err)
+	}
+	for _, expected := range fuzzErrors {
+		if errors.Is(err, expected) {
+			return
+		}
+	}
+	t.Fatalf("unexpected error %q", err)
+}
+
+// fuzzCheckPassword checks the invariants of a password generated by gen.
+// Class counts are only checked if the character sets are disjoint, since
+// otherwise a character cannot be attributed to its class.
+func fuzzCheckPassword(t *testing.T, gen *StatefulGenerator, res string, length, numDigits, numSymbols int, includeUpper, allowRepeat bool) {
+	t.Helper()
+
+	if n := utf8.RuneCountInString(res); n != length {
+		t.Fatalf("%q has length %d, want %d", res, n, length)
+	}
+	if !allowRepeat && testHasDuplicates(t, res) {
+		t.Fatalf("%q contains duplicates", res)
+	}
+
+	disjoint := true
+	var seen runeSet
+	for _, set := range []*Charset{gen.lowerLetters, gen.upperLetters, gen.digits, gen.symbols} {
+		var own runeSet
+		for _, r := range set.runes {
+			if seen.contains(r) && !own.contains(r) {
+				disjoint = false
+			}
+			own.add(r)
+			seen.add(r)
+		}
+	}
+
+	for _, r := range res {
+		letter := gen.lowerLetters.Contains(r) || includeUpper && gen.upperLetters.Contains(r)
+		if !letter && !gen.digits.Contains(r) && !gen.symbols.Contains(r) {
+			t.Fatalf("%q contains %q, which is in no character set", res, r)
+		}
+	}
+	if !disjoint {
+		return
+	}
+
+	if n := gen.digits.Count(res); n != numDigits {
+		t.Fatalf("%q has %d digits, want %d", res, n, numDigits)
+	}
+	if n := gen.symbols.Count(res); n != numSymbols {
+		t.Fatalf("%q has %d symbols, want %d", res, n, numSymbols)
+	}
+	if !includeUpper && gen.upperLetters.ContainsAny(res) {
+		t.Fatalf("%q contains uppercase letters", res)
+	}
+}
+
+func FuzzGenerate(f *testing.F) {
+	f.Add(64, 10, 10, false, false)
+	f.Add(16, 2, 2, true, true)
+	f.Add(0, 0, 0, false, false)
+	f.Add(8, -1, 0, true, true)
+	f.Add(-8, 0, 0, false, false)
+	f.Add(maxInt, 0, 0, false, true)
+	f.Add(8, maxInt/2+1, maxInt/2+1, false, true)
+	f.Add(100, 0, 0, true, false)
+
+	gen, err := NewStatefulGenerator(nil)
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	f.Fuzz(func(t *testing.T, length, numDigits, numSymbols int, includeUpper, allowRepeat bool) {
+		if allowRepeat && length > fuzzMaxLength {
+			t.Skip()
+		}
+
+		res, err := gen.Generate(length, numDigits, numSymbols, includeUpper, allowRepeat)
+		if err != nil {
+			fuzzCheckError(t, err)
+			return
+		}
+		fuzzCheckPassword(t, gen, res, length, numDigits, numSymbols, includeUpper, allowRepeat)
+	})
+}
+
+func FuzzGenerateWithPolicy(f *testing.F) {
+	f.Add(16, 2, 2, true, false, true, true, true, true)
+	f.Add(4, 1, 1, true, true, true, true, true, true)
+	f.Add(1, 0, 0, true, true, true, true, false, false)
+	f.Add(8, 0, 0, false, true, false, true, false, false)
+	f.Add(8, 0, 2, true, true, false, false, true, false)
+	f.Add(-1, -1, -1, false, false, true, true, true, true)
+
+	gen, err := NewStatefulGenerator(nil)
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	f.Fuzz(func(t *testing.T, length, numDigits, numSymbols int, includeUpper, allowRepeat, needsLower, needsUpper, needsDigit, needsSymbol bool) {
+		if allowRepeat && length > fuzzMaxLength {
+			t.Skip()
+		}
+
+		// Satisfiable policies are met after a few attempts, so running into
+		// the deadline means the generator keeps retrying in vain.
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		res, err := gen.GenerateWithPolicyContext(ctx, length, numDigits, numSymbols, includeUpper, allowRepeat, needsLower, needsUpper, needsDigit, needsSymbol)
+		if err != nil {
+			fuzzCheckError(t, err)
+			return
+		}
+		fuzzCheckPassword(t, gen, res, length, numDigits, numSymbols, includeUpper, allowRepeat)
+		if !gen.isLegalPassword(res, needsLower, needsUpper, needsDigit, needsSymbol) {
+			t.Fatalf("%q does not meet the policy", res)
+		}
+	})
+}
+
+func FuzzNewStatefulGenerator(f *testing.F) {
+	f.Add("", "", "", "", 16, 2, 2, true, false)
+	f.Add("abc", "ABC", "012", "!@#", 6, 2, 2, true, false)
+	f.Add("aab", "", "0b", "", 4, 2, 0, false, false)
+	f.Add("ä日本", "ÄÖÜ", "٠١٢", "€£¥", 8, 2, 2, true, true)
+	f.Add("a", "a", "a", "a", 4, 1, 1, true, false)
+	f.Add("\xff\xfe", "", "", "", 2, 0, 0, false, false)
+
+	f.Fuzz(func(t *testing.T, lower, upper, digits, symbols string, length, numDigits, numSymbols int, includeUpper, allowRepeat bool) {
+		if allowRepeat && length > fuzzMaxLength {
+			t.Skip()
+		}
+
+		gen, err := NewStatefulGenerator(&GeneratorInput{
+			LowerLetters: lower,
+			UpperLetters: upper,
+			Digits:       digits,
+			Symbols:      symbols,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := gen.Generate(length, numDigits, numSymbols, includeUpper, allowRepeat)
+		if err != nil {
+			fuzzCheckError(t, err)
+			return
+		}
+		fuzzCheckPassword(t, gen, res, length, numDigits, numSymbols, includeUpper, allowRepeat)
+	})
+}
@@ -11,12 +11,11 @@
 package password
 
 import (
+	"context"
 	"crypto/rand"
 	"errors"
+	"fmt"
 	"io"
-	"math/big"
-	"regexp"
-	"strings"
 )
 
 // Built-time checks that the generators implement the interface.
@@ -29,6 +28,8 @@ type Generator interface {
 	Generate(int, int, int, bool, bool) (string, error)
 	MustGenerate(int, int, int, bool, bool) string
 	GenerateWithPolicy(int, int, int, bool, bool, bool, bool, bool, bool) (string, error)
+	GenerateContext(context.Context, int, int, int, bool, bool) (string, error)
+	GenerateWithPolicyContext(context.Context, int, int, int, bool, bool, bool, bool, bool, bool) (string, error)
 }
 
 const (
@@ -46,40 +47,61 @@ const (
 )
 
 var (
-	// ErrExceedsTotalLength is the error returned with the number of digits and
-	// symbols is greater than the total length.
+	// ErrExceedsTotalLength is the error wrapped by the *CountError returned
+	// when the number of digits and symbols is greater than the total length.
 	ErrExceedsTotalLength = errors.New("number of digits and symbols must be less than total length")
 
-	// ErrLettersExceedsAvailable is the error returned with the number of letters
-	// exceeds the number of available letters and repeats are not allowed.
+	// ErrLettersExceedsAvailable is the error wrapped by the *CountError
+	// returned when the number of letters exceeds the number of available
+	// letters and repeats are not allowed.
 	ErrLettersExceedsAvailable = errors.New("number of letters exceeds available letters and repeats are not allowed")
 
-	// ErrDigitsExceedsAvailable is the error returned with the number of digits
-	// exceeds the number of available digits and repeats are not allowed.
+	// ErrDigitsExceedsAvailable is the error wrapped by the *CountError
+	// returned when the number of digits exceeds the number of available
+	// digits and repeats are not allowed.
 	ErrDigitsExceedsAvailable = errors.New("number of digits exceeds available digits and repeats are not allowed")
 
-	// ErrSymbolsExceedsAvailable is the error returned with the number of symbols
-	// exceeds the number of available symbols and repeats are not allowed.
+	// ErrSymbolsExceedsAvailable is the error wrapped by the *CountError
+	// returned when the number of symbols exceeds the number of available
+	// symbols and repeats are not allowed.
 	ErrSymbolsExceedsAvailable = errors.New("number of symbols exceeds available symbols and repeats are not allowed")
+
+	// ErrNegativeCount is the error wrapped by the *ParameterError returned
+	// when the length, the number of digits or the number of symbols is
+	// negative.
+	ErrNegativeCount = errors.New("length, number of digits and number of symbols must not be negative")
+
+	// ErrPolicyUnsatisfiable is the error returned by GenerateWithPolicy when no
+	// password with the requested counts can contain every required class.
+	ErrPolicyUnsatisfiable = errors.New("no password with the requested counts can meet the policy")
+)
+
+var (
+	defaultLowerLetters = NewCharset(LowerLetters)
+	defaultUpperLetters = NewCharset(UpperLetters)
+	defaultDigits       = NewCharset(Digits)
+	defaultSymbols      = NewCharset(Symbols)
 )
 
 // StatefulGenerator is a generator which can be used to customize the list
 // of letters, digits, and/or symbols.
 type StatefulGenerator struct {
-	lowerLetters string
-	upperLetters string
-	digits       string
-	symbols      string
+	lowerLetters *Charset
+	upperLetters *Charset
+	digits       *Charset
+	symbols      *Charset
+	letters      []rune // lower followed by upper letters
 	reader       io.Reader
 }
 
 // GeneratorInput is used as input to the NewStatefulGenerator function.
 type GeneratorInput struct {
-	LowerLetters string
-	UpperLetters string
-	Digits       string
-	Symbols      string
-	Reader       io.Reader // rand.Reader by default
+	LowerLetters  string
+	UpperLetters  string
+	Digits        string
+	Symbols       string        // takes precedence over SymbolProfile
+	SymbolProfile SymbolProfile // SymbolProfileDefault by default
+	Reader        io.Reader     // rand.Reader by default
 }
 
 // NewStatefulGenerator creates a new StatefulGenerator from the specified
@@ -90,29 +112,25 @@ func NewStatefulGenerator(i *GeneratorInput) (*StatefulGenerator, error) {
 		i = new(GeneratorInput)
 	}
 
-	g := &StatefulGenerator{
-		lowerLetters: i.LowerLetters,
-		upperLetters: i.UpperLetters,
-		digits:       i.Digits,
-		symbols:      i.Symbols,
-		reader:       i.Reader,
-	}
-
-	if g.lowerLetters == "" {
-		g.lowerLetters = LowerLetters
-	}
-
-	if g.upperLetters == "" {
-		g.upperLetters = UpperLetters
+	symbols := i.Symbols
+	if symbols == "" && i.SymbolProfile != SymbolProfileDefault {
+		var err error
+		if symbols, err = i.SymbolProfile.Symbols(); err != nil {
+			return nil, err
+		}
 	}
 
-	if g.digits == "" {
-		g.digits = Digits
+	g := &StatefulGenerator{
+		lowerLetters: charsetOrDefault(i.LowerLetters, defaultLowerLetters),
+		upperLetters: charsetOrDefault(i.UpperLetters, defaultUpperLetters),
+		digits:       charsetOrDefault(i.Digits, defaultDigits),
+		symbols:      charsetOrDefault(symbols, defaultSymbols),
+		reader:       i.Reader,
 	}
 
-	if g.symbols == "" {
-		g.symbols = Symbols
-	}
+	g.letters = make([]rune, 0, g.lowerLetters.Len()+g.upperLetters.Len())
+	g.letters = append(g.letters, g.lowerLetters.runes...)
+	g.letters = append(g.letters, g.upperLetters.runes...)
 
 	if g.reader == nil {
 		g.reader = rand.Reader
@@ -127,88 +145,109 @@ func NewStatefulGenerator(i *GeneratorInput) (*StatefulGenerator, error) {
 // the result. noUpper excludes uppercase letters from the results. allowRepeat
 // allows characters to repeat.
 //
-// The algorithm is fast, but it's not designed to be performant; it favors
-// entropy over speed. This function is safe for concurrent use.
+// A length that is not positive and negative counts yield a *ParameterError.
+//
+// The characters are picked first and then shuffled, so the algorithm runs in
+// linear time in the length of the password. This function is safe for
+// concurrent use.
 func (g *StatefulGenerator) Generate(length, numDigits, numSymbols int, includeUpper, allowRepeat bool) (string, error) {
-	letters := g.lowerLetters
-	if includeUpper {
-		letters += g.upperLetters
-	}
+	return g.GenerateContext(context.Background(), length, numDigits, numSymbols, includeUpper, allowRepeat)
+}
 
-	chars := length - numDigits - numSymbols
-	if chars < 0 {
-		return "", ErrExceedsTotalLength
-	}
+// GenerateContext is the same as Generate, but stops between random draws
+// once ctx is done. The returned error then wraps ctx.Err() and reports how
+// many characters had been picked.
+func (g *StatefulGenerator) GenerateContext(ctx context.Context, length, numDigits, numSymbols int, includeUpper, allowRepeat bool) (string, error) {
+	var res string
+	err := g.generate(ctx, length, numDigits, numSymbols, includeUpper, allowRepeat, func(buf []rune) {
+		res = string(buf)
+	})
+	return res, err
+}
 
-	if !allowRepeat && chars > len(letters) {
-		return "", ErrLettersExceedsAvailable
+// testHookGenerated, if set, is called with the random bytes and the rune
+// buffer of every generate call once they have been wiped.
+var testHookGenerated func(random []byte, runes []rune)
+
+// generate implements GenerateContext. It passes the password as runes to use
+// and wipes them, together with the random bytes they were drawn from and
+// the picks of a failed attempt, before it returns, so that GenerateSecret
+// leaves no copy of the password behind.
+func (g *StatefulGenerator) generate(ctx context.Context, length, numDigits, numSymbols int, includeUpper, allowRepeat bool, use func([]rune)) error {
+	if err := checkCounts(length, numDigits, numSymbols); err != nil {
+		return err
 	}
 
-	if !allowRepeat && numDigits > len(g.digits) {
-		return "", ErrDigitsExceedsAvailable
+	// Compare without summing the counts, which could overflow.
+	if numDigits > length || numSymbols > length-numDigits {
+		return exceedsTotalLength(length, numDigits, numSymbols)
 	}
+	chars := length - numDigits - numSymbols
 
-	if !allowRepeat && numSymbols > len(g.symbols) {
-		return "", ErrSymbolsExceedsAvailable
+	// The sets are only empty for a StatefulGenerator that was not created with
+	// NewStatefulGenerator.
+	letters, lettersField := g.lowerLetters.runesOrNil(), "LowerLetters"
+	if includeUpper {
+		letters, lettersField = g.letters, "LowerLetters/UpperLetters"
 	}
+	digits := g.digits.runesOrNil()
+	symbols := g.symbols.runesOrNil()
 
-	var result string
-
-	// Characters
-	for i := 0; i < chars; i++ {
-		ch, err := randomElement(g.reader, letters)
-		if err != nil {
-			return "", err
-		}
-
-		if !allowRepeat && strings.Contains(result, ch) {
-			i--
-			continue
-		}
-
-		result, err = randomInsert(g.reader, result, ch)
-		if err != nil {
-			return "", err
-		}
+	if err := checkSet(lettersField, letters, chars); err != nil {
+		return err
+	}
+	if err := checkSet("Digits", digits, numDigits); err != nil {
+		return err
+	}
+	if err := checkSet("Symbols", symbols, numSymbols); err != nil {
+		return err
 	}
 
-	// Digits
-	for i := 0; i < numDigits; i++ {
-		d, err := randomElement(g.reader, g.digits)
-		if err != nil {
-			return "", err
+	if !allowRepeat {
+		if n := distinctRunes(letters); chars > n {
+			return exceedsAvailable("letters", chars, n)
 		}
-
-		if !allowRepeat && strings.Contains(result, d) {
-			i--
-			continue
+		if n := distinctRunes(digits); numDigits > n {
+			return exceedsAvailable("digits", numDigits, n)
 		}
-
-		result, err = randomInsert(g.reader, result, d)
-		if err != nil {
-			return "", err
+		if n := distinctRunes(symbols); numSymbols > n {
+			return exceedsAvailable("symbols", numSymbols, n)
 		}
 	}
 
-	// Symbols
-	for i := 0; i < numSymbols; i++ {
-		sym, err := randomElement(g.reader, g.symbols)
-		if err != nil {
-			return "", err
+	src := newRandomSource(ctx, g.reader)
+	buf := make([]rune, 0, length)
+	var seen runeSet
+	defer func() {
+		src.wipe()
+		wipeRunes(buf[:cap(buf)])
+		seen.reset()
+		if testHookGenerated != nil {
+			testHookGenerated(src.buf[:], buf[:cap(buf)])
 		}
+	}()
 
-		if !allowRepeat && strings.Contains(result, sym) {
-			i--
-			continue
-		}
+	var err error
+	if buf, err = pickRunes(src, buf, letters, chars, allowRepeat, &seen, "letters"); err != nil {
+		return progressError(err, len(buf), length)
+	}
+	if buf, err = pickRunes(src, buf, digits, numDigits, allowRepeat, &seen, "digits"); err != nil {
+		return progressError(err, len(buf), length)
+	}
+	if buf, err = pickRunes(src, buf, symbols, numSymbols, allowRepeat, &seen, "symbols"); err != nil {
+		return progressError(err, len(buf), length)
+	}
 
-		result, err = randomInsert(g.reader, result, sym)
-		if err != nil {
-			return "", err
-		}
+	// Inserting every pick at a uniformly random position, as earlier versions
+	// did, yields a uniformly random permutation of the picks. A Fisher-Yates
+	// shuffle produces the same distribution in linear time. The order of the
+	// draws is frozen for site passwords, see SiteVersion.
+	if err := shuffle(src, buf); err != nil {
+		return progressError(readerError(err, "insert"), len(buf), length)
 	}
 
-	return result, nil
+	use(buf)
+	return nil
 }
 
 // MustGenerate is the same as Generate, but panics on error.
@@ -220,20 +259,36 @@ func (g *StatefulGenerator) MustGenerate(length, numDigits, numSymbols int, incl
 	return res
 }
 
-// GenerateWithPolicy is the same as Generate, but ensures result matches specified policy
-func (g *StatefulGenerator) GenerateWithPolicy(length, numDigits, numSymbols int, includeUpper, allowRepeat, needsLower, needsUpper, needsDigit, needsSymbol bool) (result string, err error) {
+// GenerateWithPolicy is the same as Generate, but ensures result matches
+// specified policy. Character classes are judged against the generator's
+// configured letters, digits and symbols. Candidates are drawn until one
+// matches, so if no password with the requested counts can contain every
+// required class, such as an uppercase letter without includeUpper, it
+// returns ErrPolicyUnsatisfiable instead of retrying forever.
+func (g *StatefulGenerator) GenerateWithPolicy(length, numDigits, numSymbols int, includeUpper, allowRepeat, needsLower, needsUpper, needsDigit, needsSymbol bool) (string, error) {
+	return g.GenerateWithPolicyContext(context.Background(), length, numDigits, numSymbols, includeUpper, allowRepeat, needsLower, needsUpper, needsDigit, needsSymbol)
+}
 
-	for {
-		result, err = g.Generate(length, numDigits, numSymbols, includeUpper, allowRepeat)
+// GenerateWithPolicyContext is the same as GenerateWithPolicy, but stops
+// between attempts and random draws once ctx is done. The returned error then
+// wraps ctx.Err() and reports the number of rejected attempts.
+func (g *StatefulGenerator) GenerateWithPolicyContext(ctx context.Context, length, numDigits, numSymbols int, includeUpper, allowRepeat, needsLower, needsUpper, needsDigit, needsSymbol bool) (string, error) {
+	for attempt := 0; ; attempt++ {
+		result, err := g.GenerateContext(ctx, length, numDigits, numSymbols, includeUpper, allowRepeat)
 		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil && errors.Is(err, ctxErr) {
+				return "", fmt.Errorf("%w (after %d rejected attempts)", err, attempt)
+			}
 			return "", err
 		}
-		if isLegalPassword(result, needsLower, needsUpper, needsDigit, needsSymbol) {
-			break
+
+		if g.isLegalPassword(result, needsLower, needsUpper, needsDigit, needsSymbol) {
+			return result, nil
+		}
+		if attempt == 0 && !g.canMeetPolicy(length, numDigits, numSymbols, includeUpper, needsLower, needsUpper, needsDigit, needsSymbol) {
+			return "", ErrPolicyUnsatisfiable
 		}
 	}
-
-	return result, nil
 }
 
 // Generate is the package shortcut for Generator.Generate.
@@ -256,6 +311,27 @@ func GenerateWithPolicy(length, numDigits, numSymbols int, includeUpper, allowRe
 	return gen.GenerateWithPolicy(length, numDigits, numSymbols, includeUpper, allowRepeat, needsLower, needsUpper, needsDigit, needsSymbol)
 }
 
+// GenerateContext is the package shortcut for Generator.GenerateContext.
+func GenerateContext(ctx context.Context, length, numDigits, numSymbols int, includeUpper, allowRepeat bool) (string, error) {
+	gen, err := NewStatefulGenerator(nil)
+	if err != nil {
+		return "", err
+	}
+
+	return gen.GenerateContext(ctx, length, numDigits, numSymbols, includeUpper, allowRepeat)
+}
+
+// GenerateWithPolicyContext is the package shortcut for
+// Generator.GenerateWithPolicyContext.
+func GenerateWithPolicyContext(ctx context.Context, length, numDigits, numSymbols int, includeUpper, allowRepeat, needsLower, needsUpper, needsDigit, needsSymbol bool) (string, error) {
+	gen, err := NewStatefulGenerator(nil)
+	if err != nil {
+		return "", err
+	}
+
+	return gen.GenerateWithPolicyContext(ctx, length, numDigits, numSymbols, includeUpper, allowRepeat, needsLower, needsUpper, needsDigit, needsSymbol)
+}
+
 // MustGenerate is the package shortcut for Generator.MustGenerate.
 func MustGenerate(length, numDigits, numSymbols int, includeUpper, allowRepeat bool) string {
 	res, err := Generate(length, numDigits, numSymbols, includeUpper, allowRepeat)
@@ -265,66 +341,216 @@ func MustGenerate(length, numDigits, numSymbols int, includeUpper, allowRepeat b
 	return res
 }
 
-// randomInsert randomly inserts the given value into the given string.
-func randomInsert(reader io.Reader, s, val string) (string, error) {
-	if s == "" {
-		return val, nil
+// charsetOrDefault returns a Charset for chars, or def if chars is empty.
+func charsetOrDefault(chars string, def *Charset) *Charset {
+	if chars == "" {
+		return def
 	}
+	return NewCharset(chars)
+}
 
-	n, err := rand.Int(reader, big.NewInt(int64(len(s)+1)))
-	if err != nil {
-		return "", err
+// progressError annotates an error caused by a done context with the number
+// of characters picked so far. Other errors are returned unchanged.
+func progressError(err error, picked, length int) error {
+	var ce *contextError
+	if !errors.As(err, &ce) {
+		return err
 	}
-	i := n.Int64()
-	return s[0:i] + val + s[i:], nil
+	return fmt.Errorf("password generation stopped after picking %d of %d characters: %w", picked, length, ce.err)
 }
 
-// randomElement extracts a random element from the given string.
-func randomElement(reader io.Reader, s string) (string, error) {
-	n, err := rand.Int(reader, big.NewInt(int64(len(s))))
-	if err != nil {
-		return "", err
+// pickRunes appends n runes of class drawn uniformly from set to buf. Unless
+// allowRepeat is set, runes already recorded in seen are drawn again, and a
+// CountError is returned if fewer than n of them are left, which happens when
+// an earlier class used up characters it shares with set. Reader errors are
+// wrapped in a ReaderError for class. On error the runes picked so far are
+// returned along with it.
+func pickRunes(src *randomSource, buf, set []rune, n int, allowRepeat bool, seen *runeSet, class string) ([]rune, error) {
+	if !allowRepeat && n > 0 {
+		var left runeSet
+		available := 0
+		for _, r := range set {
+			if !seen.contains(r) && !left.contains(r) {
+				left.add(r)
+				available++
+			}
+		}
+		if available < n {
+			return buf, exceedsAvailable(class, n, available)
+		}
 	}
-	return string(s[n.Int64()]), nil
-}
 
-func isLegalPassword(p string, needsLower bool, needsUpper bool, needsDigit bool, needsSymbol bool) bool {
+	for i := 0; i < n; i++ {
+		j, err := src.intn(len(set))
+		if err != nil {
+			return buf, readerError(err, class)
+		}
+
+		r := set[j]
+		if !allowRepeat {
+			if seen.contains(r) {
+				i--
+				continue
+			}
+			seen.add(r)
+		}
 
-	if needsLower && !containsLower(p) {
-		return false
+		buf = append(buf, r)
 	}
+	return buf, nil
+}
 
-	if needsUpper && !containsUpper(p) {
-		return false
+// distinctRunes returns the number of distinct runes in set.
+func distinctRunes(set []rune) int {
+	var seen runeSet
+	n := 0
+	for _, r := range set {
+		if !seen.contains(r) {
+			seen.add(r)
+			n++
+		}
 	}
+	return n
+}
 
-	if needsDigit && !containsDigit(p) {
-		return false
+// shuffle permutes buf uniformly at random.
+func shuffle(src *randomSource, buf []rune) error {
+	for i := len(buf) - 1; i > 0; i-- {
+		j, err := src.intn(i + 1)
+		if err != nil {
+			return err
+		}
+		buf[i], buf[j] = buf[j], buf[i]
 	}
+	return nil
+}
 
-	if needsSymbol && !containsSymbol(p) {
-		return false
-	}
+// ContainsLower reports whether s contains any of the generator's lowercase
+// letters.
+func (g *StatefulGenerator) ContainsLower(s string) bool {
+	return g.lowerLetters.ContainsAny(s)
+}
 
-	return true
+// ContainsUpper reports whether s contains any of the generator's uppercase
+// letters.
+func (g *StatefulGenerator) ContainsUpper(s string) bool {
+	return g.upperLetters.ContainsAny(s)
 }
 
-func containsLower(s string) bool {
-	r := regexp.MustCompile(".*[[:lower:]].*")
-	return r.MatchString(s)
+// ContainsDigit reports whether s contains any of the generator's digits.
+func (g *StatefulGenerator) ContainsDigit(s string) bool {
+	return g.digits.ContainsAny(s)
 }
 
-func containsUpper(s string) bool {
-	r := regexp.MustCompile(".*[[:upper:]].*")
-	return r.MatchString(s)
+// ContainsSymbol reports whether s contains any of the generator's symbols.
+func (g *StatefulGenerator) ContainsSymbol(s string) bool {
+	return g.symbols.ContainsAny(s)
 }
 
-func containsDigit(s string) bool {
-	r := regexp.MustCompile(".*[[:digit:]].*")
-	return r.MatchString(s)
+// ContainsLower reports whether s contains any of the default lowercase
+// letters.
+func ContainsLower(s string) bool {
+	return defaultLowerLetters.ContainsAny(s)
 }
 
-func containsSymbol(s string) bool {
-	r := regexp.MustCompile(`[^a-zA-Z0-9]+`)
-	return r.MatchString(s)
+// ContainsUpper reports whether s contains any of the default uppercase
+// letters.
+func ContainsUpper(s string) bool {
+	return defaultUpperLetters.ContainsAny(s)
+}
+
+// ContainsDigit reports whether s contains any of the default digits.
+func ContainsDigit(s string) bool {
+	return defaultDigits.ContainsAny(s)
+}
+
+// ContainsSymbol reports whether s contains any of the default symbols.
+func ContainsSymbol(s string) bool {
+	return defaultSymbols.ContainsAny(s)
+}
+
+// canMeetPolicy reports whether some password with valid counts contains
+// every required class, so that retrying eventually succeeds. Each character is
+// reduced to the mask of required classes it belongs to; a class needs only one
+// character, so every group of picks contributes at most one character per
+// class to the masks that can be reached.
+func (g *StatefulGenerator) canMeetPolicy(length, numDigits, numSymbols int, includeUpper, needsLower, needsUpper, needsDigit, needsSymbol bool) bool {
+	var required []*Charset
+	for _, c := range []struct {
+		needs bool
+		set   *Charset
+	}{
+		{needsLower, g.lowerLetters},
+		{needsUpper, g.upperLetters},
+		{needsDigit, g.digits},
+		{needsSymbol, g.symbols},
+	} {
+		if c.needs {
+			required = append(required, c.set)
+		}
+	}
+
+	letters := g.lowerLetters.runes
+	if includeUpper {
+		letters = g.letters
+	}
+	groups := []struct {
+		set []rune
+		n   int
+	}{
+		{letters, length - numDigits - numSymbols},
+		{g.digits.runes, numDigits},
+		{g.symbols.runes, numSymbols},
+	}
+
+	var reachable [16]bool
+	reachable[0] = true
+	for _, group := range groups {
+		var masks [16]bool
+		for _, r := range group.set {
+			mask := 0
+			for i, set := range required {
+				if set.Contains(r) {
+					mask |= 1 << uint(i)
+				}
+			}
+			masks[mask] = true
+		}
+
+		for i := 0; i < group.n && i < len(required); i++ {
+			next := reachable
+			for m, ok := range reachable {
+				if !ok {
+					continue
+				}
+				for mask, ok := range masks {
+					if ok {
+						next[m|mask] = true
+					}
+				}
+			}
+			reachable = next
+		}
+	}
+	return reachable[1<<uint(len(required))-1]
+}
+
+func (g *StatefulGenerator) isLegalPassword(p string, needsLower, needsUpper, needsDigit, needsSymbol bool) bool {
+	if needsLower && !g.ContainsLower(p) {
+		return false
+	}
+
+	if needsUpper && !g.ContainsUpper(p) {
+		return false
+	}
+
+	if needsDigit && !g.ContainsDigit(p) {
+		return false
+	}
+
+	if needsSymbol && !g.ContainsSymbol(p) {
+		return false
+	}
+
+	return true
 }
@@ -1,10 +1,14 @@
 package password
 
 import (
+	"context"
+	"crypto/rand"
+	"errors"
 	"io"
 	"strings"
 	"sync/atomic"
 	"testing"
+	"time"
 )
 
 type (
@@ -24,6 +28,19 @@ func (mr *MockReader) Read(data []byte) (int, error) {
 	return len(data), nil
 }
 
+// maxInt is the largest int, on 32-bit as well as 64-bit platforms.
+const maxInt = int(^uint(0) >> 1)
+
+// zeroReader reads an endless stream of zero bytes.
+type zeroReader struct{}
+
+func (zeroReader) Read(data []byte) (int, error) {
+	for i := range data {
+		data[i] = 0
+	}
+	return len(data), nil
+}
+
 func testHasDuplicates(tb testing.TB, s string) bool {
 	found := make(map[rune]struct{}, len(s))
 	for _, ch := range s {
@@ -49,11 +66,11 @@ func testGeneratorGenerate(t *testing.T, reader io.Reader) {
 	t.Run("exceeds_length", func(t *testing.T) {
 		t.Parallel()
 
-		if _, err := gen.Generate(0, 1, 0, true, false); err != ErrExceedsTotalLength {
+		if _, err := gen.Generate(1, 2, 0, true, false); !errors.Is(err, ErrExceedsTotalLength) {
 			t.Errorf("expected %q to be %q", err, ErrExceedsTotalLength)
 		}
 
-		if _, err := gen.Generate(0, 0, 1, true, false); err != ErrExceedsTotalLength {
+		if _, err := gen.Generate(1, 0, 2, true, false); !errors.Is(err, ErrExceedsTotalLength) {
 			t.Errorf("expected %q to be %q", err, ErrExceedsTotalLength)
 		}
 	})
@@ -61,7 +78,7 @@ func testGeneratorGenerate(t *testing.T, reader io.Reader) {
 	t.Run("exceeds_letters_available", func(t *testing.T) {
 		t.Parallel()
 
-		if _, err := gen.Generate(1000, 0, 0, true, false); err != ErrLettersExceedsAvailable {
+		if _, err := gen.Generate(1000, 0, 0, true, false); !errors.Is(err, ErrLettersExceedsAvailable) {
 			t.Errorf("expected %q to be %q", err, ErrLettersExceedsAvailable)
 		}
 	})
@@ -69,7 +86,7 @@ func testGeneratorGenerate(t *testing.T, reader io.Reader) {
 	t.Run("exceeds_digits_available", func(t *testing.T) {
 		t.Parallel()
 
-		if _, err := gen.Generate(52, 11, 0, true, false); err != ErrDigitsExceedsAvailable {
+		if _, err := gen.Generate(52, 11, 0, true, false); !errors.Is(err, ErrDigitsExceedsAvailable) {
 			t.Errorf("expected %q to be %q", err, ErrDigitsExceedsAvailable)
 		}
 	})
@@ -77,7 +94,7 @@ func testGeneratorGenerate(t *testing.T, reader io.Reader) {
 	t.Run("exceeds_symbols_available", func(t *testing.T) {
 		t.Parallel()
 
-		if _, err := gen.Generate(52, 0, 31, true, false); err != ErrSymbolsExceedsAvailable {
+		if _, err := gen.Generate(52, 0, 31, true, false); !errors.Is(err, ErrSymbolsExceedsAvailable) {
 			t.Errorf("expected %q to be %q", err, ErrSymbolsExceedsAvailable)
 		}
 	})
@@ -86,7 +103,7 @@ func testGeneratorGenerate(t *testing.T, reader io.Reader) {
 		t.Parallel()
 
 		for i := 0; i < N; i++ {
-			res, err := gen.Generate(i%len(LowerLetters), 0, 0, false, true)
+			res, err := gen.Generate(i%len(LowerLetters)+1, 0, 0, false, true)
 			if err != nil {
 				t.Error(err)
 			}
@@ -180,7 +197,7 @@ func TestGenerator_Reader_Generate_Custom(t *testing.T) {
 	testGeneratorGenerateCustom(t, &MockReader{})
 }
 
-func Test_containsUpper(t *testing.T) {
+func TestContainsUpper(t *testing.T) {
 
 	var TestCases = []struct {
 		Name           string
@@ -200,7 +217,7 @@ func Test_containsUpper(t *testing.T) {
 	}
 
 	for _, test := range TestCases {
-		res := containsUpper(test.InputString)
+		res := ContainsUpper(test.InputString)
 
 		if res != test.ExpectedOutput {
 			t.Errorf("Testcase %s failed. want - %t, got - %t", test.Name, test.ExpectedOutput, res)
@@ -208,7 +225,7 @@ func Test_containsUpper(t *testing.T) {
 	}
 }
 
-func Test_containsLower(t *testing.T) {
+func TestContainsLower(t *testing.T) {
 
 	var TestCases = []struct {
 		Name           string
@@ -228,7 +245,7 @@ func Test_containsLower(t *testing.T) {
 	}
 
 	for _, test := range TestCases {
-		res := containsLower(test.InputString)
+		res := ContainsLower(test.InputString)
 
 		if res != test.ExpectedOutput {
 			t.Errorf("Testcase %s failed. want - %t, got - %t", test.Name, test.ExpectedOutput, res)
@@ -236,7 +253,7 @@ func Test_containsLower(t *testing.T) {
 	}
 }
 
-func Test_containsDigits(t *testing.T) {
+func TestContainsDigit(t *testing.T) {
 
 	var TestCases = []struct {
 		Name           string
@@ -256,7 +273,7 @@ func Test_containsDigits(t *testing.T) {
 	}
 
 	for _, test := range TestCases {
-		res := containsDigit(test.InputString)
+		res := ContainsDigit(test.InputString)
 
 		if res != test.ExpectedOutput {
 			t.Errorf("Testcase %s failed. want - %t, got - %t", test.Name, test.ExpectedOutput, res)
@@ -264,7 +281,7 @@ func Test_containsDigits(t *testing.T) {
 	}
 }
 
-func Test_containsSymbol(t *testing.T) {
+func TestContainsSymbol(t *testing.T) {
 
 	var TestCases = []struct {
 		Name           string
@@ -286,13 +303,18 @@ func Test_containsSymbol(t *testing.T) {
 			InputString:    "",
 			ExpectedOutput: false,
 		},
+		{
+			Name:           "Unconfigured Symbol",
+			InputString:    "mary§hadalittlelamb",
+			ExpectedOutput: false,
+		},
 	}
 
 	for _, tc := range TestCases {
 		tc := tc
 		t.Run(tc.Name, func(t *testing.T) {
 			t.Parallel()
-			res := containsSymbol(tc.InputString)
+			res := ContainsSymbol(tc.InputString)
 
 			if res != tc.ExpectedOutput {
 				t.Errorf("Testcase %s failed. want - %t, got - %t", tc.Name, tc.ExpectedOutput, res)
@@ -300,7 +322,12 @@ func Test_containsSymbol(t *testing.T) {
 		})
 	}
 }
-func Test_isLegalPassword(t *testing.T) {
+
+func TestGenerator_isLegalPassword(t *testing.T) {
+	gen, err := NewStatefulGenerator(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	var TestCases = []struct {
 		Name           string
@@ -425,7 +452,7 @@ func Test_isLegalPassword(t *testing.T) {
 		tc := tc
 		t.Run(tc.Name, func(t *testing.T) {
 			t.Parallel()
-			res := isLegalPassword(tc.Password, tc.NeedsLower, tc.NeedsUpper, tc.NeedsDigit, tc.NeedsSymbol)
+			res := gen.isLegalPassword(tc.Password, tc.NeedsLower, tc.NeedsUpper, tc.NeedsDigit, tc.NeedsSymbol)
 
 			if res != tc.ExpectedOutput {
 				t.Errorf("Testcase %s failed. want - %t, got - %t", tc.Name, tc.ExpectedOutput, res)
@@ -433,3 +460,348 @@ func Test_isLegalPassword(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerator_GenerateWithPolicy_Custom(t *testing.T) {
+	t.Parallel()
+
+	gen, err := NewStatefulGenerator(&GeneratorInput{
+		Symbols: "§",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if gen.ContainsSymbol("$") {
+		t.Errorf("%q should not be a symbol of the generator", "$")
+	}
+
+	for i := 0; i < 100; i++ {
+		res, err := gen.GenerateWithPolicy(8, 0, 1, false, false, true, false, false, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !strings.Contains(res, "§") {
+			t.Errorf("%q should contain the configured symbol", res)
+		}
+	}
+}
+
+// cancelReader cancels its context on every read after the first one.
+type cancelReader struct {
+	cancel context.CancelFunc
+	reads  int
+}
+
+func (cr *cancelReader) Read(data []byte) (int, error) {
+	cr.reads++
+	if cr.reads > 1 {
+		cr.cancel()
+	}
+	return rand.Read(data)
+}
+
+func TestGenerator_GenerateContext(t *testing.T) {
+	t.Parallel()
+
+	t.Run("canceled", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		gen, err := NewStatefulGenerator(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := gen.GenerateContext(ctx, 64, 10, 10, true, false); !errors.Is(err, context.Canceled) {
+			t.Errorf("expected %v to be %v", err, context.Canceled)
+		}
+	})
+
+	t.Run("canceled_midway", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		gen, err := NewStatefulGenerator(&GeneratorInput{
+			Reader: &cancelReader{cancel: cancel},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = gen.GenerateContext(ctx, 4096, 0, 0, true, true)
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected %v to be %v", err, context.Canceled)
+		}
+
+		if !strings.Contains(err.Error(), "of 4096 characters") {
+			t.Errorf("expected %q to report progress", err)
+		}
+	})
+
+	t.Run("not_canceled", func(t *testing.T) {
+		t.Parallel()
+
+		res, err := GenerateContext(context.Background(), 64, 10, 10, true, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(res) != 64 {
+			t.Errorf("%q should be 64 characters long", res)
+		}
+	})
+}
+
+func TestGenerator_GenerateWithPolicyContext(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// The reader always picks the first letter, so the policy is never met
+	// although it could be.
+	gen, err := NewStatefulGenerator(&GeneratorInput{Reader: zeroReader{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = gen.GenerateWithPolicyContext(ctx, 16, 2, 2, true, true, false, true, false, false)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected %v to be %v", err, context.DeadlineExceeded)
+	}
+
+	if !strings.Contains(err.Error(), "rejected attempts") {
+		t.Errorf("expected %q to report the number of attempts", err)
+	}
+}
+
+func TestGenerator_GenerateWithPolicy_unsatisfiable(t *testing.T) {
+	t.Parallel()
+
+	gen, err := NewStatefulGenerator(&GeneratorInput{
+		LowerLetters: "abc",
+		UpperLetters: "ABC",
+		Digits:       "012",
+		Symbols:      "a!",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var TestCases = []struct {
+		Name         string
+		Length       int
+		NumDigits    int
+		NumSymbols   int
+		IncludeUpper bool
+		NeedsLower   bool
+		NeedsUpper   bool
+		NeedsDigit   bool
+		NeedsSymbol  bool
+		Err          error
+	}{
+		{Name: "upper excluded", Length: 8, NeedsUpper: true, Err: ErrPolicyUnsatisfiable},
+		{Name: "no digits", Length: 8, NumSymbols: 2, NeedsDigit: true, Err: ErrPolicyUnsatisfiable},
+		{Name: "too short", Length: 1, IncludeUpper: true, NeedsLower: true, NeedsUpper: true, Err: ErrPolicyUnsatisfiable},
+		{Name: "too short for digits", Length: 2, NumDigits: 1, IncludeUpper: true, NeedsLower: true, NeedsUpper: true, NeedsDigit: true, Err: ErrPolicyUnsatisfiable},
+		{Name: "shared symbol", Length: 1, NumSymbols: 1, NeedsLower: true, NeedsSymbol: true},
+		{Name: "all classes", Length: 4, NumDigits: 1, NumSymbols: 1, IncludeUpper: true, NeedsLower: true, NeedsUpper: true, NeedsDigit: true, NeedsSymbol: true},
+	}
+
+	for _, tc := range TestCases {
+		tc := tc
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			_, err := gen.GenerateWithPolicyContext(ctx, tc.Length, tc.NumDigits, tc.NumSymbols, tc.IncludeUpper, true,
+				tc.NeedsLower, tc.NeedsUpper, tc.NeedsDigit, tc.NeedsSymbol)
+			if err != tc.Err {
+				t.Errorf("expected %q to be %q", err, tc.Err)
+			}
+		})
+	}
+}
+
+func TestGenerator_Generate_invalidCounts(t *testing.T) {
+	t.Parallel()
+
+	gen, err := NewStatefulGenerator(&GeneratorInput{
+		LowerLetters: "aab",
+		Digits:       "0b",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var TestCases = []struct {
+		Name        string
+		Length      int
+		NumDigits   int
+		NumSymbols  int
+		AllowRepeat bool
+		Err         error
+	}{
+		{Name: "negative length", Length: -1, Err: ErrNegativeCount},
+		{Name: "negative digits", Length: 4, NumDigits: -1, Err: ErrNegativeCount},
+		{Name: "negative symbols", Length: 4, NumSymbols: -1, AllowRepeat: true, Err: ErrNegativeCount},
+		{Name: "overflowing counts", Length: 4, NumDigits: maxInt/2 + 1, NumSymbols: maxInt/2 + 1, AllowRepeat: true, Err: ErrExceedsTotalLength},
+		{Name: "duplicate letters", Length: 3, Err: ErrLettersExceedsAvailable},
+		{Name: "shared digit", Length: 4, NumDigits: 2, Err: ErrDigitsExceedsAvailable},
+	}
+
+	for _, tc := range TestCases {
+		tc := tc
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := gen.Generate(tc.Length, tc.NumDigits, tc.NumSymbols, false, tc.AllowRepeat); !errors.Is(err, tc.Err) {
+				t.Errorf("expected %q to be %q", err, tc.Err)
+			}
+		})
+	}
+}
+
+func TestGenerator_Generate_ParameterError(t *testing.T) {
+	t.Parallel()
+
+	gen, err := NewStatefulGenerator(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var TestCases = []struct {
+		Name         string
+		Generator    *StatefulGenerator
+		Length       int
+		NumDigits    int
+		NumSymbols   int
+		IncludeUpper bool
+		Expected     ParameterError
+		Message      string
+	}{
+		{
+			Name: "zero length", Generator: gen, Length: 0,
+			Expected: ParameterError{Field: "length", Value: 0, Reason: "must be positive"},
+			Message:  "invalid length 0: must be positive",
+		},
+		{
+			Name: "negative length", Generator: gen, Length: -3,
+			Expected: ParameterError{Field: "length", Value: -3, Reason: "must be positive", err: ErrNegativeCount},
+			Message:  "invalid length -3: must be positive",
+		},
+		{
+			Name: "negative digits", Generator: gen, Length: 8, NumDigits: -1,
+			Expected: ParameterError{Field: "numDigits", Value: -1, Reason: "must not be negative", err: ErrNegativeCount},
+			Message:  "invalid numDigits -1: must not be negative",
+		},
+		{
+			Name: "negative symbols", Generator: gen, Length: 8, NumDigits: 1, NumSymbols: -1,
+			Expected: ParameterError{Field: "numSymbols", Value: -1, Reason: "must not be negative", err: ErrNegativeCount},
+			Message:  "invalid numSymbols -1: must not be negative",
+		},
+		{
+			Name: "empty letters", Generator: &StatefulGenerator{}, Length: 8,
+			Expected: ParameterError{Field: "LowerLetters", Value: "", Reason: "must not be empty"},
+			Message:  `invalid LowerLetters "": must not be empty`,
+		},
+		{
+			Name: "empty letters with upper", Generator: &StatefulGenerator{}, Length: 8, IncludeUpper: true,
+			Expected: ParameterError{Field: "LowerLetters/UpperLetters", Value: "", Reason: "must not be empty"},
+			Message:  `invalid LowerLetters/UpperLetters "": must not be empty`,
+		},
+		{
+			Name: "empty digits", Generator: &StatefulGenerator{lowerLetters: defaultLowerLetters, letters: defaultLowerLetters.runes}, Length: 8, NumDigits: 1,
+			Expected: ParameterError{Field: "Digits", Value: "", Reason: "must not be empty"},
+			Message:  `invalid Digits "": must not be empty`,
+		},
+	}
+
+	for _, tc := range TestCases {
+		tc := tc
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := tc.Generator.GenerateWithPolicy(tc.Length, tc.NumDigits, tc.NumSymbols, tc.IncludeUpper, true, false, false, false, false)
+
+			var pe *ParameterError
+			if !errors.As(err, &pe) {
+				t.Fatalf("expected %q to be a *ParameterError", err)
+			}
+			if *pe != tc.Expected {
+				t.Errorf("expected %#v to be %#v", *pe, tc.Expected)
+			}
+			if err.Error() != tc.Message {
+				t.Errorf("expected %q to be %q", err, tc.Message)
+			}
+			if errors.Is(err, ErrNegativeCount) != (tc.Expected.err != nil) {
+				t.Errorf("expected %q to wrap %v", err, tc.Expected.err)
+			}
+		})
+	}
+}
+
+func BenchmarkGenerator_isLegalPassword(b *testing.B) {
+	gen, err := NewStatefulGenerator(nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		gen.isLegalPassword("M4RYHADAlittleLAMB$xxxxxxxxxxxxxxxx", true, true, true, true)
+	}
+}
+
+func BenchmarkGenerator_GenerateWithPolicy(b *testing.B) {
+	gen, err := NewStatefulGenerator(nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := gen.GenerateWithPolicy(16, 2, 2, true, false, true, true, true, true); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchmarkGenerate(b *testing.B, length, numDigits, numSymbols int, allowRepeat bool) {
+	gen, err := NewStatefulGenerator(nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := gen.Generate(length, numDigits, numSymbols, true, allowRepeat); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGenerate(b *testing.B) {
+	b.Run("8", func(b *testing.B) {
+		benchmarkGenerate(b, 8, 2, 2, false)
+	})
+	b.Run("64", func(b *testing.B) {
+		benchmarkGenerate(b, 64, 10, 10, false)
+	})
+	b.Run("4096", func(b *testing.B) {
+		benchmarkGenerate(b, 4096, 512, 512, true)
+	})
+}
@@ -0,0 +1,75 @@
+package password
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Parameters for hashing secrets at rest, following the OWASP recommendation
+// for Argon2id. They are recorded in every hash, so changing them does not
+// invalidate existing hashes.
+const (
+	hashTime     = 2
+	hashMemory   = 19 * 1024
+	hashThreads  = 1
+	hashSaltSize = 16
+	hashKeySize  = 32
+)
+
+// ErrHashMalformed is the error returned when verifying against a hash that
+// was not produced by this package.
+var ErrHashMalformed = errors.New("hash is malformed")
+
+// hashSecret hashes secret with Argon2id and a random salt read from reader.
+// The result uses the PHC string format, e.g.
+// "$argon2id$v=19$m=19456,t=2,p=1$<salt>$<hash>".
+func hashSecret(reader io.Reader, secret []byte) (string, error) {
+	salt := make([]byte, hashSaltSize)
+	if _, err := io.ReadFull(reader, salt); err != nil {
+		return "", readerError(err, "salt")
+	}
+
+	key := argon2.IDKey(secret, salt, hashTime, hashMemory, hashThreads, hashKeySize)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, hashMemory, hashTime, hashThreads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// verifySecret reports whether secret matches a hash produced by hashSecret.
+// The comparison takes constant time.
+func verifySecret(secret []byte, encoded string) (bool, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[0] != "" || parts[1] != "argon2id" {
+		return false, ErrHashMalformed
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, ErrHashMalformed
+	}
+
+	var memory, time uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil ||
+		memory == 0 || time == 0 || threads == 0 {
+		return false, ErrHashMalformed
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, ErrHashMalformed
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return false, ErrHashMalformed
+	}
+
+	other := argon2.IDKey(secret, salt, time, memory, threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, other) == 1, nil
+}
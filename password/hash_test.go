@@ -0,0 +1,56 @@
+package password
+
+import (
+	"crypto/rand"
+	"strings"
+	"testing"
+)
+
+func TestHashSecret(t *testing.T) {
+	t.Parallel()
+
+	h, err := hashSecret(rand.Reader, []byte("hunter2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(h, "$argon2id$v=19$m=19456,t=2,p=1$") {
+		t.Errorf("unexpected hash format %q", h)
+	}
+
+	other, err := hashSecret(rand.Reader, []byte("hunter2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h == other {
+		t.Errorf("hashes of the same secret should be salted differently")
+	}
+
+	for secret, want := range map[string]bool{"hunter2": true, "hunter3": false, "": false} {
+		ok, err := verifySecret([]byte(secret), h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != want {
+			t.Errorf("verifySecret(%q) = %t, want %t", secret, ok, want)
+		}
+	}
+}
+
+func TestVerifySecret_malformed(t *testing.T) {
+	t.Parallel()
+
+	for _, h := range []string{
+		"",
+		"hunter2",
+		"$argon2i$v=19$m=19456,t=2,p=1$c2FsdHNhbHRzYWx0$a2V5",
+		"$argon2id$v=16$m=19456,t=2,p=1$c2FsdHNhbHRzYWx0$a2V5",
+		"$argon2id$v=19$m=0,t=2,p=1$c2FsdHNhbHRzYWx0$a2V5",
+		"$argon2id$v=19$m=19456,t=2,p=1$!!!$a2V5",
+		"$argon2id$v=19$m=19456,t=2,p=1$c2FsdHNhbHRzYWx0$",
+	} {
+		if _, err := verifySecret([]byte("hunter2"), h); err != ErrHashMalformed {
+			t.Errorf("verifySecret(%q): expected %v to be %v", h, err, ErrHashMalformed)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+package password
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Built-time checks that the histories and generators implement the
+// interfaces.
+var (
+	_ History   = (*MemoryHistory)(nil)
+	_ History   = (*FileHistory)(nil)
+	_ Generator = (*HistoryGenerator)(nil)
+)
+
+const (
+	// DefaultHistorySize is the number of passwords a history remembers
+	// unless configured otherwise. It matches the maximum password history of
+	// Active Directory.
+	DefaultHistorySize = 24
+)
+
+// ErrPasswordReused is the error returned by CheckReuse when the candidate is
+// one of the remembered passwords.
+var ErrPasswordReused = errors.New("password was used before")
+
+// History remembers the last passwords of a credential, so that rotation can
+// refuse to reuse them. Implementations store salted hashes only.
+type History interface {
+	// CheckReuse returns ErrPasswordReused if candidate is one of the
+	// remembered passwords.
+	CheckReuse(candidate string) error
+
+	// Record remembers password, forgetting the oldest one once the history
+	// is full.
+	Record(password string) error
+}
+
+// historySize returns size, or DefaultHistorySize if size is zero.
+func historySize(size int) (int, error) {
+	switch {
+	case size < 0:
+		return 0, &ParameterError{Field: "size", Value: size, Reason: "must not be negative"}
+	case size == 0:
+		return DefaultHistorySize, nil
+	}
+	return size, nil
+}
+
+// checkReuse returns ErrPasswordReused if candidate matches any of hashes.
+func checkReuse(candidate string, hashes []string) error {
+	for _, h := range hashes {
+		ok, err := verifySecret([]byte(candidate), h)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return ErrPasswordReused
+		}
+	}
+	return nil
+}
+
+// recordHash hashes password and appends it to hashes, keeping the last size
+// hashes.
+func recordHash(reader io.Reader, password string, hashes []string, size int) ([]string, error) {
+	h, err := hashSecret(reader, []byte(password))
+	if err != nil {
+		return nil, err
+	}
+
+	hashes = append(hashes, h)
+	if len(hashes) > size {
+		hashes = hashes[len(hashes)-size:]
+	}
+	return hashes, nil
+}
+
+// MemoryHistory is a History kept in memory. It is safe for concurrent use.
+type MemoryHistory struct {
+	size   int
+	reader io.Reader
+
+	mu     sync.Mutex
+	hashes []string // oldest first
+}
+
+// NewMemoryHistory creates an empty MemoryHistory that remembers size
+// passwords, or DefaultHistorySize if size is zero. Salts are read from
+// reader, or rand.Reader if reader is nil.
+func NewMemoryHistory(size int, reader io.Reader) (*MemoryHistory, error) {
+	size, err := historySize(size)
+	if err != nil {
+		return nil, err
+	}
+
+	if reader == nil {
+		reader = rand.Reader
+	}
+
+	return &MemoryHistory{size: size, reader: reader}, nil
+}
+
+// CheckReuse returns ErrPasswordReused if candidate is one of the remembered
+// passwords.
+func (h *MemoryHistory) CheckReuse(candidate string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return checkReuse(candidate, h.hashes)
+}
+
+// Record remembers password, forgetting the oldest one once the history is
+// full.
+func (h *MemoryHistory) Record(password string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	hashes, err := recordHash(h.reader, password, h.hashes, h.size)
+	if err != nil {
+		return err
+	}
+	h.hashes = hashes
+	return nil
+}
+
+// FileHistory is a History kept in a local file with one hash per line,
+// oldest first. The file is read on every call and replaced atomically on
+// every Record, so it survives restarts and crashes. FileHistory is safe for
+// concurrent use within a process; processes sharing a file must coordinate
+// their calls to Record.
+type FileHistory struct {
+	path   string
+	size   int
+	reader io.Reader
+
+	mu sync.Mutex
+}
+
+// OpenFileHistory returns a FileHistory stored at path that remembers size
+// passwords, or DefaultHistorySize if size is zero. A missing file is an empty
+// history and is created by the first Record. Salts are read from reader, or
+// rand.Reader if reader is nil.
+func OpenFileHistory(path string, size int, reader io.Reader) (*FileHistory, error) {
+	size, err := historySize(size)
+	if err != nil {
+		return nil, err
+	}
+
+	if reader == nil {
+		reader = rand.Reader
+	}
+
+	h := &FileHistory{path: path, size: size, reader: reader}
+	if _, err := h.load(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// load reads the hashes from the file.
+func (h *FileHistory) load() ([]string, error) {
+	data, err := ioutil.ReadFile(h.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var hashes []string
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "$argon2id$") {
+			return nil, fmt.Errorf("%s:%d: %w", h.path, n, ErrHashMalformed)
+		}
+		hashes = append(hashes, line)
+	}
+	return hashes, s.Err()
+}
+
+// CheckReuse returns ErrPasswordReused if candidate is one of the remembered
+// passwords.
+func (h *FileHistory) CheckReuse(candidate string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	hashes, err := h.load()
+	if err != nil {
+		return err
+	}
+	return checkReuse(candidate, hashes)
+}
+
+// Record remembers password, forgetting the oldest one once the history is
+// full. The file is written to a temporary file in the same directory, which
+// then replaces it.
+func (h *FileHistory) Record(password string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	hashes, err := h.load()
+	if err != nil {
+		return err
+	}
+	if hashes, err = recordHash(h.reader, password, hashes, h.size); err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(h.path), filepath.Base(h.path)+".*")
+	if err != nil {
+		return err
+	}
+
+	err = writeHistory(f, hashes)
+	if err == nil {
+		err = os.Rename(f.Name(), h.path)
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+	}
+	return err
+}
+
+// writeHistory writes hashes to f, one per line, and closes it.
+func writeHistory(f *os.File, hashes []string) error {
+	if _, err := io.WriteString(f, strings.Join(hashes, "\n")+"\n"); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// HistoryGenerator is a Generator that never returns a password remembered by
+// its History. It is a ValidatingGenerator whose validator calls CheckReuse,
+// so it fails with an error wrapping ErrPasswordReused if 100 candidates in a
+// row were used before, which only happens for tiny passwords or broken
+// generators. Reused candidates are rejected with a *ValidationError for the
+// "history" rule. It does not record the passwords it returns: call Record
+// once the new password is in use.
+type HistoryGenerator struct {
+	*ValidatingGenerator
+	history History
+}
+
+// NewHistoryGenerator creates a HistoryGenerator drawing from gen and
+// checking against history.
+func NewHistoryGenerator(gen Generator, history History) *HistoryGenerator {
+	return &HistoryGenerator{
+		ValidatingGenerator: NewValidatingGenerator(gen, ValidatorFunc(func(candidate string) error {
+			err := history.CheckReuse(candidate)
+			if err == ErrPasswordReused {
+				return &ValidationError{Rule: "history", Message: "was used before", err: err}
+			}
+			return err
+		})),
+		history: history,
+	}
+}
+
+// History returns the history the generator checks against.
+func (g *HistoryGenerator) History() History {
+	return g.history
+}
@@ -0,0 +1,152 @@
+package password
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fullHistory is a History that remembers every password.
+type fullHistory struct{}
+
+func (fullHistory) CheckReuse(string) error { return ErrPasswordReused }
+func (fullHistory) Record(string) error     { return nil }
+
+func testHistory(t *testing.T, h History) {
+	t.Helper()
+
+	for _, pw := range []string{"first", "second", "third"} {
+		if err := h.CheckReuse(pw); err != nil {
+			t.Fatalf("expected %q to be unused, got %q", pw, err)
+		}
+		if err := h.Record(pw); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var TestCases = []struct {
+		Candidate string
+		Err       error
+	}{
+		{Candidate: "first"}, // forgotten, the history keeps two passwords
+		{Candidate: "second", Err: ErrPasswordReused},
+		{Candidate: "third", Err: ErrPasswordReused},
+		{Candidate: "Third"},
+	}
+
+	for _, tc := range TestCases {
+		if err := h.CheckReuse(tc.Candidate); err != tc.Err {
+			t.Errorf("%q: expected %q to be %q", tc.Candidate, err, tc.Err)
+		}
+	}
+}
+
+func TestMemoryHistory(t *testing.T) {
+	t.Parallel()
+
+	h, err := NewMemoryHistory(2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testHistory(t, h)
+
+	for _, stored := range h.hashes {
+		if !strings.HasPrefix(stored, "$argon2id$") {
+			t.Errorf("expected %q to be a hash", stored)
+		}
+	}
+}
+
+func TestFileHistory(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "history")
+	h, err := OpenFileHistory(path, 2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testHistory(t, h)
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 2 {
+		t.Errorf("expected %d hashes to be 2", len(lines))
+	}
+	if strings.Contains(string(data), "third") {
+		t.Errorf("expected %q not to contain the password", data)
+	}
+
+	// The history survives reopening the file.
+	h, err = OpenFileHistory(path, 2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.CheckReuse("third"); err != ErrPasswordReused {
+		t.Errorf("expected %q to be %q", err, ErrPasswordReused)
+	}
+}
+
+func TestOpenFileHistory_errors(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "history")
+	if err := ioutil.WriteFile(path, []byte("$argon2id$v=19$m=19456,t=2,p=1$c2FsdA$a2V5\nhunter2\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := OpenFileHistory(path, 0, nil); !errors.Is(err, ErrHashMalformed) || !strings.Contains(err.Error(), ":2:") {
+		t.Errorf("expected %q to be %q on line 2", err, ErrHashMalformed)
+	}
+
+	var pe *ParameterError
+	if _, err := OpenFileHistory(filepath.Join(dir, "other"), -1, nil); !errors.As(err, &pe) {
+		t.Errorf("expected %q to be a *ParameterError", err)
+	}
+	if _, err := NewMemoryHistory(-1, nil); !errors.As(err, &pe) {
+		t.Errorf("expected %q to be a *ParameterError", err)
+	}
+}
+
+func TestHistoryGenerator(t *testing.T) {
+	t.Parallel()
+
+	history, err := NewMemoryHistory(0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := history.Record("old"); err != nil {
+		t.Fatal(err)
+	}
+
+	mock := NewMockPasswordGenerator("new", nil).Queue("old", nil).Queue("old", nil)
+	gen := NewHistoryGenerator(mock, history)
+
+	if res := gen.MustGenerate(16, 2, 2, true, false); res != "new" {
+		t.Errorf("expected %q to be %q", res, "new")
+	}
+	if n := len(mock.Calls()); n != 3 {
+		t.Errorf("expected %d calls to be 3", n)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := gen.GenerateWithPolicyContext(ctx, 16, 2, 2, true, false, true, true, true, true); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %q to be %q", err, context.Canceled)
+	}
+
+	stuck := NewHistoryGenerator(NewMockPasswordGenerator("old", nil), fullHistory{})
+	if _, err := stuck.Generate(16, 2, 2, true, false); !errors.Is(err, ErrPasswordReused) {
+		t.Errorf("expected %q to be %q", err, ErrPasswordReused)
+	}
+
+	failing := NewHistoryGenerator(NewMockPasswordGenerator("", ErrExceedsTotalLength), history)
+	if _, err := failing.GenerateWithPolicy(1, 2, 0, true, false, false, false, false, false); err != ErrExceedsTotalLength {
+		t.Errorf("expected %q to be %q", err, ErrExceedsTotalLength)
+	}
+}
@@ -1,18 +1,115 @@
 package password
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
 // Built-time checks that the generators implement the interface.
 var _ Generator = (*MockPasswordGenerator)(nil)
 
+// ErrMockUnexpectedCall is the error wrapped by AssertExpectations for calls
+// that matched no expectation.
+var ErrMockUnexpectedCall = errors.New("unexpected call")
+
+// MockCall is a recorded call to a MockPasswordGenerator. The Needs fields
+// are only set for the GenerateWithPolicy methods.
+type MockCall struct {
+	Method       string
+	Length       int
+	NumDigits    int
+	NumSymbols   int
+	IncludeUpper bool
+	AllowRepeat  bool
+	NeedsLower   bool
+	NeedsUpper   bool
+	NeedsDigit   bool
+	NeedsSymbol  bool
+}
+
+// String formats the call like Go source.
+func (c MockCall) String() string {
+	args := fmt.Sprintf("%d, %d, %d, %t, %t", c.Length, c.NumDigits, c.NumSymbols, c.IncludeUpper, c.AllowRepeat)
+	if c.policy() {
+		args += fmt.Sprintf(", %t, %t, %t, %t", c.NeedsLower, c.NeedsUpper, c.NeedsDigit, c.NeedsSymbol)
+	}
+	return c.Method + "(" + args + ")"
+}
+
+// policy reports whether the call is to one of the GenerateWithPolicy
+// methods.
+func (c MockCall) policy() bool {
+	return strings.HasPrefix(c.Method, "GenerateWithPolicy")
+}
+
+// matches reports whether c has the arguments of the expected call e.
+// Generate expectations match Generate, GenerateContext and MustGenerate;
+// GenerateWithPolicy expectations match both policy methods.
+func (c MockCall) matches(e MockCall) bool {
+	if c.policy() != e.policy() {
+		return false
+	}
+	c.Method = e.Method
+	return c == e
+}
+
+// MockExpectation is an expected call registered with ExpectGenerate or
+// ExpectGenerateWithPolicy.
+type MockExpectation struct {
+	call   MockCall
+	result string
+	err    error
+	times  int
+	calls  int
+}
+
+// Return sets the result and error returned for the expected call.
+func (e *MockExpectation) Return(result string, err error) *MockExpectation {
+	e.result = result
+	e.err = err
+	return e
+}
+
+// Times sets how many calls the expectation covers. The default is one.
+func (e *MockExpectation) Times(n int) *MockExpectation {
+	e.times = n
+	return e
+}
+
+// TestingT is the subset of testing.TB used by AssertExpectations.
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// mockResult is a queued result.
+type mockResult struct {
+	result string
+	err    error
+}
+
 // MockPasswordGenerator is a generator that satisfies the Generator interface.
+// Every call is answered by the first matching expectation that is not yet
+// used up, else by the next queued result, else by the result given to
+// NewMockPasswordGenerator. It is safe for concurrent use.
 type MockPasswordGenerator struct {
 	result string
 	err    error
+
+	mu           sync.Mutex
+	queue        []mockResult
+	expectations []*MockExpectation
+	calls        []MockCall
+	unexpected   []MockCall
 }
 
 // NewMockPasswordGenerator creates a new mock generator. If an error is
 // provided, the error is returned. If a result if provided, the result is
 // always returned, regardless of what parameters are passed into the Generate
-// or MustGenerate methods.
+// or MustGenerate methods, unless Queue or an expectation answers the call.
 //
 // This function is most useful for tests where you want to have predicable
 // results for a transitive resource that depends on the password package.
@@ -23,26 +120,171 @@ func NewMockPasswordGenerator(result string, err error) *MockPasswordGenerator {
 	}
 }
 
-// Generate returns the mocked result or error.
-func (g *MockPasswordGenerator) Generate(int, int, int, bool, bool) (string, error) {
-	if g.err != nil {
-		return "", g.err
+// Queue appends a result or error that is returned once, after all results
+// queued before it, by a call without a matching expectation.
+func (g *MockPasswordGenerator) Queue(result string, err error) *MockPasswordGenerator {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.queue = append(g.queue, mockResult{result: result, err: err})
+	return g
+}
+
+// ExpectGenerate expects a call to Generate, GenerateContext or MustGenerate
+// with the given arguments.
+func (g *MockPasswordGenerator) ExpectGenerate(length, numDigits, numSymbols int, includeUpper, allowRepeat bool) *MockExpectation {
+	return g.expect(MockCall{
+		Method:       "Generate",
+		Length:       length,
+		NumDigits:    numDigits,
+		NumSymbols:   numSymbols,
+		IncludeUpper: includeUpper,
+		AllowRepeat:  allowRepeat,
+	})
+}
+
+// ExpectGenerateWithPolicy expects a call to GenerateWithPolicy or
+// GenerateWithPolicyContext with the given arguments.
+func (g *MockPasswordGenerator) ExpectGenerateWithPolicy(length, numDigits, numSymbols int, includeUpper, allowRepeat, needsLower, needsUpper, needsDigit, needsSymbol bool) *MockExpectation {
+	return g.expect(MockCall{
+		Method:       "GenerateWithPolicy",
+		Length:       length,
+		NumDigits:    numDigits,
+		NumSymbols:   numSymbols,
+		IncludeUpper: includeUpper,
+		AllowRepeat:  allowRepeat,
+		NeedsLower:   needsLower,
+		NeedsUpper:   needsUpper,
+		NeedsDigit:   needsDigit,
+		NeedsSymbol:  needsSymbol,
+	})
+}
+
+func (g *MockPasswordGenerator) expect(call MockCall) *MockExpectation {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	e := &MockExpectation{call: call, times: 1}
+	g.expectations = append(g.expectations, e)
+	return e
+}
+
+// Calls returns all calls made so far, in order.
+func (g *MockPasswordGenerator) Calls() []MockCall {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return append([]MockCall(nil), g.calls...)
+}
+
+// AssertExpectations reports every expectation that was called fewer times
+// than expected and every call that matched no expectation, if any
+// expectations were registered. It returns whether there were no failures.
+func (g *MockPasswordGenerator) AssertExpectations(t TestingT) bool {
+	t.Helper()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ok := true
+	for _, e := range g.expectations {
+		if e.calls < e.times {
+			t.Errorf("expected %v to be called %d times, got %d", e.call, e.times, e.calls)
+			ok = false
+		}
+	}
+	for _, call := range g.unexpected {
+		t.Errorf("%v: %v", ErrMockUnexpectedCall, call)
+		ok = false
 	}
-	return g.result, nil
+	return ok
 }
 
-// GenerateWithPolicy returns the mocked result or error.
-func (g *MockPasswordGenerator) GenerateWithPolicy(int, int, int, bool, bool, bool, bool, bool, bool) (string, error) {
-	if g.err != nil {
-		return "", g.err
+// call records call and returns its result.
+func (g *MockPasswordGenerator) call(call MockCall) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.calls = append(g.calls, call)
+
+	for _, e := range g.expectations {
+		if e.calls < e.times && call.matches(e.call) {
+			e.calls++
+			return e.result, e.err
+		}
+	}
+	if len(g.expectations) > 0 {
+		g.unexpected = append(g.unexpected, call)
+	}
+
+	if len(g.queue) > 0 {
+		next := g.queue[0]
+		g.queue = g.queue[1:]
+		return next.result, next.err
 	}
-	return g.result, nil
+	return g.result, g.err
+}
+
+func (g *MockPasswordGenerator) generate(method string, length, numDigits, numSymbols int, includeUpper, allowRepeat bool) (string, error) {
+	result, err := g.call(MockCall{
+		Method:       method,
+		Length:       length,
+		NumDigits:    numDigits,
+		NumSymbols:   numSymbols,
+		IncludeUpper: includeUpper,
+		AllowRepeat:  allowRepeat,
+	})
+	if err != nil {
+		return "", err
+	}
+	return result, nil
+}
+
+func (g *MockPasswordGenerator) generateWithPolicy(method string, length, numDigits, numSymbols int, includeUpper, allowRepeat, needsLower, needsUpper, needsDigit, needsSymbol bool) (string, error) {
+	result, err := g.call(MockCall{
+		Method:       method,
+		Length:       length,
+		NumDigits:    numDigits,
+		NumSymbols:   numSymbols,
+		IncludeUpper: includeUpper,
+		AllowRepeat:  allowRepeat,
+		NeedsLower:   needsLower,
+		NeedsUpper:   needsUpper,
+		NeedsDigit:   needsDigit,
+		NeedsSymbol:  needsSymbol,
+	})
+	if err != nil {
+		return "", err
+	}
+	return result, nil
+}
+
+// Generate returns the mocked result or error.
+func (g *MockPasswordGenerator) Generate(length, numDigits, numSymbols int, includeUpper, allowRepeat bool) (string, error) {
+	return g.generate("Generate", length, numDigits, numSymbols, includeUpper, allowRepeat)
+}
+
+// GenerateWithPolicy returns the mocked result or error.
+func (g *MockPasswordGenerator) GenerateWithPolicy(length, numDigits, numSymbols int, includeUpper, allowRepeat, needsLower, needsUpper, needsDigit, needsSymbol bool) (string, error) {
+	return g.generateWithPolicy("GenerateWithPolicy", length, numDigits, numSymbols, includeUpper, allowRepeat, needsLower, needsUpper, needsDigit, needsSymbol)
 }
 
 // MustGenerate returns the mocked result or panics if an error was given.
-func (g *MockPasswordGenerator) MustGenerate(int, int, int, bool, bool) string {
-	if g.err != nil {
-		panic(g.err)
+func (g *MockPasswordGenerator) MustGenerate(length, numDigits, numSymbols int, includeUpper, allowRepeat bool) string {
+	res, err := g.generate("MustGenerate", length, numDigits, numSymbols, includeUpper, allowRepeat)
+	if err != nil {
+		panic(err)
 	}
-	return g.result
+	return res
+}
+
+// GenerateContext returns the mocked result or error. The context is ignored.
+func (g *MockPasswordGenerator) GenerateContext(_ context.Context, length, numDigits, numSymbols int, includeUpper, allowRepeat bool) (string, error) {
+	return g.generate("GenerateContext", length, numDigits, numSymbols, includeUpper, allowRepeat)
+}
+
+// GenerateWithPolicyContext returns the mocked result or error. The context is
+// ignored.
+func (g *MockPasswordGenerator) GenerateWithPolicyContext(_ context.Context, length, numDigits, numSymbols int, includeUpper, allowRepeat, needsLower, needsUpper, needsDigit, needsSymbol bool) (string, error) {
+	return g.generateWithPolicy("GenerateWithPolicyContext", length, numDigits, numSymbols, includeUpper, allowRepeat, needsLower, needsUpper, needsDigit, needsSymbol)
 }
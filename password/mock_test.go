@@ -0,0 +1,152 @@
+package password
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// recordingT records the failures reported by AssertExpectations.
+type recordingT struct {
+	errors []string
+}
+
+func (t *recordingT) Helper() {}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestMockPasswordGenerator_fixed(t *testing.T) {
+	t.Parallel()
+
+	gen := NewMockPasswordGenerator("canned", nil)
+	for i := 0; i < 3; i++ {
+		if res, err := gen.Generate(i, 0, 0, false, false); res != "canned" || err != nil {
+			t.Errorf("expected %q, %v to be %q, <nil>", res, err, "canned")
+		}
+	}
+
+	gen = NewMockPasswordGenerator("canned", ErrExceedsTotalLength)
+	if res, err := gen.GenerateWithPolicy(1, 2, 3, false, false, true, true, true, true); res != "" || err != ErrExceedsTotalLength {
+		t.Errorf("expected %q, %q to be \"\", %q", res, err, ErrExceedsTotalLength)
+	}
+}
+
+func TestMockPasswordGenerator_Queue(t *testing.T) {
+	t.Parallel()
+
+	errTemporary := errors.New("temporary")
+	gen := NewMockPasswordGenerator("fallback", nil).
+		Queue("", errTemporary).
+		Queue("first", nil).
+		Queue("second", nil)
+
+	var TestCases = []struct {
+		Result string
+		Err    error
+	}{
+		{Err: errTemporary},
+		{Result: "first"},
+		{Result: "second"},
+		{Result: "fallback"},
+	}
+
+	for _, tc := range TestCases {
+		res, err := gen.GenerateContext(context.Background(), 16, 2, 2, true, false)
+		if res != tc.Result || err != tc.Err {
+			t.Errorf("expected %q, %v to be %q, %v", res, err, tc.Result, tc.Err)
+		}
+	}
+
+	if !gen.AssertExpectations(t) {
+		t.Errorf("expected no failures without expectations")
+	}
+}
+
+func TestMockPasswordGenerator_Expect(t *testing.T) {
+	t.Parallel()
+
+	gen := NewMockPasswordGenerator("fallback", nil)
+	gen.ExpectGenerate(64, 10, 10, false, false).Return("sixty-four", nil)
+	gen.ExpectGenerate(8, 1, 1, true, true).Return("", ErrExceedsTotalLength).Times(2)
+	gen.ExpectGenerateWithPolicy(16, 2, 2, true, false, true, true, true, true).Return("policy", nil)
+
+	if res := gen.MustGenerate(64, 10, 10, false, false); res != "sixty-four" {
+		t.Errorf("expected %q to be %q", res, "sixty-four")
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := gen.Generate(8, 1, 1, true, true); err != ErrExceedsTotalLength {
+			t.Errorf("expected %q to be %q", err, ErrExceedsTotalLength)
+		}
+	}
+	if res, _ := gen.GenerateWithPolicyContext(context.Background(), 16, 2, 2, true, false, true, true, true, true); res != "policy" {
+		t.Errorf("expected %q to be %q", res, "policy")
+	}
+
+	if !gen.AssertExpectations(t) {
+		t.Errorf("expected all expectations to be met")
+	}
+
+	expected := []MockCall{
+		{Method: "MustGenerate", Length: 64, NumDigits: 10, NumSymbols: 10},
+		{Method: "Generate", Length: 8, NumDigits: 1, NumSymbols: 1, IncludeUpper: true, AllowRepeat: true},
+		{Method: "Generate", Length: 8, NumDigits: 1, NumSymbols: 1, IncludeUpper: true, AllowRepeat: true},
+		{Method: "GenerateWithPolicyContext", Length: 16, NumDigits: 2, NumSymbols: 2, IncludeUpper: true, NeedsLower: true, NeedsUpper: true, NeedsDigit: true, NeedsSymbol: true},
+	}
+	if calls := gen.Calls(); !reflect.DeepEqual(calls, expected) {
+		t.Errorf("expected %v to be %v", calls, expected)
+	}
+}
+
+func TestMockPasswordGenerator_AssertExpectations(t *testing.T) {
+	t.Parallel()
+
+	gen := NewMockPasswordGenerator("fallback", nil)
+	gen.ExpectGenerate(64, 10, 10, false, false).Return("sixty-four", nil).Times(2)
+
+	gen.Generate(64, 10, 10, false, false)
+	if res, _ := gen.Generate(32, 10, 10, false, false); res != "fallback" {
+		t.Errorf("expected %q to be %q", res, "fallback")
+	}
+	gen.GenerateWithPolicy(64, 10, 10, false, false, false, false, false, false)
+
+	rt := &recordingT{}
+	if gen.AssertExpectations(rt) {
+		t.Errorf("expected failures")
+	}
+
+	expected := []string{
+		"expected Generate(64, 10, 10, false, false) to be called 2 times, got 1",
+		"unexpected call: Generate(32, 10, 10, false, false)",
+		"unexpected call: GenerateWithPolicy(64, 10, 10, false, false, false, false, false, false)",
+	}
+	if !reflect.DeepEqual(rt.errors, expected) {
+		t.Errorf("expected %q to be %q", rt.errors, expected)
+	}
+}
+
+func TestMockPasswordGenerator_concurrent(t *testing.T) {
+	t.Parallel()
+
+	gen := NewMockPasswordGenerator("fallback", nil)
+	gen.ExpectGenerate(8, 0, 0, false, false).Return("eight", nil).Times(50)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			gen.Generate(8, 0, 0, false, false)
+		}()
+	}
+	wg.Wait()
+
+	gen.AssertExpectations(t)
+	if n := len(gen.Calls()); n != 50 {
+		t.Errorf("expected %d calls to be 50", n)
+	}
+}
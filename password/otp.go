@@ -0,0 +1,376 @@
+package password
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultOTPSecretSize is the size in bytes of generated OTP secrets, the
+	// 160 bits recommended by RFC 4226.
+	DefaultOTPSecretSize = 20
+
+	// MinOTPSecretSize is the smallest secret size in bytes RFC 4226 permits.
+	MinOTPSecretSize = 16
+
+	// DefaultOTPDigits is the number of digits of a one-time password.
+	DefaultOTPDigits = 6
+
+	// DefaultOTPPeriod is the time step of TOTP.
+	DefaultOTPPeriod = 30 * time.Second
+)
+
+// OTPAlgorithm is the HMAC hash function used to compute one-time passwords.
+type OTPAlgorithm int
+
+const (
+	// OTPSHA1 is HMAC-SHA-1, the default and most widely supported.
+	OTPSHA1 OTPAlgorithm = iota
+
+	// OTPSHA256 is HMAC-SHA-256.
+	OTPSHA256
+
+	// OTPSHA512 is HMAC-SHA-512.
+	OTPSHA512
+)
+
+// String returns the name of the algorithm as used in otpauth URIs.
+func (a OTPAlgorithm) String() string {
+	switch a {
+	case OTPSHA1:
+		return "SHA1"
+	case OTPSHA256:
+		return "SHA256"
+	case OTPSHA512:
+		return "SHA512"
+	default:
+		return "unknown"
+	}
+}
+
+// hash returns the hash function of the algorithm.
+func (a OTPAlgorithm) hash() func() hash.Hash {
+	switch a {
+	case OTPSHA256:
+		return sha256.New
+	case OTPSHA512:
+		return sha512.New
+	default:
+		return sha1.New
+	}
+}
+
+var (
+	// ErrOTPSecretTooShort is the error returned when an OTP secret is
+	// shorter than MinOTPSecretSize.
+	ErrOTPSecretTooShort = errors.New("otp secret must be at least 16 bytes")
+
+	// ErrOTPDigitsInvalid is the error returned when the number of digits of
+	// a one-time password is not between 6 and 8.
+	ErrOTPDigitsInvalid = errors.New("otp digits must be between 6 and 8")
+
+	// ErrOTPPeriodInvalid is the error returned when the TOTP period is not a
+	// positive number of seconds.
+	ErrOTPPeriodInvalid = errors.New("otp period must be a positive number of seconds")
+
+	// ErrOTPAlgorithmUnknown is the error returned for an unknown algorithm.
+	ErrOTPAlgorithmUnknown = errors.New("unknown otp algorithm")
+
+	// ErrOTPTimeInvalid is the error returned for a TOTP time before the Unix
+	// epoch, which has no time step.
+	ErrOTPTimeInvalid = errors.New("otp time must not be before 1970")
+)
+
+// otpEncoding is the unpadded base32 encoding authenticator apps expect.
+var otpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// OTPInput is used as input to the GenerateOTPKey function.
+type OTPInput struct {
+	Issuer     string
+	Account    string
+	SecretSize int           // DefaultOTPSecretSize by default
+	Algorithm  OTPAlgorithm  // OTPSHA1 by default
+	Digits     int           // DefaultOTPDigits by default
+	Period     time.Duration // DefaultOTPPeriod by default, TOTP only
+	Reader     io.Reader     // rand.Reader by default
+}
+
+// OTPKey is a shared secret for HOTP (RFC 4226) and TOTP (RFC 6238) along
+// with its parameters. Create one with GenerateOTPKey, or fill in the fields
+// of a secret provisioned elsewhere. Methods computing or verifying codes
+// return ErrOTPDigitsInvalid, ErrOTPPeriodInvalid or ErrOTPAlgorithmUnknown
+// for invalid parameters.
+type OTPKey struct {
+	Issuer    string
+	Account   string
+	Secret    []byte
+	Algorithm OTPAlgorithm
+	Digits    int           // DefaultOTPDigits if zero
+	Period    time.Duration // DefaultOTPPeriod if zero, TOTP only
+}
+
+// GenerateOTPKey generates a new random shared secret from the specified
+// configuration. If no input is given, all the default values are used.
+func GenerateOTPKey(i *OTPInput) (*OTPKey, error) {
+	if i == nil {
+		i = new(OTPInput)
+	}
+
+	k := &OTPKey{
+		Issuer:    i.Issuer,
+		Account:   i.Account,
+		Algorithm: i.Algorithm,
+		Digits:    i.Digits,
+		Period:    i.Period,
+	}
+
+	if k.Digits == 0 {
+		k.Digits = DefaultOTPDigits
+	}
+
+	if k.Period == 0 {
+		k.Period = DefaultOTPPeriod
+	}
+
+	size := i.SecretSize
+	if size == 0 {
+		size = DefaultOTPSecretSize
+	}
+
+	if size < MinOTPSecretSize {
+		return nil, ErrOTPSecretTooShort
+	}
+
+	reader := i.Reader
+	if reader == nil {
+		reader = rand.Reader
+	}
+
+	k.Secret = make([]byte, size)
+	if _, err := io.ReadFull(reader, k.Secret); err != nil {
+		return nil, readerError(err, "secret")
+	}
+
+	if err := k.Validate(); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// ParseOTPSecret decodes a base32 secret as shown by authenticator apps.
+// Case, spaces and padding are ignored.
+func ParseOTPSecret(s string) ([]byte, error) {
+	s = strings.ToUpper(strings.Replace(s, " ", "", -1))
+	return otpEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+// Validate checks the key's parameters. Unlike the methods computing codes,
+// it also rejects secrets shorter than MinOTPSecretSize.
+func (k *OTPKey) Validate() error {
+	if len(k.Secret) < MinOTPSecretSize {
+		return ErrOTPSecretTooShort
+	}
+
+	_, _, err := k.params()
+	return err
+}
+
+// params returns the number of digits and the period of the key, filling in
+// the defaults for zero values, and checks them and the algorithm.
+func (k *OTPKey) params() (int, time.Duration, error) {
+	digits, period := k.Digits, k.Period
+	if digits == 0 {
+		digits = DefaultOTPDigits
+	}
+	if period == 0 {
+		period = DefaultOTPPeriod
+	}
+
+	if digits < 6 || digits > 8 {
+		return 0, 0, ErrOTPDigitsInvalid
+	}
+
+	if period < time.Second || period%time.Second != 0 {
+		return 0, 0, ErrOTPPeriodInvalid
+	}
+
+	if k.Algorithm != OTPSHA1 && k.Algorithm != OTPSHA256 && k.Algorithm != OTPSHA512 {
+		return 0, 0, ErrOTPAlgorithmUnknown
+	}
+
+	return digits, period, nil
+}
+
+// SecretBase32 returns the secret in unpadded base32, for manual entry into
+// authenticator apps.
+func (k *OTPKey) SecretBase32() string {
+	return otpEncoding.EncodeToString(k.Secret)
+}
+
+// TOTPURI returns the otpauth:// URI of the key for TOTP, which is usually
+// rendered as a QR code.
+func (k *OTPKey) TOTPURI() (string, error) {
+	digits, period, err := k.params()
+	if err != nil {
+		return "", err
+	}
+
+	v := k.uriValues(digits)
+	v.Set("period", strconv.Itoa(int(period/time.Second)))
+	return k.uri("totp", v), nil
+}
+
+// HOTPURI returns the otpauth:// URI of the key for HOTP, starting at the
+// given counter.
+func (k *OTPKey) HOTPURI(counter uint64) (string, error) {
+	digits, _, err := k.params()
+	if err != nil {
+		return "", err
+	}
+
+	v := k.uriValues(digits)
+	v.Set("counter", strconv.FormatUint(counter, 10))
+	return k.uri("hotp", v), nil
+}
+
+// HOTP computes the one-time password for the given counter as specified by
+// RFC 4226.
+func (k *OTPKey) HOTP(counter uint64) (string, error) {
+	digits, _, err := k.params()
+	if err != nil {
+		return "", err
+	}
+	return k.hotp(counter, digits), nil
+}
+
+// hotp implements HOTP for checked parameters.
+func (k *OTPKey) hotp(counter uint64, digits int) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+
+	m := hmac.New(k.Algorithm.hash(), k.Secret)
+	m.Write(msg[:])
+	sum := m.Sum(nil)
+
+	// Dynamic truncation.
+	off := sum[len(sum)-1] & 0x0f
+	code := binary.BigEndian.Uint32(sum[off:off+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+	return zeroPad(int(code%mod), digits)
+}
+
+// TOTP computes the one-time password for the time step containing t as
+// specified by RFC 6238.
+func (k *OTPKey) TOTP(t time.Time) (string, error) {
+	digits, period, err := k.params()
+	if err != nil {
+		return "", err
+	}
+
+	step, err := totpStep(t, period)
+	if err != nil {
+		return "", err
+	}
+	return k.hotp(step, digits), nil
+}
+
+// VerifyHOTP checks code against the counters from counter to
+// counter+window. It returns the matching counter, after which the caller
+// must continue counting to prevent replays.
+func (k *OTPKey) VerifyHOTP(code string, counter uint64, window int) (uint64, bool, error) {
+	digits, _, err := k.params()
+	if err != nil {
+		return 0, false, err
+	}
+
+	for i := 0; i <= window; i++ {
+		if k.equal(code, k.hotp(counter+uint64(i), digits)) {
+			return counter + uint64(i), true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// VerifyTOTP checks code against the time step containing t and skew steps
+// before and after it, to allow for clock drift and typing delays. It returns
+// the matching time step, which the caller must record to reject the same
+// code, or an earlier one, from then on (RFC 6238, section 5.2).
+func (k *OTPKey) VerifyTOTP(code string, t time.Time, skew int) (uint64, bool, error) {
+	digits, period, err := k.params()
+	if err != nil {
+		return 0, false, err
+	}
+
+	step, err := totpStep(t, period)
+	if err != nil {
+		return 0, false, err
+	}
+
+	for i := -skew; i <= skew; i++ {
+		if i < 0 && uint64(-i) > step {
+			continue
+		}
+		if k.equal(code, k.hotp(step+uint64(i), digits)) {
+			return step + uint64(i), true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// totpStep returns the TOTP time step of the given period containing t.
+func totpStep(t time.Time, period time.Duration) (uint64, error) {
+	if t.Unix() < 0 {
+		return 0, ErrOTPTimeInvalid
+	}
+	return uint64(t.Unix()) / uint64(period/time.Second), nil
+}
+
+// equal compares two codes in constant time.
+func (k *OTPKey) equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// uriValues returns the query parameters common to both URI types.
+func (k *OTPKey) uriValues(digits int) url.Values {
+	v := url.Values{}
+	v.Set("secret", k.SecretBase32())
+	if k.Issuer != "" {
+		v.Set("issuer", k.Issuer)
+	}
+	v.Set("algorithm", k.Algorithm.String())
+	v.Set("digits", strconv.Itoa(digits))
+	return v
+}
+
+// uri builds an otpauth:// URI with the "issuer:account" label.
+func (k *OTPKey) uri(typ string, v url.Values) string {
+	label := k.Account
+	if k.Issuer != "" {
+		label = k.Issuer + ":" + k.Account
+	}
+
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     typ,
+		Path:     "/" + label,
+		RawQuery: v.Encode(),
+	}
+	return u.String()
+}
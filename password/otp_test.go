@@ -0,0 +1,336 @@
+package password
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOTPKey_HOTP_RFC4226(t *testing.T) {
+	t.Parallel()
+
+	// RFC 4226, Appendix D.
+	k := &OTPKey{
+		Secret:    []byte("12345678901234567890"),
+		Algorithm: OTPSHA1,
+		Digits:    6,
+		Period:    DefaultOTPPeriod,
+	}
+
+	expected := []string{
+		"755224", "287082", "359152", "969429", "338314",
+		"254676", "287922", "162583", "399871", "520489",
+	}
+
+	for counter, want := range expected {
+		if got, err := k.HOTP(uint64(counter)); err != nil || got != want {
+			t.Errorf("HOTP(%d) = %q, want %q", counter, got, want)
+		}
+	}
+}
+
+func TestOTPKey_TOTP_RFC6238(t *testing.T) {
+	t.Parallel()
+
+	// RFC 6238, Appendix B. Every algorithm uses the ASCII seed repeated to
+	// the length of its hash.
+	seed := "1234567890"
+	keys := map[OTPAlgorithm][]byte{
+		OTPSHA1:   []byte(strings.Repeat(seed, 2)),
+		OTPSHA256: []byte(strings.Repeat(seed, 4)[:32]),
+		OTPSHA512: []byte(strings.Repeat(seed, 7)[:64]),
+	}
+
+	var TestCases = []struct {
+		Time      int64
+		Algorithm OTPAlgorithm
+		Expected  string
+	}{
+		{Time: 59, Algorithm: OTPSHA1, Expected: "94287082"},
+		{Time: 59, Algorithm: OTPSHA256, Expected: "46119246"},
+		{Time: 59, Algorithm: OTPSHA512, Expected: "90693936"},
+		{Time: 1111111109, Algorithm: OTPSHA1, Expected: "07081804"},
+		{Time: 1111111109, Algorithm: OTPSHA256, Expected: "68084774"},
+		{Time: 1111111109, Algorithm: OTPSHA512, Expected: "25091201"},
+		{Time: 1111111111, Algorithm: OTPSHA1, Expected: "14050471"},
+		{Time: 1111111111, Algorithm: OTPSHA256, Expected: "67062674"},
+		{Time: 1111111111, Algorithm: OTPSHA512, Expected: "99943326"},
+		{Time: 1234567890, Algorithm: OTPSHA1, Expected: "89005924"},
+		{Time: 1234567890, Algorithm: OTPSHA256, Expected: "91819424"},
+		{Time: 1234567890, Algorithm: OTPSHA512, Expected: "93441116"},
+		{Time: 2000000000, Algorithm: OTPSHA1, Expected: "69279037"},
+		{Time: 2000000000, Algorithm: OTPSHA256, Expected: "90698825"},
+		{Time: 2000000000, Algorithm: OTPSHA512, Expected: "38618901"},
+		{Time: 20000000000, Algorithm: OTPSHA1, Expected: "65353130"},
+		{Time: 20000000000, Algorithm: OTPSHA256, Expected: "77737706"},
+		{Time: 20000000000, Algorithm: OTPSHA512, Expected: "47863826"},
+	}
+
+	for _, tc := range TestCases {
+		k := &OTPKey{
+			Secret:    keys[tc.Algorithm],
+			Algorithm: tc.Algorithm,
+			Digits:    8,
+			Period:    30 * time.Second,
+		}
+
+		if got, err := k.TOTP(time.Unix(tc.Time, 0)); err != nil || got != tc.Expected {
+			t.Errorf("TOTP(%d, %s) = %q, want %q", tc.Time, tc.Algorithm, got, tc.Expected)
+		}
+	}
+}
+
+func TestOTPKey_VerifyHOTP(t *testing.T) {
+	t.Parallel()
+
+	k := &OTPKey{
+		Secret: []byte("12345678901234567890"),
+		Digits: 6,
+		Period: DefaultOTPPeriod,
+	}
+
+	// 969429 is the code for counter 3.
+	if _, ok, err := k.VerifyHOTP("969429", 0, 2); err != nil || ok {
+		t.Errorf("code outside of the window should not verify")
+	}
+
+	counter, ok, err := k.VerifyHOTP("969429", 1, 2)
+	if err != nil || !ok || counter != 3 {
+		t.Errorf("VerifyHOTP() = %d, %t, %v, want 3, true, <nil>", counter, ok, err)
+	}
+}
+
+func TestOTPKey_VerifyTOTP(t *testing.T) {
+	t.Parallel()
+
+	k, err := GenerateOTPKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verify := func(code string, t time.Time, skew int) bool {
+		_, ok, err := k.VerifyTOTP(code, t, skew)
+		return err == nil && ok
+	}
+	totp := func(t time.Time) string {
+		code, err := k.TOTP(t)
+		if err != nil {
+			return ""
+		}
+		return code
+	}
+
+	now := time.Unix(1600000000, 0)
+	code := totp(now)
+
+	if !verify(code, now, 0) {
+		t.Errorf("current code should verify")
+	}
+
+	if verify(code, now.Add(k.Period), 0) {
+		t.Errorf("previous code should not verify without skew")
+	}
+
+	if !verify(code, now.Add(k.Period), 1) {
+		t.Errorf("previous code should verify with skew")
+	}
+
+	if verify(code, now.Add(2*k.Period), 1) {
+		t.Errorf("code two steps back should not verify with a skew of one")
+	}
+
+	if !verify(totp(time.Unix(0, 0)), time.Unix(0, 0), 1) {
+		t.Errorf("first time step should verify")
+	}
+
+	// The matching step lets callers reject replays.
+	want := uint64(now.Unix()) / uint64(k.Period/time.Second)
+	if step, ok, err := k.VerifyTOTP(code, now.Add(k.Period), 1); err != nil || !ok || step != want {
+		t.Errorf("VerifyTOTP() = %d, %t, %v, want %d, true, <nil>", step, ok, err, want)
+	}
+
+	before := time.Unix(-1, 0)
+	if _, err := k.TOTP(before); err != ErrOTPTimeInvalid {
+		t.Errorf("expected %v to be %v", err, ErrOTPTimeInvalid)
+	}
+	if _, ok, err := k.VerifyTOTP(code, before, 1); ok || err != ErrOTPTimeInvalid {
+		t.Errorf("expected %v to be %v", err, ErrOTPTimeInvalid)
+	}
+}
+
+func TestOTPKey_defaults(t *testing.T) {
+	t.Parallel()
+
+	k := &OTPKey{Secret: []byte("12345678901234567890")}
+	defaults := &OTPKey{Secret: k.Secret, Digits: DefaultOTPDigits, Period: DefaultOTPPeriod}
+
+	now := time.Unix(1600000000, 0)
+	got, err := k.TOTP(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := defaults.TOTP(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("TOTP() = %q, want %q", got, want)
+	}
+}
+
+func TestOTPKey_invalid(t *testing.T) {
+	t.Parallel()
+
+	var TestCases = []struct {
+		Name string
+		Key  OTPKey
+		Err  error
+	}{
+		{Name: "too few digits", Key: OTPKey{Digits: 1}, Err: ErrOTPDigitsInvalid},
+		{Name: "too many digits", Key: OTPKey{Digits: 10}, Err: ErrOTPDigitsInvalid},
+		{Name: "negative digits", Key: OTPKey{Digits: -6}, Err: ErrOTPDigitsInvalid},
+		{Name: "sub-second period", Key: OTPKey{Period: time.Millisecond}, Err: ErrOTPPeriodInvalid},
+		{Name: "negative period", Key: OTPKey{Period: -30 * time.Second}, Err: ErrOTPPeriodInvalid},
+		{Name: "fractional period", Key: OTPKey{Period: 1500 * time.Millisecond}, Err: ErrOTPPeriodInvalid},
+		{Name: "algorithm", Key: OTPKey{Algorithm: 7}, Err: ErrOTPAlgorithmUnknown},
+	}
+
+	now := time.Unix(1600000000, 0)
+	for _, tc := range TestCases {
+		tc := tc
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			k := tc.Key
+			k.Secret = []byte("12345678901234567890")
+
+			if _, err := k.HOTP(0); err != tc.Err {
+				t.Errorf("HOTP: expected %v to be %v", err, tc.Err)
+			}
+			if _, err := k.TOTP(now); err != tc.Err {
+				t.Errorf("TOTP: expected %v to be %v", err, tc.Err)
+			}
+			if _, ok, err := k.VerifyHOTP("0", 0, 0); ok || err != tc.Err {
+				t.Errorf("VerifyHOTP: expected %v to be %v", err, tc.Err)
+			}
+			if _, ok, err := k.VerifyTOTP("0", now, 1); ok || err != tc.Err {
+				t.Errorf("VerifyTOTP: expected %v to be %v", err, tc.Err)
+			}
+			if _, err := k.TOTPURI(); err != tc.Err {
+				t.Errorf("TOTPURI: expected %v to be %v", err, tc.Err)
+			}
+			if _, err := k.HOTPURI(0); err != tc.Err {
+				t.Errorf("HOTPURI: expected %v to be %v", err, tc.Err)
+			}
+			if err := k.Validate(); err != tc.Err {
+				t.Errorf("Validate: expected %v to be %v", err, tc.Err)
+			}
+		})
+	}
+}
+
+func TestGenerateOTPKey(t *testing.T) {
+	t.Parallel()
+
+	drbg, err := NewDRBG([]byte("otp fixtures, not a real seed!!!"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	k, err := GenerateOTPKey(&OTPInput{
+		Issuer:    "ACME Co",
+		Account:   "john@example.com",
+		Algorithm: OTPSHA256,
+		Digits:    8,
+		Period:    60 * time.Second,
+		Reader:    drbg,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(k.Secret) != DefaultOTPSecretSize {
+		t.Errorf("secret has %d bytes, want %d", len(k.Secret), DefaultOTPSecretSize)
+	}
+
+	secret, err := ParseOTPSecret(strings.ToLower(k.SecretBase32()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(secret, k.Secret) {
+		t.Errorf("ParseOTPSecret() = %x, want %x", secret, k.Secret)
+	}
+
+	uri, err := k.TOTPURI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/ACME Co:john@example.com" {
+		t.Errorf("unexpected URI %q", u)
+	}
+
+	want := url.Values{
+		"secret":    {k.SecretBase32()},
+		"issuer":    {"ACME Co"},
+		"algorithm": {"SHA256"},
+		"digits":    {"8"},
+		"period":    {"60"},
+	}
+	if got := u.Query(); got.Encode() != want.Encode() {
+		t.Errorf("query = %q, want %q", got.Encode(), want.Encode())
+	}
+
+	if got, err := k.HOTPURI(7); err != nil || !strings.HasPrefix(got, "otpauth://hotp/ACME%20Co:john@example.com?") || !strings.Contains(got, "counter=7") {
+		t.Errorf("unexpected URI %q", got)
+	}
+}
+
+func TestGenerateOTPKey_errors(t *testing.T) {
+	t.Parallel()
+
+	var TestCases = []struct {
+		Name  string
+		Input OTPInput
+		Err   error
+	}{
+		{
+			Name:  "short secret",
+			Input: OTPInput{SecretSize: 10},
+			Err:   ErrOTPSecretTooShort,
+		},
+		{
+			Name:  "digits",
+			Input: OTPInput{Digits: 9},
+			Err:   ErrOTPDigitsInvalid,
+		},
+		{
+			Name:  "period",
+			Input: OTPInput{Period: 1500 * time.Millisecond},
+			Err:   ErrOTPPeriodInvalid,
+		},
+		{
+			Name:  "algorithm",
+			Input: OTPInput{Algorithm: 7},
+			Err:   ErrOTPAlgorithmUnknown,
+		},
+	}
+
+	for _, tc := range TestCases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := GenerateOTPKey(&tc.Input); err != tc.Err {
+				t.Errorf("expected %v to be %v", err, tc.Err)
+			}
+		})
+	}
+}
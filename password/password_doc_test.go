@@ -1,8 +1,10 @@
 package password_test
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/tullo/password/password"
 )
@@ -21,6 +23,26 @@ func ExampleMustGenerate() {
 	log.Print(res)
 }
 
+func ExampleGenerateSecret() {
+	secret, err := password.GenerateSecret(16, 2, 2, true, false)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Wipe the password from memory once it is no longer needed.
+	defer secret.Destroy()
+
+	// Printing the buffer does not leak the password.
+	fmt.Println(secret)
+
+	err = secret.Reveal(func(b []byte) {
+		_ = b // hand the password to its consumer
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Output: [REDACTED]
+}
+
 func ExampleStatefulGenerator_Generate() {
 	gen, err := password.NewStatefulGenerator(nil)
 	if err != nil {
@@ -34,6 +56,23 @@ func ExampleStatefulGenerator_Generate() {
 	log.Print(res)
 }
 
+func ExampleStatefulGenerator_GenerateWithPolicyContext() {
+	gen, err := password.NewStatefulGenerator(nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Give up if a matching password cannot be found within a second.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	res, err := gen.GenerateWithPolicyContext(ctx, 16, 2, 2, true, false, true, true, true, true)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Print(res)
+}
+
 func ExampleNewStatefulGenerator_nil() {
 	// This is exactly the same as calling "Generate" directly.
 	// It will use all the default values.
@@ -57,6 +96,44 @@ func ExampleNewStatefulGenerator_custom() {
 	_ = gen // gen.Generate(...)
 }
 
+func ExampleNewDRBG() {
+	// A fixed seed makes the generated passwords reproducible. Only use a
+	// DRBG outside of tests if the seed itself is secret.
+	drbg, err := password.NewDRBG([]byte("correct horse battery staple, v1"), nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	gen, err := password.NewStatefulGenerator(&password.GeneratorInput{
+		Reader: drbg,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Print(gen.MustGenerate(16, 2, 2, true, false))
+	// Output: mTCVg0pFf!d7cS%B
+}
+
+func ExampleTokenGenerator() {
+	gen, err := password.NewTokenGenerator(&password.TokenInput{
+		Prefix: "acme_live_",
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	token := gen.MustGenerate()
+
+	// Reject mistyped or made-up tokens before looking them up.
+	if _, err := gen.ParseToken(token); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(gen.Pattern())
+	// Output: \bacme_live_[0-9A-Za-z]{33}\b
+}
+
 func ExampleNewMockPasswordGenerator_testing() {
 	// Accept a password.Generator interface instead of a
 	// password.Generator struct.
@@ -71,3 +148,24 @@ func ExampleNewMockPasswordGenerator_testing() {
 	fmt.Print(f(gen))
 	// Output: canned-response
 }
+
+func ExampleMockPasswordGenerator_ExpectGenerateWithPolicy() {
+	// retry asks for a new password until it differs from the old one.
+	retry := func(g password.Generator, old string) (string, error) {
+		for {
+			res, err := g.GenerateWithPolicy(16, 2, 2, true, false, true, true, true, true)
+			if err != nil || res != old {
+				return res, err
+			}
+		}
+	}
+
+	// In tests
+	gen := password.NewMockPasswordGenerator("", nil)
+	gen.ExpectGenerateWithPolicy(16, 2, 2, true, false, true, true, true, true).Return("old", nil)
+	gen.ExpectGenerateWithPolicy(16, 2, 2, true, false, true, true, true, true).Return("new", nil)
+
+	res, _ := retry(gen, "old")
+	fmt.Println(res, len(gen.Calls()))
+	// Output: new 2
+}
@@ -0,0 +1,389 @@
+package password
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Character classes of the passwordrules syntax.
+const (
+	// PasswordRulesSpecial is the passwordrules "special" class: the ASCII
+	// printable characters that are neither letters nor digits, including
+	// space.
+	PasswordRulesSpecial = " !\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+
+	// ASCIIPrintable is the passwordrules "ascii-printable" class.
+	ASCIIPrintable = UpperLetters + LowerLetters + Digits + PasswordRulesSpecial
+)
+
+var (
+	// ErrPasswordRulesSyntax is the error returned for malformed
+	// passwordrules.
+	ErrPasswordRulesSyntax = errors.New("passwordrules syntax error")
+
+	// ErrPasswordRulesUnsupported is the error returned when formatting a
+	// policy that passwordrules cannot express.
+	ErrPasswordRulesUnsupported = errors.New("policy cannot be expressed as passwordrules")
+)
+
+// passwordRulesClasses lists the named classes in canonical order.
+var passwordRulesClasses = []struct {
+	name  string
+	chars string
+}{
+	{"upper", UpperLetters},
+	{"lower", LowerLetters},
+	{"digit", Digits},
+	{"special", PasswordRulesSpecial},
+}
+
+// ParsePasswordRules parses the value of a passwordrules attribute, as in
+// "required: lower; required: upper; allowed: [-().&@?'#,/"+]; max-consecutive: 2; minlength: 12",
+// into a policy.
+//
+// Every required property becomes a class with a minimum of one, and allowed
+// properties become classes without a minimum. Without either, all ASCII
+// printable characters are allowed. The "unicode" class is treated as
+// ascii-printable. Like browsers, the parser ignores unknown properties,
+// keeps the largest minlength and the smallest maxlength and max-consecutive
+// when a property is repeated, and drops non-ASCII characters from custom
+// classes. Rules that no password can satisfy, such as
+// "allowed: [a]; max-consecutive: 1", are rejected with a *PolicyError
+// wrapping ErrPolicyUnsatisfiable.
+func ParsePasswordRules(rules string) (*Policy, error) {
+	p := &Policy{}
+	var allowed runeSet
+
+	for _, prop := range splitPasswordRules(rules) {
+		prop = strings.TrimSpace(prop)
+		if prop == "" {
+			continue
+		}
+
+		i := strings.IndexByte(prop, ':')
+		if i < 0 {
+			return nil, fmt.Errorf("%w: property %q has no value", ErrPasswordRulesSyntax, prop)
+		}
+		name := strings.ToLower(strings.TrimSpace(prop[:i]))
+		value := strings.TrimSpace(prop[i+1:])
+
+		switch name {
+		case "required":
+			var set runeSet
+			if err := parsePasswordRulesClasses(value, &set); err != nil {
+				return nil, err
+			}
+			if chars := asciiString(&set); chars != "" {
+				p.addPasswordRulesClass(chars, 1)
+			}
+		case "allowed":
+			if err := parsePasswordRulesClasses(value, &allowed); err != nil {
+				return nil, err
+			}
+		case "minlength", "maxlength", "max-consecutive":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("%w: %s must be a non-negative integer, got %q", ErrPasswordRulesSyntax, name, value)
+			}
+			switch {
+			case name == "minlength" && n > p.MinLength:
+				p.MinLength = n
+			case name == "maxlength" && (p.MaxLength == 0 || n < p.MaxLength):
+				p.MaxLength = n
+			case name == "max-consecutive" && (p.MaxConsecutive == 0 || n < p.MaxConsecutive):
+				p.MaxConsecutive = n
+			}
+		}
+	}
+
+	chars := asciiString(&allowed)
+	if len(p.Classes) == 0 && chars == "" {
+		chars = ASCIIPrintable
+	}
+	if chars != "" {
+		p.addPasswordRulesClass(chars, 0)
+	}
+
+	if err := p.Check(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// splitPasswordRules splits rules into properties at semicolons outside of
+// custom classes.
+func splitPasswordRules(rules string) []string {
+	var props []string
+	start := 0
+	for i := 0; i < len(rules); i++ {
+		switch rules[i] {
+		case '[':
+			i = customClassEnd(rules, i)
+		case ';':
+			props = append(props, rules[start:i])
+			start = i + 1
+		}
+	}
+	return append(props, rules[start:])
+}
+
+// customClassEnd returns the index of the bracket closing the custom class
+// starting at s[i], or len(s) if it is unterminated. "]" is literal right
+// before the closing bracket, as in "[abc]]".
+func customClassEnd(s string, i int) int {
+	end := strings.IndexByte(s[i+1:], ']')
+	if end < 0 {
+		return len(s)
+	}
+	end += i + 1
+	for end+1 < len(s) && s[end+1] == ']' {
+		end++
+	}
+	return end
+}
+
+// addPasswordRulesClass adds a class for chars named by its canonical
+// passwordrules spelling. Classes that were already added keep the larger
+// minimum.
+func (p *Policy) addPasswordRulesClass(chars string, min int) {
+	name := formatPasswordRulesClass(chars)
+	for i := range p.Classes {
+		if p.Classes[i].Name == name {
+			if min > p.Classes[i].Min {
+				p.Classes[i].Min = min
+			}
+			return
+		}
+	}
+	p.Classes = append(p.Classes, ClassRule{Name: name, Chars: chars, Min: min})
+}
+
+// parsePasswordRulesClasses parses a comma separated list of named and custom
+// classes and adds their characters to set. Unknown class names are ignored.
+func parsePasswordRulesClasses(value string, set *runeSet) error {
+	for value != "" {
+		if value[0] == '[' {
+			end := customClassEnd(value, 0)
+			if end == len(value) {
+				return fmt.Errorf("%w: unterminated custom class %q", ErrPasswordRulesSyntax, value)
+			}
+
+			// "-" is literal only as the first character.
+			custom := value[1:end]
+			for i, r := range custom {
+				if r == '-' && i > 0 || r < 0x20 || r > 0x7e {
+					continue
+				}
+				set.add(r)
+			}
+			value = strings.TrimSpace(value[end+1:])
+		} else {
+			i := strings.IndexByte(value, ',')
+			if i < 0 {
+				i = len(value)
+			}
+			name := strings.ToLower(strings.TrimSpace(value[:i]))
+			value = value[i:]
+
+			chars := ""
+			switch name {
+			case "ascii-printable", "unicode":
+				chars = ASCIIPrintable
+			default:
+				for _, c := range passwordRulesClasses {
+					if c.name == name {
+						chars = c.chars
+					}
+				}
+			}
+			for _, r := range chars {
+				set.add(r)
+			}
+		}
+
+		switch {
+		case value == "":
+		case value[0] == ',':
+			value = strings.TrimSpace(value[1:])
+		default:
+			return fmt.Errorf("%w: expected , before %q", ErrPasswordRulesSyntax, value)
+		}
+	}
+	return nil
+}
+
+// asciiString returns the ASCII printable characters in set, in the order of
+// ASCIIPrintable.
+func asciiString(set *runeSet) string {
+	var b strings.Builder
+	for _, r := range ASCIIPrintable {
+		if set.contains(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// formatPasswordRulesClass returns the canonical passwordrules spelling of a
+// set of ASCII printable characters: "ascii-printable", or the named classes
+// it fully contains followed by a custom class with the rest, in which "-"
+// comes first and "]" last.
+func formatPasswordRulesClass(chars string) string {
+	var set runeSet
+	for _, r := range chars {
+		set.add(r)
+	}
+
+	if containsAll(&set, ASCIIPrintable) {
+		return "ascii-printable"
+	}
+
+	var names []string
+	var named runeSet
+	for _, c := range passwordRulesClasses {
+		if containsAll(&set, c.chars) {
+			names = append(names, c.name)
+			for _, r := range c.chars {
+				named.add(r)
+			}
+		}
+	}
+
+	var rest []rune
+	for _, r := range ASCIIPrintable {
+		if set.contains(r) && !named.contains(r) {
+			rest = append(rest, r)
+		}
+	}
+	if len(rest) > 0 {
+		sort.Slice(rest, func(i, j int) bool {
+			return passwordRulesOrder(rest[i]) < passwordRulesOrder(rest[j])
+		})
+		names = append(names, "["+string(rest)+"]")
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// passwordRulesOrder sorts "-" first and "]" last within a custom class.
+func passwordRulesOrder(r rune) rune {
+	switch r {
+	case '-':
+		return -1
+	case ']':
+		return 0x80
+	}
+	return r
+}
+
+func containsAll(set *runeSet, chars string) bool {
+	for _, r := range chars {
+		if !set.contains(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// FormatPasswordRules returns the passwordrules spelling of p. Classes with a
+// minimum become required properties and the others allowed properties, with
+// excluded characters removed. passwordrules have no generation length, so
+// if p.Length differs from the length the minlength and maxlength alone lead
+// to, minlength is raised or maxlength lowered to it. It returns an error wrapping
+// ErrPasswordRulesUnsupported if p uses rules passwordrules lacks: class
+// minimums above one, class maximums, no_repeat, blocklists or characters
+// outside of printable ASCII.
+func FormatPasswordRules(p *Policy) (string, error) {
+	if err := p.Check(); err != nil {
+		return "", err
+	}
+
+	switch {
+	case p.NoRepeat:
+		return "", fmt.Errorf("%w: no_repeat", ErrPasswordRulesUnsupported)
+	case len(p.Blocklist) > 0:
+		return "", fmt.Errorf("%w: blocklist", ErrPasswordRulesUnsupported)
+	}
+
+	exclude := NewCharset(p.Exclude)
+	var required, allowed []string
+	for i, c := range p.Classes {
+		switch {
+		case c.Min > 1:
+			return "", fmt.Errorf("%w: classes[%d].min is %d", ErrPasswordRulesUnsupported, i, c.Min)
+		case c.Max > 0:
+			return "", fmt.Errorf("%w: classes[%d].max", ErrPasswordRulesUnsupported, i)
+		}
+
+		chars := c.Chars
+		if chars == "" {
+			chars = builtinClasses[c.Name]
+		}
+
+		var b strings.Builder
+		for _, r := range chars {
+			if exclude.Contains(r) {
+				continue
+			}
+			if r < 0x20 || r > 0x7e {
+				return "", fmt.Errorf("%w: classes[%d] contains %q", ErrPasswordRulesUnsupported, i, r)
+			}
+			b.WriteRune(r)
+		}
+		if b.Len() == 0 {
+			continue
+		}
+
+		class := formatPasswordRulesClass(b.String())
+		if c.Min == 1 {
+			required = append(required, "required: "+class)
+		} else {
+			allowed = append(allowed, class)
+		}
+	}
+
+	props := required
+	if len(allowed) > 0 {
+		props = append(props, "allowed: "+strings.Join(allowed, ", "))
+	}
+	if p.MaxConsecutive > 0 {
+		props = append(props, "max-consecutive: "+strconv.Itoa(p.MaxConsecutive))
+	}
+
+	// Parsing yields a policy without Length, which generates GenerateLength
+	// characters.
+	minLength, maxLength := p.MinLength, p.MaxLength
+	bounds := &Policy{MinLength: minLength, MaxLength: maxLength}
+	switch n := bounds.GenerateLength(); {
+	case p.Length > n:
+		minLength = p.Length
+	case p.Length > 0 && p.Length < n:
+		maxLength = p.Length
+	}
+	if minLength > 0 {
+		props = append(props, "minlength: "+strconv.Itoa(minLength))
+	}
+	if maxLength > 0 {
+		props = append(props, "maxlength: "+strconv.Itoa(maxLength))
+	}
+
+	return strings.Join(props, "; ") + ";", nil
+}
+
+// GenerateForPasswordRules generates a password satisfying the given
+// passwordrules, using crypto/rand.
+func GenerateForPasswordRules(rules string) (string, error) {
+	p, err := ParsePasswordRules(rules)
+	if err != nil {
+		return "", err
+	}
+
+	gen, err := NewPolicyGenerator(p, nil)
+	if err != nil {
+		return "", err
+	}
+	return gen.Generate()
+}
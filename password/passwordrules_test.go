@@ -0,0 +1,438 @@
+package password
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestParsePasswordRules(t *testing.T) {
+	t.Parallel()
+
+	var TestCases = []struct {
+		Name     string
+		Rules    string
+		Expected *Policy
+	}{
+		{
+			Name:  "example",
+			Rules: `required: lower; required: upper; allowed: [-().&@?'#,/"+]; max-consecutive: 2; minlength: 12`,
+			Expected: &Policy{
+				MinLength:      12,
+				MaxConsecutive: 2,
+				Classes: []ClassRule{
+					{Name: "lower", Chars: LowerLetters, Min: 1},
+					{Name: "upper", Chars: UpperLetters, Min: 1},
+					{Name: `[-"#&'()+,./?@]`, Chars: `"#&'()+,-./?@`},
+				},
+			},
+		},
+		{
+			Name:  "combined classes",
+			Rules: "required: upper, digit; allowed: lower, special",
+			Expected: &Policy{
+				Classes: []ClassRule{
+					{Name: "upper, digit", Chars: UpperLetters + Digits, Min: 1},
+					{Name: "lower, special", Chars: LowerLetters + PasswordRulesSpecial},
+				},
+			},
+		},
+		{
+			Name:  "defaults to ascii-printable",
+			Rules: "minlength: 10; maxlength: 12",
+			Expected: &Policy{
+				MinLength: 10,
+				MaxLength: 12,
+				Classes:   []ClassRule{{Name: "ascii-printable", Chars: ASCIIPrintable}},
+			},
+		},
+		{
+			Name:  "duplicate required",
+			Rules: "required: digit; required: [0123456789]; allowed: digit",
+			Expected: &Policy{
+				Classes: []ClassRule{{Name: "digit", Chars: Digits, Min: 1}},
+			},
+		},
+		{
+			Name:  "dash and bracket",
+			Rules: "required: [-a-z]; required: [xyz]]",
+			Expected: &Policy{
+				Classes: []ClassRule{
+					{Name: "[-az]", Chars: "az-", Min: 1},
+					{Name: "[xyz]]", Chars: "xyz]", Min: 1},
+				},
+			},
+		},
+	}
+
+	for _, tc := range TestCases {
+		tc := tc
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			p, err := ParsePasswordRules(tc.Rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(p, tc.Expected) {
+				t.Errorf("expected %+v to be %+v", p, tc.Expected)
+			}
+		})
+	}
+}
+
+func TestParsePasswordRules_errors(t *testing.T) {
+	t.Parallel()
+
+	var TestCases = []struct {
+		Name  string
+		Rules string
+	}{
+		{Name: "no value", Rules: "minlength: 8; required lower"},
+		{Name: "length", Rules: "minlength: eight"},
+		{Name: "negative length", Rules: "maxlength: -1"},
+		{Name: "unterminated class", Rules: "required: [abc"},
+		{Name: "missing comma", Rules: "required: [abc] digit"},
+	}
+
+	for _, tc := range TestCases {
+		tc := tc
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := ParsePasswordRules(tc.Rules); !errors.Is(err, ErrPasswordRulesSyntax) {
+				t.Errorf("expected %q to be %q", err, ErrPasswordRulesSyntax)
+			}
+		})
+	}
+
+	var pe *PolicyError
+	if _, err := ParsePasswordRules("minlength: 20; maxlength: 10"); !errors.As(err, &pe) {
+		t.Errorf("expected %v to be a *PolicyError", err)
+	}
+}
+
+func TestParsePasswordRules_unsatisfiable(t *testing.T) {
+	t.Parallel()
+
+	var TestCases = []struct {
+		Name  string
+		Rules string
+	}{
+		{Name: "single character", Rules: "allowed: [a]; max-consecutive: 1;"},
+		{Name: "required classes exceed length", Rules: "required: lower; required: upper; required: digit; maxlength: 2;"},
+	}
+
+	for _, tc := range TestCases {
+		tc := tc
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			var pe *PolicyError
+			if _, err := ParsePasswordRules(tc.Rules); !errors.As(err, &pe) {
+				t.Errorf("expected %v to be a *PolicyError", err)
+			}
+			if _, err := GenerateForPasswordRules(tc.Rules); err == nil {
+				t.Errorf("expected %q to be rejected", tc.Rules)
+			}
+		})
+	}
+
+	if _, err := ParsePasswordRules("allowed: [a]; max-consecutive: 1;"); !errors.Is(err, ErrPolicyUnsatisfiable) {
+		t.Errorf("expected %q to be %q", err, ErrPolicyUnsatisfiable)
+	}
+}
+
+func TestFormatPasswordRules(t *testing.T) {
+	t.Parallel()
+
+	var TestCases = []struct {
+		Name     string
+		Policy   *Policy
+		Expected string
+	}{
+		{
+			Name: "builtin classes",
+			Policy: &Policy{
+				MinLength: 12,
+				MaxLength: 20,
+				Classes: []ClassRule{
+					{Name: "lower", Min: 1},
+					{Name: "upper", Min: 1},
+					{Name: "digit"},
+					{Name: "symbol", Chars: "-]#"},
+				},
+			},
+			Expected: "required: lower; required: upper; allowed: digit, [-#]]; minlength: 12; maxlength: 20;",
+		},
+		{
+			Name: "exclude",
+			Policy: &Policy{
+				MaxConsecutive: 2,
+				Exclude:        "0O1lI",
+				Classes: []ClassRule{
+					{Name: "letters", Chars: LowerLetters + UpperLetters, Min: 1},
+					{Name: "digit", Min: 1},
+				},
+			},
+			Expected: "required: [ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz]; required: [23456789]; max-consecutive: 2;",
+		},
+		{
+			Name: "ascii-printable",
+			Policy: &Policy{
+				Classes: []ClassRule{{Name: "all", Chars: ASCIIPrintable}},
+			},
+			Expected: "allowed: ascii-printable;",
+		},
+	}
+
+	for _, tc := range TestCases {
+		tc := tc
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			res, err := FormatPasswordRules(tc.Policy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res != tc.Expected {
+				t.Errorf("expected %q to be %q", res, tc.Expected)
+			}
+		})
+	}
+}
+
+func TestFormatPasswordRules_unsupported(t *testing.T) {
+	t.Parallel()
+
+	var TestCases = []struct {
+		Name   string
+		Policy *Policy
+	}{
+		{Name: "min", Policy: &Policy{Classes: []ClassRule{{Name: "digit", Min: 2}}}},
+		{Name: "max", Policy: &Policy{Classes: []ClassRule{{Name: "digit", Max: 20}}}},
+		{Name: "no repeat", Policy: &Policy{Length: 8, NoRepeat: true, Classes: []ClassRule{{Name: "lower"}}}},
+		{Name: "blocklist", Policy: &Policy{Blocklist: []string{"x"}, Classes: []ClassRule{{Name: "lower"}}}},
+		{Name: "non-ascii", Policy: &Policy{Classes: []ClassRule{{Name: "umlaut", Chars: "äöü"}}}},
+	}
+
+	for _, tc := range TestCases {
+		tc := tc
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := FormatPasswordRules(tc.Policy); !errors.Is(err, ErrPasswordRulesUnsupported) {
+				t.Errorf("expected %q to be %q", err, ErrPasswordRulesUnsupported)
+			}
+		})
+	}
+}
+
+func TestFormatPasswordRules_length(t *testing.T) {
+	t.Parallel()
+
+	var TestCases = []struct {
+		Name     string
+		Policy   *Policy
+		Expected string
+	}{
+		{
+			Name:     "longer than the default",
+			Policy:   &Policy{Length: 20, Classes: []ClassRule{{Name: "lower", Min: 1}}},
+			Expected: "required: lower; minlength: 20;",
+		},
+		{
+			Name:     "longer than the bounds imply",
+			Policy:   &Policy{Length: 18, MinLength: 12, MaxLength: 64, Classes: []ClassRule{{Name: "lower", Min: 1}}},
+			Expected: "required: lower; minlength: 18; maxlength: 64;",
+		},
+		{
+			Name:     "shorter than the default",
+			Policy:   &Policy{Length: 10, MinLength: 8, Classes: []ClassRule{{Name: "lower", Min: 1}}},
+			Expected: "required: lower; minlength: 8; maxlength: 10;",
+		},
+		{
+			Name:     "implied by the bounds",
+			Policy:   &Policy{Length: 16, MinLength: 12, Classes: []ClassRule{{Name: "lower", Min: 1}}},
+			Expected: "required: lower; minlength: 12;",
+		},
+	}
+
+	for _, tc := range TestCases {
+		tc := tc
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			res, err := FormatPasswordRules(tc.Policy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res != tc.Expected {
+				t.Errorf("expected %q to be %q", res, tc.Expected)
+			}
+
+			p, err := ParsePasswordRules(res)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n := p.GenerateLength(); n != tc.Policy.Length {
+				t.Errorf("expected %d to be %d", n, tc.Policy.Length)
+			}
+		})
+	}
+}
+
+// testPasswordRules parses, formats and reparses rules, and generates
+// passwords for them.
+func testPasswordRules(t *testing.T, rules string) {
+	t.Helper()
+
+	p, err := ParsePasswordRules(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	formatted, err := FormatPasswordRules(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reparsed, err := ParsePasswordRules(formatted)
+	if err != nil {
+		t.Fatalf("%q: %v", formatted, err)
+	}
+	if !reflect.DeepEqual(reparsed, p) {
+		t.Errorf("%q parses to %+v, want %+v", formatted, reparsed, p)
+	}
+
+	gen, err := NewPolicyGenerator(p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 50; i++ {
+		res, err := gen.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := utf8.RuneCountInString(res); n < p.MinLength || p.MaxLength > 0 && n > p.MaxLength {
+			t.Errorf("%q has length %d outside of [%d, %d]", res, n, p.MinLength, p.MaxLength)
+		}
+		if err := p.Validate(res); err != nil {
+			t.Errorf("%q: %v", res, err)
+		}
+	}
+}
+
+// TestPasswordRules_sample runs testPasswordRules over a hand-picked sample
+// of entries of the quirks file of Apple's password-manager-resources.
+func TestPasswordRules_sample(t *testing.T) {
+	t.Parallel()
+
+	testPasswordRulesFile(t, "testdata/password-rules-sample.json")
+}
+
+// TestPasswordRules_quirks runs testPasswordRules over every entry of the
+// quirks file of Apple's password-manager-resources. It is skipped unless
+// "make quirks" has downloaded the file to testdata/password-rules.json.
+func TestPasswordRules_quirks(t *testing.T) {
+	t.Parallel()
+
+	path := "testdata/password-rules.json"
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		t.Skipf("%s is missing, run make quirks", path)
+	}
+	testPasswordRulesFile(t, path)
+}
+
+// testPasswordRulesFile runs testPasswordRules over every entry of a file in
+// the format of the quirks file, one subtest per domain.
+func testPasswordRulesFile(t *testing.T, path string) {
+	t.Helper()
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var corpus map[string]struct {
+		Rules string `json:"password-rules"`
+	}
+	if err := json.Unmarshal(data, &corpus); err != nil {
+		t.Fatal(err)
+	}
+
+	domains := make([]string, 0, len(corpus))
+	for domain := range corpus {
+		domains = append(domains, domain)
+	}
+	sort.Strings(domains)
+
+	for _, domain := range domains {
+		rules := corpus[domain].Rules
+
+		t.Run(domain, func(t *testing.T) {
+			t.Parallel()
+
+			testPasswordRules(t, rules)
+		})
+	}
+}
+
+func TestPasswordRules_edgeCases(t *testing.T) {
+	t.Parallel()
+
+	var TestCases = []struct {
+		Name  string
+		Rules string
+	}{
+		{Name: "case and spacing", Rules: "  MinLength : 12 ;REQUIRED:LOWER;required :  Upper  ,  digit;;"},
+		{Name: "no trailing semicolon", Rules: "required: lower; required: digit; max-consecutive: 2; minlength: 12"},
+		{Name: "repeated lengths", Rules: "minlength: 8; minlength: 12; maxlength: 32; maxlength: 24; max-consecutive: 3; max-consecutive: 2;"},
+		{Name: "unknown property", Rules: "minlength: 10; required: lower; frobnicate: yes; allowed: upper, sparkles;"},
+		{Name: "unicode", Rules: "minlength: 16; allowed: unicode;"},
+		{Name: "non-ascii custom", Rules: "minlength: 8; required: [äöü!]; allowed: lower;"},
+		{Name: "dash and bracket", Rules: "minlength: 8; required: [-a-z]; required: [xyz]]; allowed: digit;"},
+		{Name: "only lengths", Rules: "minlength: 20; maxlength: 20;"},
+		{Name: "two characters alternating", Rules: "allowed: [ab]; max-consecutive: 1;"},
+	}
+
+	for _, tc := range TestCases {
+		tc := tc
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			testPasswordRules(t, tc.Rules)
+		})
+	}
+}
+
+func TestGenerateForPasswordRules(t *testing.T) {
+	t.Parallel()
+
+	res, err := GenerateForPasswordRules("required: lower; required: upper; required: digit; allowed: [-_]; minlength: 24; max-consecutive: 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 24 {
+		t.Errorf("%q has length %d, want 24", res, len(res))
+	}
+	if !ContainsLower(res) || !ContainsUpper(res) || !ContainsDigit(res) {
+		t.Errorf("%q misses a required class", res)
+	}
+	for i := 1; i < len(res); i++ {
+		if res[i] == res[i-1] {
+			t.Errorf("%q repeats %q", res, res[i])
+		}
+	}
+}
@@ -0,0 +1,397 @@
+// Package passwordtest provides statistical tests for password generators.
+//
+// Run draws many passwords from a password.Generator and checks with
+// chi-square tests that characters are uniform within their class and at
+// every position, that digits and symbols are inserted at uniformly random
+// positions, and that the number of uppercase letters follows the expected
+// distribution. Each test reports a p-value: the probability that an unbiased
+// generator produces a deviation at least as large. Biases such as modulo
+// bias or a skewed shuffle show up as p-values near zero.
+//
+//	func TestGenerator(t *testing.T) {
+//		gen, _ := password.NewStatefulGenerator(&password.GeneratorInput{Symbols: "!@#"})
+//		passwordtest.Verify(t, gen, passwordtest.Config{
+//			Length: 16, NumDigits: 2, NumSymbols: 2, IncludeUpper: true, AllowRepeat: true,
+//			Symbols: "!@#",
+//		})
+//	}
+package passwordtest
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/tullo/password/password"
+)
+
+const (
+	// DefaultSamples is the number of passwords Run generates by default.
+	DefaultSamples = 10000
+
+	// DefaultAlpha is the family-wise significance level Verify uses by
+	// default.
+	DefaultAlpha = 0.001
+
+	// minExpected is the smallest expected count of a chi-square cell.
+	// Smaller cells are pooled with their neighbours.
+	minExpected = 5
+)
+
+// ErrOverlappingSets is the error returned when the character sets of a
+// Config share characters, so that characters cannot be attributed to a
+// class.
+var ErrOverlappingSets = errors.New("character sets overlap")
+
+// Config describes the passwords to generate and the character sets the
+// generator was created with.
+type Config struct {
+	Length       int
+	NumDigits    int
+	NumSymbols   int
+	IncludeUpper bool
+	AllowRepeat  bool
+
+	// The character sets of the generator's GeneratorInput, defaulting to
+	// those of the password package.
+	LowerLetters string
+	UpperLetters string
+	Digits       string
+	Symbols      string
+
+	Samples int     // DefaultSamples by default
+	Alpha   float64 // DefaultAlpha by default
+}
+
+// Result is the outcome of one test. Exact checks, such as "length", have no
+// statistic and a p-value of either 0 or 1.
+type Result struct {
+	Name      string
+	Statistic float64 // chi-square statistic
+	DF        int     // degrees of freedom
+	PValue    float64
+}
+
+// String formats the result for test logs.
+func (r Result) String() string {
+	if r.DF == 0 {
+		return fmt.Sprintf("%s: p = %g", r.Name, r.PValue)
+	}
+	return fmt.Sprintf("%s: chi-square = %.2f, df = %d, p = %.4g", r.Name, r.Statistic, r.DF, r.PValue)
+}
+
+// Report holds the results of Run.
+type Report struct {
+	Samples int
+	Results []Result
+}
+
+// Failures returns the results that are significant at the family-wise level
+// alpha, applying the Bonferroni correction: a result fails if its p-value is
+// below alpha divided by the number of results.
+func (r *Report) Failures(alpha float64) []Result {
+	var failures []Result
+	for _, res := range r.Results {
+		if res.PValue < alpha/float64(len(r.Results)) {
+			failures = append(failures, res)
+		}
+	}
+	return failures
+}
+
+// String formats the report with one result per line.
+func (r *Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d samples\n", r.Samples)
+	for _, res := range r.Results {
+		b.WriteString(res.String())
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// Verify runs the tests and reports every failure at cfg.Alpha through t.
+func Verify(t testing.TB, g password.Generator, cfg Config) *Report {
+	t.Helper()
+
+	report, err := Run(g, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alpha := cfg.Alpha
+	if alpha == 0 {
+		alpha = DefaultAlpha
+	}
+	for _, res := range report.Failures(alpha) {
+		t.Errorf("passwordtest: %v", res)
+	}
+	return report
+}
+
+// class is a character class of the generated passwords.
+type class struct {
+	name   string
+	count  int // characters of the class per password
+	runes  []rune
+	weight map[rune]float64 // probability of each rune within the class
+}
+
+func newClass(name, chars string, count int) *class {
+	c := &class{name: name, count: count, weight: make(map[rune]float64)}
+	n := float64(utf8.RuneCountInString(chars))
+	for _, r := range chars {
+		if _, ok := c.weight[r]; !ok {
+			c.runes = append(c.runes, r)
+		}
+		c.weight[r] += 1 / n
+	}
+	return c
+}
+
+func orDefault(chars, def string) string {
+	if chars == "" {
+		return def
+	}
+	return chars
+}
+
+// Run generates cfg.Samples passwords with g and tests their distribution.
+// It returns an error if generation fails or the character sets overlap.
+func Run(g password.Generator, cfg Config) (*Report, error) {
+	samples := cfg.Samples
+	if samples == 0 {
+		samples = DefaultSamples
+	}
+
+	letters := orDefault(cfg.LowerLetters, password.LowerLetters)
+	upper := orDefault(cfg.UpperLetters, password.UpperLetters)
+	if cfg.IncludeUpper {
+		letters += upper
+	}
+	classes := []*class{
+		newClass("letters", letters, cfg.Length-cfg.NumDigits-cfg.NumSymbols),
+		newClass("digits", orDefault(cfg.Digits, password.Digits), cfg.NumDigits),
+		newClass("symbols", orDefault(cfg.Symbols, password.Symbols), cfg.NumSymbols),
+	}
+
+	if classes[0].count < 0 {
+		return nil, password.ErrExceedsTotalLength
+	}
+
+	classOf := make(map[rune]int)
+	for i, c := range classes {
+		for _, r := range c.runes {
+			if _, ok := classOf[r]; ok {
+				return nil, fmt.Errorf("%w: %q", ErrOverlappingSets, r)
+			}
+			classOf[r] = i
+		}
+	}
+
+	s := &stats{
+		cfg:       cfg,
+		classes:   classes,
+		classOf:   classOf,
+		badCounts: make([]int, len(classes)),
+		chars:     make([]map[rune]int, len(classes)),
+		positions: make([][]int, len(classes)),
+		atPos:     make([]map[rune]int, cfg.Length),
+		upper:     make([]int, classes[0].count+1),
+	}
+	for i := range classes {
+		s.chars[i] = make(map[rune]int)
+		s.positions[i] = make([]int, cfg.Length)
+	}
+	for i := range s.atPos {
+		s.atPos[i] = make(map[rune]int)
+	}
+	upperSet := make(map[rune]bool)
+	for _, r := range upper {
+		upperSet[r] = cfg.IncludeUpper
+	}
+
+	for n := 0; n < samples; n++ {
+		res, err := g.Generate(cfg.Length, cfg.NumDigits, cfg.NumSymbols, cfg.IncludeUpper, cfg.AllowRepeat)
+		if err != nil {
+			return nil, err
+		}
+		s.add(res, upperSet)
+	}
+
+	return s.report(samples), nil
+}
+
+// stats accumulates the observations of Run.
+type stats struct {
+	cfg     Config
+	classes []*class
+	classOf map[rune]int
+
+	badLength   int
+	badAlphabet int
+	badCounts   []int // passwords with a wrong count, per class
+
+	chars     []map[rune]int // occurrences per class and rune
+	positions [][]int        // occurrences per class and position
+	atPos     []map[rune]int // occurrences per position and rune
+	upper     []int          // passwords per number of uppercase letters
+}
+
+func (s *stats) add(res string, upperSet map[rune]bool) {
+	runes := []rune(res)
+	if len(runes) != s.cfg.Length {
+		s.badLength++
+		return
+	}
+
+	counts := make([]int, len(s.classes))
+	numUpper := 0
+	for pos, r := range runes {
+		i, ok := s.classOf[r]
+		if !ok {
+			s.badAlphabet++
+			return
+		}
+		counts[i]++
+		s.chars[i][r]++
+		s.positions[i][pos]++
+		s.atPos[pos][r]++
+		if upperSet[r] {
+			numUpper++
+		}
+	}
+
+	for i, c := range s.classes {
+		if counts[i] != c.count {
+			s.badCounts[i]++
+		}
+	}
+	if numUpper < len(s.upper) {
+		s.upper[numUpper]++
+	}
+}
+
+// exact returns the result of an exact check that failed bad times.
+func exact(name string, bad int) Result {
+	if bad > 0 {
+		return Result{Name: name, PValue: 0}
+	}
+	return Result{Name: name, PValue: 1}
+}
+
+// test returns the chi-square result of observed against expected counts,
+// pooling small cells. It returns false if there is nothing to test.
+func test(name string, observed []int, expected []float64) (Result, bool) {
+	for i, e := range expected {
+		if e == 0 && observed[i] > 0 {
+			return Result{Name: name, PValue: 0}, true
+		}
+	}
+
+	observed, expected = pool(observed, expected, minExpected)
+	if len(expected) < 2 {
+		return Result{}, false
+	}
+
+	x := chiSquare(observed, expected)
+	df := len(expected) - 1
+	return Result{Name: name, Statistic: x, DF: df, PValue: ChiSquarePValue(x, df)}, true
+}
+
+func (s *stats) report(samples int) *Report {
+	r := &Report{Samples: samples}
+	r.Results = append(r.Results,
+		exact("length", s.badLength),
+		exact("alphabet", s.badAlphabet))
+	if s.badLength > 0 || s.badAlphabet > 0 {
+		return r
+	}
+
+	for i, c := range s.classes {
+		if c.count == 0 {
+			continue
+		}
+		r.Results = append(r.Results, exact("class-count/"+c.name, s.badCounts[i]))
+	}
+
+	add := func(res Result, ok bool) {
+		if ok {
+			r.Results = append(r.Results, res)
+		}
+	}
+
+	// Every character of a class is equally likely, weighted by how often
+	// it appears in its character set.
+	for i, c := range s.classes {
+		total := float64(samples * c.count)
+		observed := make([]int, len(c.runes))
+		expected := make([]float64, len(c.runes))
+		for j, ch := range c.runes {
+			observed[j] = s.chars[i][ch]
+			expected[j] = total * c.weight[ch]
+		}
+		add(test("characters/"+c.name, observed, expected))
+	}
+
+	// Every position holds each character with the probability of its class
+	// landing there times its probability within the class.
+	for pos := 0; pos < s.cfg.Length; pos++ {
+		var observed []int
+		var expected []float64
+		for _, c := range s.classes {
+			share := float64(samples*c.count) / float64(s.cfg.Length)
+			for _, ch := range c.runes {
+				observed = append(observed, s.atPos[pos][ch])
+				expected = append(expected, share*c.weight[ch])
+			}
+		}
+		add(test(fmt.Sprintf("position[%d]", pos), observed, expected))
+	}
+
+	// Digits and symbols land on every position equally often.
+	for i, c := range s.classes[1:] {
+		if c.count == 0 || c.count == s.cfg.Length {
+			continue
+		}
+		expected := make([]float64, s.cfg.Length)
+		for pos := range expected {
+			expected[pos] = float64(samples*c.count) / float64(s.cfg.Length)
+		}
+		add(test("insertion/"+c.name, s.positions[i+1], expected))
+	}
+
+	// Uppercase letters follow a binomial distribution among the letters,
+	// or a hypergeometric one without repeats.
+	if up, weight := s.upperLetters(); s.cfg.IncludeUpper && up > 0 {
+		letters := s.classes[0]
+		expected := make([]float64, len(s.upper))
+		for k := range expected {
+			if s.cfg.AllowRepeat {
+				expected[k] = float64(samples) * binomialPMF(letters.count, k, weight)
+			} else {
+				expected[k] = float64(samples) * hypergeometricPMF(len(letters.runes), up, letters.count, k)
+			}
+		}
+		add(test("class-count/upper", s.upper, expected))
+	}
+
+	return r
+}
+
+// upperLetters returns the number of distinct uppercase letters and the
+// probability of drawing one of them.
+func (s *stats) upperLetters() (int, float64) {
+	n, w := 0, 0.0
+	seen := make(map[rune]bool)
+	for _, r := range orDefault(s.cfg.UpperLetters, password.UpperLetters) {
+		if !seen[r] {
+			seen[r] = true
+			n++
+			w += s.classes[0].weight[r]
+		}
+	}
+	return n, w
+}
@@ -0,0 +1,204 @@
+package passwordtest
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/tullo/password/password"
+)
+
+// newGenerator returns a StatefulGenerator reading from a DRBG, so that the
+// tests are reproducible.
+func newGenerator(t *testing.T, input password.GeneratorInput) *password.StatefulGenerator {
+	t.Helper()
+
+	drbg, err := password.NewDRBG([]byte(strings.Repeat("passwordtest seed ", 2)), []byte(t.Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	input.Reader = drbg
+
+	gen, err := password.NewStatefulGenerator(&input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gen
+}
+
+// funcGenerator is a Generator whose Generate method is a function.
+type funcGenerator struct {
+	*password.MockPasswordGenerator
+	generate func(length, numDigits, numSymbols int, includeUpper, allowRepeat bool) (string, error)
+}
+
+func (g *funcGenerator) Generate(length, numDigits, numSymbols int, includeUpper, allowRepeat bool) (string, error) {
+	return g.generate(length, numDigits, numSymbols, includeUpper, allowRepeat)
+}
+
+func TestChiSquarePValue(t *testing.T) {
+	t.Parallel()
+
+	var TestCases = []struct {
+		Name     string
+		X        float64
+		DF       int
+		Expected float64
+	}{
+		{Name: "zero", X: 0, DF: 3, Expected: 1},
+		{Name: "df 1", X: 3.841459, DF: 1, Expected: 0.05},
+		{Name: "df 2", X: 9.210340, DF: 2, Expected: 0.01},
+		{Name: "df 10", X: 18.307038, DF: 10, Expected: 0.05},
+		{Name: "df 100", X: 124.342113, DF: 100, Expected: 0.05},
+		{Name: "median", X: 9.341818, DF: 10, Expected: 0.5},
+	}
+
+	for _, tc := range TestCases {
+		tc := tc
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			if p := ChiSquarePValue(tc.X, tc.DF); math.Abs(p-tc.Expected) > 1e-5 {
+				t.Errorf("expected %g to be %g", p, tc.Expected)
+			}
+		})
+	}
+}
+
+func TestRun_unbiased(t *testing.T) {
+	t.Parallel()
+
+	var TestCases = []struct {
+		Name   string
+		Input  password.GeneratorInput
+		Config Config
+	}{
+		{
+			Name:   "defaults",
+			Config: Config{Length: 16, NumDigits: 3, NumSymbols: 3, IncludeUpper: true, AllowRepeat: true},
+		},
+		{
+			Name:   "no repeats",
+			Config: Config{Length: 16, NumDigits: 4, NumSymbols: 2, IncludeUpper: true},
+		},
+		{
+			Name:   "lowercase only",
+			Config: Config{Length: 10, AllowRepeat: true},
+		},
+		{
+			Name:  "custom sets",
+			Input: password.GeneratorInput{LowerLetters: "abcd", UpperLetters: "WXYZ", Digits: "01", Symbols: "!@#"},
+			Config: Config{
+				Length: 8, NumDigits: 2, NumSymbols: 2, IncludeUpper: true, AllowRepeat: true,
+				LowerLetters: "abcd", UpperLetters: "WXYZ", Digits: "01", Symbols: "!@#",
+			},
+		},
+		{
+			Name:   "repeated set characters",
+			Input:  password.GeneratorInput{Symbols: "!!@"},
+			Config: Config{Length: 8, NumSymbols: 3, AllowRepeat: true, Symbols: "!!@"},
+		},
+	}
+
+	for _, tc := range TestCases {
+		tc := tc
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			tc.Config.Samples = 5000
+			report := Verify(t, newGenerator(t, tc.Input), tc.Config)
+			if report.Samples != 5000 {
+				t.Errorf("expected %d to be %d", report.Samples, 5000)
+			}
+			if len(report.Results) < 3 {
+				t.Errorf("expected more results in\n%v", report)
+			}
+		})
+	}
+}
+
+func TestRun_biased(t *testing.T) {
+	t.Parallel()
+
+	cfg := Config{Length: 8, NumDigits: 2, AllowRepeat: true, Samples: 5000}
+	gen := newGenerator(t, password.GeneratorInput{})
+
+	var TestCases = []struct {
+		Name     string
+		Generate func(length, numDigits, numSymbols int, includeUpper, allowRepeat bool) (string, error)
+		Expected string
+	}{
+		{
+			Name: "wrong length",
+			Generate: func(length, numDigits, numSymbols int, includeUpper, allowRepeat bool) (string, error) {
+				res, err := gen.Generate(length, numDigits, numSymbols, includeUpper, allowRepeat)
+				return res[1:], err
+			},
+			Expected: "length",
+		},
+		{
+			Name: "digits at the end",
+			Generate: func(length, numDigits, numSymbols int, includeUpper, allowRepeat bool) (string, error) {
+				res, err := gen.Generate(length, numDigits, numSymbols, includeUpper, allowRepeat)
+				var letters, digits []rune
+				for _, r := range res {
+					if strings.ContainsRune(password.Digits, r) {
+						digits = append(digits, r)
+					} else {
+						letters = append(letters, r)
+					}
+				}
+				return string(letters) + string(digits), err
+			},
+			Expected: "insertion/digits",
+		},
+		{
+			Name: "modulo bias",
+			Generate: func(length, numDigits, numSymbols int, includeUpper, allowRepeat bool) (string, error) {
+				res, err := gen.Generate(length, numDigits, numSymbols, includeUpper, allowRepeat)
+				// Make a, c and e more likely than v, x and z, as reducing
+				// random values modulo the alphabet size would.
+				return strings.Map(func(r rune) rune {
+					if r >= 'u' && r <= 'z' && r%2 == 0 {
+						return r - 'u' + 'a' - 1
+					}
+					return r
+				}, res), err
+			},
+			Expected: "characters/letters",
+		},
+	}
+
+	for _, tc := range TestCases {
+		tc := tc
+
+		t.Run(tc.Name, func(t *testing.T) {
+			report, err := Run(&funcGenerator{generate: tc.Generate}, cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			found := false
+			for _, res := range report.Failures(DefaultAlpha) {
+				found = found || res.Name == tc.Expected
+			}
+			if !found {
+				t.Errorf("expected %s to fail in\n%v", tc.Expected, report)
+			}
+		})
+	}
+}
+
+func TestRun_errors(t *testing.T) {
+	t.Parallel()
+
+	if _, err := Run(newGenerator(t, password.GeneratorInput{}), Config{Length: 4, Digits: "0a"}); !errors.Is(err, ErrOverlappingSets) {
+		t.Errorf("expected %q to be %q", err, ErrOverlappingSets)
+	}
+	if _, err := Run(newGenerator(t, password.GeneratorInput{}), Config{Length: 4, NumDigits: 5}); !errors.Is(err, password.ErrExceedsTotalLength) {
+		t.Errorf("expected %q to be %q", err, password.ErrExceedsTotalLength)
+	}
+}
@@ -0,0 +1,141 @@
+package passwordtest
+
+import "math"
+
+// ChiSquarePValue returns the probability that a chi-square distributed
+// variable with df degrees of freedom is at least x.
+func ChiSquarePValue(x float64, df int) float64 {
+	if df <= 0 {
+		return math.NaN()
+	}
+	if x <= 0 {
+		return 1
+	}
+	return upperIncompleteGamma(float64(df)/2, x/2)
+}
+
+// chiSquare returns the chi-square statistic of observed counts against
+// expected counts. Cells with an expected count of zero are skipped; the
+// caller checks that nothing was observed there.
+func chiSquare(observed []int, expected []float64) float64 {
+	x := 0.0
+	for i, e := range expected {
+		if e == 0 {
+			continue
+		}
+		d := float64(observed[i]) - e
+		x += d * d / e
+	}
+	return x
+}
+
+// pool merges adjacent cells until every expected count is at least
+// minExpected, the usual requirement of the chi-square approximation.
+func pool(observed []int, expected []float64, minExpected float64) ([]int, []float64) {
+	var o []int
+	var e []float64
+	accO, accE := 0, 0.0
+	for i := range expected {
+		accO += observed[i]
+		accE += expected[i]
+		if accE >= minExpected {
+			o = append(o, accO)
+			e = append(e, accE)
+			accO, accE = 0, 0
+		}
+	}
+	if accE > 0 || accO > 0 {
+		if len(e) == 0 {
+			return []int{accO}, []float64{accE}
+		}
+		o[len(o)-1] += accO
+		e[len(e)-1] += accE
+	}
+	return o, e
+}
+
+const (
+	gammaEpsilon = 1e-15
+	gammaTiny    = 1e-300
+	gammaMaxIter = 10000
+)
+
+// upperIncompleteGamma returns the regularized upper incomplete gamma
+// function Q(a, x), using its series for x < a+1 and Lentz's continued
+// fraction otherwise.
+func upperIncompleteGamma(a, x float64) float64 {
+	lg, _ := math.Lgamma(a)
+	prefix := math.Exp(-x + a*math.Log(x) - lg)
+
+	if x < a+1 {
+		sum, term := 1/a, 1/a
+		for n := 1; n < gammaMaxIter; n++ {
+			term *= x / (a + float64(n))
+			sum += term
+			if math.Abs(term) < math.Abs(sum)*gammaEpsilon {
+				break
+			}
+		}
+		return math.Max(0, 1-sum*prefix)
+	}
+
+	b := x + 1 - a
+	c := 1 / gammaTiny
+	d := 1 / b
+	h := d
+	for i := 1; i < gammaMaxIter; i++ {
+		an := -float64(i) * (float64(i) - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < gammaTiny {
+			d = gammaTiny
+		}
+		c = b + an/c
+		if math.Abs(c) < gammaTiny {
+			c = gammaTiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < gammaEpsilon {
+			break
+		}
+	}
+	return prefix * h
+}
+
+// logChoose returns ln(n choose k).
+func logChoose(n, k int) float64 {
+	a, _ := math.Lgamma(float64(n + 1))
+	b, _ := math.Lgamma(float64(k + 1))
+	c, _ := math.Lgamma(float64(n - k + 1))
+	return a - b - c
+}
+
+// binomialPMF returns the probability of k successes in n draws with
+// replacement and success probability p.
+func binomialPMF(n, k int, p float64) float64 {
+	switch {
+	case p == 0:
+		if k == 0 {
+			return 1
+		}
+		return 0
+	case p == 1:
+		if k == n {
+			return 1
+		}
+		return 0
+	}
+	return math.Exp(logChoose(n, k) + float64(k)*math.Log(p) + float64(n-k)*math.Log(1-p))
+}
+
+// hypergeometricPMF returns the probability of k successes in n draws
+// without replacement from a population of size total containing
+// successes successes.
+func hypergeometricPMF(total, successes, n, k int) float64 {
+	if k < 0 || k > n || k > successes || n-k > total-successes {
+		return 0
+	}
+	return math.Exp(logChoose(successes, k) + logChoose(total-successes, n-k) - logChoose(total, n))
+}
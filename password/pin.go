@@ -0,0 +1,282 @@
+package password
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MinPINLength is the shortest PIN a PINGenerator creates.
+	MinPINLength = 4
+
+	// MaxPINLength is the longest PIN a PINGenerator creates.
+	MaxPINLength = 12
+)
+
+// PINWeakness is the reason a PIN is rejected as weak.
+type PINWeakness string
+
+const (
+	// PINSequence marks runs of consecutive digits such as 1234, 9876 or 7890.
+	PINSequence PINWeakness = "sequence"
+
+	// PINRepeated marks PINs that repeat a single digit or a pair of digits,
+	// such as 1111 or 1212.
+	PINRepeated PINWeakness = "repeated"
+
+	// PINCommon marks PINs from the built-in list of frequently used PINs.
+	PINCommon PINWeakness = "common"
+
+	// PINDate marks PINs that read as a date or a year, such as 0704 or 1987.
+	PINDate PINWeakness = "date"
+
+	// PINBlocklisted marks PINs from the user-supplied blocklist.
+	PINBlocklisted PINWeakness = "blocklisted"
+)
+
+var (
+	// ErrPINLengthInvalid is the error returned when the PIN length is outside
+	// of MinPINLength and MaxPINLength.
+	ErrPINLengthInvalid = fmt.Errorf("pin length must be between %d and %d", MinPINLength, MaxPINLength)
+
+	// ErrPINBlocklistInvalid is the error returned when the blocklist contains
+	// an entry that is not made of digits.
+	ErrPINBlocklistInvalid = errors.New("pin blocklist entries must only contain digits")
+)
+
+// commonPINs are frequently chosen PINs, taken from published analyses of
+// leaked PIN and passcode datasets.
+var commonPINs = []string{
+	"1234", "1111", "0000", "1212", "7777", "1004", "2000", "4444", "2222",
+	"6969", "9999", "3333", "5555", "6666", "1122", "1313", "8888", "4321",
+	"2001", "1010", "2580", "0852", "1379", "1470", "0987", "5683",
+	"123456", "654321", "111111", "000000", "123123", "666666", "121212",
+	"112233", "789456", "159753", "987654", "147258", "123321", "696969",
+	"159357", "147852", "258369", "246810",
+	"12345678", "87654321", "11111111", "00000000", "12341234", "11223344",
+	"147258369", "123456789", "987654321", "1234567890", "0987654321",
+}
+
+// PINInput is used as input to the NewPINGenerator function.
+type PINInput struct {
+	Length    int       // MinPINLength by default
+	Blocklist []string  // PINs of other lengths are ignored
+	Reader    io.Reader // rand.Reader by default
+}
+
+// PINGenerator generates numeric PINs, rejecting sequences, repeated digits,
+// common PINs, dates and blocklisted PINs. Unlike Generate, digits may repeat
+// within a PIN. A PINGenerator is safe for concurrent use.
+type PINGenerator struct {
+	length int
+	weak   map[string]PINWeakness
+	reader io.Reader
+}
+
+// NewPINGenerator creates a new PINGenerator from the specified
+// configuration. If no input is given, all the default values are used.
+func NewPINGenerator(i *PINInput) (*PINGenerator, error) {
+	if i == nil {
+		i = new(PINInput)
+	}
+
+	g := &PINGenerator{
+		length: i.Length,
+		weak:   make(map[string]PINWeakness),
+		reader: i.Reader,
+	}
+
+	if g.length == 0 {
+		g.length = MinPINLength
+	}
+
+	if g.length < MinPINLength || g.length > MaxPINLength {
+		return nil, ErrPINLengthInvalid
+	}
+
+	if g.reader == nil {
+		g.reader = rand.Reader
+	}
+
+	for _, pin := range i.Blocklist {
+		if strings.Trim(pin, Digits) != "" {
+			return nil, ErrPINBlocklistInvalid
+		}
+	}
+
+	// Earlier rules take precedence when reporting why a PIN is weak.
+	g.exclude(PINBlocklisted, i.Blocklist)
+	g.exclude(PINSequence, sequencePINs(g.length))
+	g.exclude(PINRepeated, repeatedPINs(g.length))
+	g.exclude(PINCommon, commonPINs)
+	g.exclude(PINDate, datePINs(g.length))
+
+	return g, nil
+}
+
+// Generate generates a PIN that is not weak.
+func (g *PINGenerator) Generate() (string, error) {
+	return g.GenerateContext(context.Background())
+}
+
+// GenerateContext is the same as Generate, but stops between random draws
+// once ctx is done.
+func (g *PINGenerator) GenerateContext(ctx context.Context) (string, error) {
+	src := newRandomSource(ctx, g.reader)
+	buf := make([]byte, g.length)
+	for {
+		for i := range buf {
+			d, err := src.intn(10)
+			if err != nil {
+				return "", progressError(readerError(err, "digits"), i, g.length)
+			}
+			buf[i] = Digits[d]
+		}
+
+		if pin := string(buf); !g.IsWeak(pin) {
+			return pin, nil
+		}
+	}
+}
+
+// MustGenerate is the same as Generate, but panics on error.
+func (g *PINGenerator) MustGenerate() string {
+	res, err := g.Generate()
+	if err != nil {
+		panic(err)
+	}
+	return res
+}
+
+// Weakness returns why pin is weak, or the empty string if it is not. Only
+// PINs of the generator's length are judged.
+func (g *PINGenerator) Weakness(pin string) PINWeakness {
+	return g.weak[pin]
+}
+
+// IsWeak reports whether pin is rejected by the generator.
+func (g *PINGenerator) IsWeak(pin string) bool {
+	_, ok := g.weak[pin]
+	return ok
+}
+
+// Candidates returns the number of PINs the generator chooses from. It is an
+// int64 because there are more PINs of MaxPINLength than a 32-bit int holds.
+func (g *PINGenerator) Candidates() int64 {
+	n := int64(1)
+	for i := 0; i < g.length; i++ {
+		n *= 10
+	}
+	return n - int64(len(g.weak))
+}
+
+// Entropy returns the entropy in bits of the generated PINs, which is lower
+// than that of a random PIN of the same length because weak PINs are
+// excluded.
+func (g *PINGenerator) Entropy() float64 {
+	return math.Log2(float64(g.Candidates()))
+}
+
+// exclude marks the PINs of the generator's length as weak for the given
+// reason, unless they were already marked.
+func (g *PINGenerator) exclude(reason PINWeakness, pins []string) {
+	for _, pin := range pins {
+		if len(pin) != g.length {
+			continue
+		}
+		if _, ok := g.weak[pin]; !ok {
+			g.weak[pin] = reason
+		}
+	}
+}
+
+// sequencePINs returns the ascending and descending runs of digits of the
+// given length, wrapping around from 9 to 0.
+func sequencePINs(length int) []string {
+	var pins []string
+	for start := 0; start < 10; start++ {
+		for _, step := range []int{1, 9} {
+			b := make([]byte, length)
+			for i := range b {
+				b[i] = Digits[(start+i*step)%10]
+			}
+			pins = append(pins, string(b))
+		}
+	}
+	return pins
+}
+
+// repeatedPINs returns the PINs made of a single repeated digit or a repeated
+// pair of digits.
+func repeatedPINs(length int) []string {
+	var pins []string
+	for a := 0; a < 10; a++ {
+		for b := 0; b < 10; b++ {
+			pin := make([]byte, length)
+			for i := range pin {
+				pin[i] = Digits[a]
+				if i%2 == 1 {
+					pin[i] = Digits[b]
+				}
+			}
+			pins = append(pins, string(pin))
+		}
+	}
+	return pins
+}
+
+// datePINs returns the PINs that read as a date or a year of the 20th or
+// 21st century: MMDD, DDMM and YYYY for four digits, DDMMYY, MMDDYY and
+// YYMMDD for six, and DDMMYYYY, MMDDYYYY and YYYYMMDD for eight.
+func datePINs(length int) []string {
+	if length != 4 && length != 6 && length != 8 {
+		return nil
+	}
+
+	days := [12]int{31, 29, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}
+
+	var pins []string
+	if length == 4 {
+		for year := 1900; year < 2100; year++ {
+			pins = append(pins, zeroPad(year, 4))
+		}
+	}
+
+	for month := 1; month <= 12; month++ {
+		mm := zeroPad(month, 2)
+		for day := 1; day <= days[month-1]; day++ {
+			dd := zeroPad(day, 2)
+			switch length {
+			case 4:
+				pins = append(pins,
+					mm+dd, dd+mm)
+			case 6:
+				for year := 0; year < 100; year++ {
+					yy := zeroPad(year, 2)
+					pins = append(pins, dd+mm+yy, mm+dd+yy, yy+mm+dd)
+				}
+			case 8:
+				for year := 1900; year < 2100; year++ {
+					yyyy := zeroPad(year, 4)
+					pins = append(pins, dd+mm+yyyy, mm+dd+yyyy, yyyy+mm+dd)
+				}
+			}
+		}
+	}
+	return pins
+}
+
+// zeroPad formats v with leading zeros up to width digits.
+func zeroPad(v, width int) string {
+	s := strconv.Itoa(v)
+	if len(s) < width {
+		s = strings.Repeat("0", width-len(s)) + s
+	}
+	return s
+}
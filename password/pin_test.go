@@ -0,0 +1,181 @@
+package password
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestPINGenerator_Weakness(t *testing.T) {
+	t.Parallel()
+
+	gen, err := NewPINGenerator(&PINInput{
+		Blocklist: []string{"4711", "123456"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var TestCases = []struct {
+		PIN      string
+		Expected PINWeakness
+	}{
+		{PIN: "1234", Expected: PINSequence},
+		{PIN: "9876", Expected: PINSequence},
+		{PIN: "7890", Expected: PINSequence},
+		{PIN: "1111", Expected: PINRepeated},
+		{PIN: "1212", Expected: PINRepeated},
+		{PIN: "2580", Expected: PINCommon},
+		{PIN: "6969", Expected: PINRepeated},
+		{PIN: "1004", Expected: PINCommon},
+		{PIN: "1987", Expected: PINDate},
+		{PIN: "0704", Expected: PINDate},
+		{PIN: "3112", Expected: PINDate},
+		{PIN: "4711", Expected: PINBlocklisted},
+		{PIN: "3529", Expected: ""},
+		{PIN: "8642", Expected: ""},
+		{PIN: "123456", Expected: ""}, // different length
+	}
+
+	for _, tc := range TestCases {
+		if got := gen.Weakness(tc.PIN); got != tc.Expected {
+			t.Errorf("Weakness(%q) = %q, want %q", tc.PIN, got, tc.Expected)
+		}
+
+		if got := gen.IsWeak(tc.PIN); got != (tc.Expected != "") {
+			t.Errorf("IsWeak(%q) = %t", tc.PIN, got)
+		}
+	}
+}
+
+func TestPINGenerator_Weakness_long(t *testing.T) {
+	t.Parallel()
+
+	gen, err := NewPINGenerator(&PINInput{Length: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var TestCases = []struct {
+		PIN      string
+		Expected PINWeakness
+	}{
+		{PIN: "56789012", Expected: PINSequence},
+		{PIN: "12341234", Expected: PINCommon},
+		{PIN: "19870704", Expected: PINDate},
+		{PIN: "04071987", Expected: PINDate},
+		{PIN: "07041987", Expected: PINDate},
+		{PIN: "31022000", Expected: ""},
+		{PIN: "58304141", Expected: ""},
+	}
+
+	for _, tc := range TestCases {
+		if got := gen.Weakness(tc.PIN); got != tc.Expected {
+			t.Errorf("Weakness(%q) = %q, want %q", tc.PIN, got, tc.Expected)
+		}
+	}
+}
+
+func TestPINGenerator_Generate(t *testing.T) {
+	t.Parallel()
+
+	for length := MinPINLength; length <= MaxPINLength; length++ {
+		gen, err := NewPINGenerator(&PINInput{Length: length})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i := 0; i < 100; i++ {
+			pin := gen.MustGenerate()
+			if len(pin) != length || strings.Trim(pin, Digits) != "" {
+				t.Errorf("%q is not a %d-digit PIN", pin, length)
+			}
+
+			if gen.IsWeak(pin) {
+				t.Errorf("%q is weak (%s)", pin, gen.Weakness(pin))
+			}
+		}
+	}
+}
+
+func TestPINGenerator_Entropy(t *testing.T) {
+	t.Parallel()
+
+	// 20 sequences, 100 single digit and pair repetitions of which 10 are
+	// single digits, 200 years and 366 days as MMDD and DDMM, plus common
+	// PINs, minus the overlap between them.
+	gen, err := NewPINGenerator(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := gen.Candidates(), int64(10000-873); got != want {
+		t.Errorf("Candidates() = %d, want %d", got, want)
+	}
+
+	if got, want := gen.Entropy(), math.Log2(10000-873); got != want {
+		t.Errorf("Entropy() = %v, want %v", got, want)
+	}
+
+	blocked, err := NewPINGenerator(&PINInput{Blocklist: []string{"3529", "1234"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 1234 is already a sequence, so only one more PIN is excluded.
+	if got, want := blocked.Candidates(), gen.Candidates()-1; got != want {
+		t.Errorf("Candidates() = %d, want %d", got, want)
+	}
+
+	// Only the 20 sequences and 100 repetitions have 12 digits. The count
+	// does not fit into a 32-bit int.
+	long, err := NewPINGenerator(&PINInput{Length: MaxPINLength})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := long.Candidates(), int64(1000000000000-120); got != want {
+		t.Errorf("Candidates() = %d, want %d", got, want)
+	}
+
+	if got, want := long.Entropy(), math.Log2(1000000000000-120); math.Abs(got-want) > 1e-9 {
+		t.Errorf("Entropy() = %v, want %v", got, want)
+	}
+}
+
+func TestNewPINGenerator_errors(t *testing.T) {
+	t.Parallel()
+
+	var TestCases = []struct {
+		Name  string
+		Input PINInput
+		Err   error
+	}{
+		{
+			Name:  "too short",
+			Input: PINInput{Length: 3},
+			Err:   ErrPINLengthInvalid,
+		},
+		{
+			Name:  "too long",
+			Input: PINInput{Length: MaxPINLength + 1},
+			Err:   ErrPINLengthInvalid,
+		},
+		{
+			Name:  "blocklist",
+			Input: PINInput{Blocklist: []string{"12a4"}},
+			Err:   ErrPINBlocklistInvalid,
+		},
+	}
+
+	for _, tc := range TestCases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := NewPINGenerator(&tc.Input); err != tc.Err {
+				t.Errorf("expected %v to be %v", err, tc.Err)
+			}
+		})
+	}
+}
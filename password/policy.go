@@ -0,0 +1,774 @@
+package password
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultPolicyLength is the length of passwords generated for a policy that
+// sets neither a length nor a minimum length above it.
+const DefaultPolicyLength = 16
+
+const (
+	// policyMaxAttempts is the number of candidates in a row
+	// GenerateForPolicy discards before giving up.
+	policyMaxAttempts = 1000
+
+	// policySearchLimit bounds the number of partial passwords Check explores
+	// when looking for one that satisfies all rules.
+	policySearchLimit = 1 << 16
+)
+
+// Names of the built-in character classes. A ClassRule with one of these
+// names and no characters uses the package defaults.
+const (
+	ClassLower  = "lower"
+	ClassUpper  = "upper"
+	ClassDigit  = "digit"
+	ClassSymbol = "symbol"
+)
+
+var builtinClasses = map[string]string{
+	ClassLower:  LowerLetters,
+	ClassUpper:  UpperLetters,
+	ClassDigit:  Digits,
+	ClassSymbol: Symbols,
+}
+
+// ClassRule allows a set of characters and bounds how many of them a
+// password contains.
+type ClassRule struct {
+	Name  string `json:"name"`
+	Chars string `json:"chars,omitempty"` // defaults for built-in class names
+	Min   int    `json:"min,omitempty"`   // a positive minimum makes the class required
+	Max   int    `json:"max,omitempty"`   // 0 means no maximum
+}
+
+// Policy describes acceptable passwords. It can be built in code or loaded
+// from JSON, YAML or TOML with ParsePolicy, and both validates candidates and
+// drives generation. The characters allowed in a password are those of all
+// classes, minus the excluded ones.
+type Policy struct {
+	Length         int         `json:"length,omitempty"`     // length of generated passwords
+	MinLength      int         `json:"min_length,omitempty"` // shortest accepted password
+	MaxLength      int         `json:"max_length,omitempty"` // 0 means no maximum
+	Classes        []ClassRule `json:"classes"`
+	NoRepeat       bool        `json:"no_repeat,omitempty"`       // no character may appear twice
+	MaxConsecutive int         `json:"max_consecutive,omitempty"` // longest run of one character, 0 means no limit
+	Exclude        string      `json:"exclude,omitempty"`         // characters never allowed
+	Blocklist      []string    `json:"blocklist,omitempty"`       // case-insensitive substrings never allowed
+}
+
+// PolicyError is the error returned for an invalid policy. Line is the line
+// of the offending field in the policy file, or 0 if unknown.
+type PolicyError struct {
+	Line    int
+	Field   string
+	Message string
+
+	err error
+}
+
+// Error implements the error interface.
+func (e *PolicyError) Error() string {
+	var b strings.Builder
+	if e.Line > 0 {
+		fmt.Fprintf(&b, "line %d: ", e.Line)
+	}
+	if e.Field != "" {
+		b.WriteString(e.Field)
+		b.WriteString(": ")
+	}
+	b.WriteString(e.Message)
+	return b.String()
+}
+
+// Unwrap returns ErrPolicyUnsatisfiable if no password can satisfy the
+// policy, and nil otherwise.
+func (e *PolicyError) Unwrap() error {
+	return e.err
+}
+
+// policyClass is a ClassRule resolved for validation and generation.
+type policyClass struct {
+	rule    ClassRule
+	field   string
+	charset *Charset
+	runes   []rune // distinct characters, excluded ones removed
+}
+
+// Check reports whether the policy itself is well-formed and satisfiable.
+// It returns a *PolicyError describing the first problem found. If the rules
+// are well-formed but no password of the generated length meets all of them,
+// such as when the blocklist or MaxConsecutive rule out every candidate, the
+// error wraps ErrPolicyUnsatisfiable.
+func (p *Policy) Check() error {
+	classes, err := p.resolve()
+	if err != nil {
+		return err
+	}
+	return p.checkSatisfiable(classes)
+}
+
+// checkSatisfiable returns a *PolicyError wrapping ErrPolicyUnsatisfiable if
+// no password of the generated length satisfies p. Policies whose search
+// exceeds policySearchLimit are assumed to be satisfiable.
+func (p *Policy) checkSatisfiable(classes []policyClass) error {
+	length := p.GenerateLength()
+	if ok, decided := p.satisfiable(classes, length); decided && !ok {
+		return &PolicyError{
+			Message: fmt.Sprintf("no password of length %d satisfies all rules", length),
+			err:     ErrPolicyUnsatisfiable,
+		}
+	}
+	return nil
+}
+
+// GenerateLength returns the length of generated passwords: Length if set,
+// otherwise the larger of MinLength and DefaultPolicyLength, capped at
+// MaxLength.
+func (p *Policy) GenerateLength() int {
+	if p.Length > 0 {
+		return p.Length
+	}
+
+	n := DefaultPolicyLength
+	if p.MinLength > n {
+		n = p.MinLength
+	}
+	if p.MaxLength > 0 && n > p.MaxLength {
+		n = p.MaxLength
+	}
+	return n
+}
+
+// resolve checks the policy and resolves its classes.
+func (p *Policy) resolve() ([]policyClass, error) {
+	fail := func(field, format string, args ...interface{}) ([]policyClass, error) {
+		return nil, &PolicyError{Field: field, Message: fmt.Sprintf(format, args...)}
+	}
+
+	switch {
+	case p.Length < 0:
+		return fail("length", "must not be negative")
+	case p.MinLength < 0:
+		return fail("min_length", "must not be negative")
+	case p.MaxLength < 0:
+		return fail("max_length", "must not be negative")
+	case p.MaxLength > 0 && p.MaxLength < p.MinLength:
+		return fail("max_length", "must not be less than min_length %d", p.MinLength)
+	case p.Length > 0 && p.Length < p.MinLength:
+		return fail("length", "must not be less than min_length %d", p.MinLength)
+	case p.Length > 0 && p.MaxLength > 0 && p.Length > p.MaxLength:
+		return fail("length", "must not exceed max_length %d", p.MaxLength)
+	case p.MaxConsecutive < 0:
+		return fail("max_consecutive", "must not be negative")
+	case len(p.Classes) == 0:
+		return fail("classes", "at least one character class is required")
+	}
+
+	for i, entry := range p.Blocklist {
+		if entry == "" {
+			return fail(fmt.Sprintf("blocklist[%d]", i), "must not be empty")
+		}
+	}
+
+	exclude := NewCharset(p.Exclude)
+	length := p.GenerateLength()
+	classes := make([]policyClass, len(p.Classes))
+	names := make(map[string]bool, len(p.Classes))
+	var all runeSet
+	distinct, mins, maxes := 0, 0, 0
+	bounded := true
+
+	for i, rule := range p.Classes {
+		field := fmt.Sprintf("classes[%d]", i)
+		switch {
+		case rule.Name == "":
+			return fail(field+".name", "must not be empty")
+		case names[rule.Name]:
+			return fail(field+".name", "duplicate class %q", rule.Name)
+		case rule.Min < 0:
+			return fail(field+".min", "must not be negative")
+		case rule.Max < 0:
+			return fail(field+".max", "must not be negative")
+		case rule.Max > 0 && rule.Max < rule.Min:
+			return fail(field+".max", "must not be less than min %d", rule.Min)
+		}
+		names[rule.Name] = true
+
+		chars := rule.Chars
+		if chars == "" {
+			chars = builtinClasses[rule.Name]
+		}
+		if chars == "" {
+			return fail(field+".chars", "must not be empty unless the class is one of lower, upper, digit or symbol")
+		}
+		if !utf8.ValidString(chars) {
+			return fail(field+".chars", "must be valid UTF-8")
+		}
+
+		c := policyClass{rule: rule, field: field, charset: NewCharset(chars)}
+		var seen runeSet
+		for _, r := range chars {
+			if exclude.Contains(r) || seen.contains(r) {
+				continue
+			}
+			seen.add(r)
+			c.runes = append(c.runes, r)
+			if !all.contains(r) {
+				all.add(r)
+				distinct++
+			}
+		}
+
+		if rule.Min > 0 && len(c.runes) == 0 {
+			return fail(field+".chars", "all characters are excluded")
+		}
+		if p.NoRepeat && rule.Min > len(c.runes) {
+			return fail(field+".min", "exceeds the %d available characters and repeats are not allowed", len(c.runes))
+		}
+
+		mins += rule.Min
+		maxes += rule.Max
+		bounded = bounded && rule.Max > 0
+		classes[i] = c
+	}
+
+	switch {
+	case distinct == 0:
+		return fail("classes", "all characters are excluded")
+	case mins > length:
+		return fail("classes", "minimum counts add up to %d, more than the password length %d", mins, length)
+	case bounded && maxes < length:
+		return fail("classes", "maximum counts add up to %d, less than the password length %d", maxes, length)
+	case p.NoRepeat && length > distinct:
+		return fail("no_repeat", "password length %d exceeds the %d available characters", length, distinct)
+	}
+
+	return classes, nil
+}
+
+// Validate checks password against the policy and returns a
+// *ValidationError for the first violated rule. An invalid policy is
+// reported as a *PolicyError. Policy implements Validator.
+func (p *Policy) Validate(password string) error {
+	classes, err := p.resolve()
+	if err != nil {
+		return err
+	}
+	return p.validate(classes, password)
+}
+
+// validate implements Validate for resolved classes.
+func (p *Policy) validate(classes []policyClass, password string) error {
+	reject := func(rule, format string, args ...interface{}) error {
+		return &ValidationError{Rule: rule, Message: fmt.Sprintf(format, args...)}
+	}
+
+	n := utf8.RuneCountInString(password)
+	if n < p.MinLength {
+		return reject("min_length", "must be at least %d characters long", p.MinLength)
+	}
+	if p.MaxLength > 0 && n > p.MaxLength {
+		return reject("max_length", "must be at most %d characters long", p.MaxLength)
+	}
+
+	exclude := NewCharset(p.Exclude)
+	var seen runeSet
+	var last rune
+	run := 0
+	for i, r := range password {
+		if exclude.Contains(r) {
+			return reject("exclude", "contains an excluded character at position %d", i)
+		}
+
+		allowed := false
+		for _, c := range classes {
+			allowed = allowed || c.charset.Contains(r)
+		}
+		if !allowed {
+			return reject("classes", "contains a character outside of the allowed classes at position %d", i)
+		}
+
+		if p.NoRepeat && seen.contains(r) {
+			return reject("no_repeat", "repeats a character")
+		}
+		seen.add(r)
+
+		if r == last {
+			run++
+		} else {
+			last, run = r, 1
+		}
+		if p.MaxConsecutive > 0 && run > p.MaxConsecutive {
+			return reject("max_consecutive", "repeats a character more than %d times in a row", p.MaxConsecutive)
+		}
+	}
+
+	for _, c := range classes {
+		count := c.charset.Count(password)
+		if count < c.rule.Min {
+			return reject(c.field+".min", "needs at least %d %s characters", c.rule.Min, c.rule.Name)
+		}
+		if c.rule.Max > 0 && count > c.rule.Max {
+			return reject(c.field+".max", "allows at most %d %s characters", c.rule.Max, c.rule.Name)
+		}
+	}
+
+	lower := strings.ToLower(password)
+	for i, entry := range p.Blocklist {
+		if strings.Contains(lower, strings.ToLower(entry)) {
+			return reject(fmt.Sprintf("blocklist[%d]", i), "contains a blocked word")
+		}
+	}
+
+	return nil
+}
+
+// GenerateForPolicy generates a password satisfying p, using the generator's
+// reader. The generator's character sets are ignored in favor of the
+// policy's classes. Every class first receives its minimum number of
+// characters, the rest are drawn uniformly from all allowed characters while
+// respecting class maximums, and the characters are arranged in random order
+// without runs longer than MaxConsecutive. Candidates violating the remaining
+// rules are discarded. It returns an error wrapping ErrPolicyUnsatisfiable if no
+// password can satisfy p, or if 1000 candidates in a row were discarded.
+func (g *StatefulGenerator) GenerateForPolicy(p *Policy) (string, error) {
+	return g.GenerateForPolicyContext(context.Background(), p)
+}
+
+// GenerateForPolicyContext is the same as GenerateForPolicy, but stops
+// between attempts and random draws once ctx is done.
+func (g *StatefulGenerator) GenerateForPolicyContext(ctx context.Context, p *Policy) (string, error) {
+	classes, err := p.resolve()
+	if err != nil {
+		return "", err
+	}
+
+	var allowed []rune
+	var seen runeSet
+	for _, c := range classes {
+		for _, r := range c.runes {
+			if !seen.contains(r) {
+				seen.add(r)
+				allowed = append(allowed, r)
+			}
+		}
+	}
+
+	length := p.GenerateLength()
+	src := newRandomSource(ctx, g.reader)
+	for attempt := 0; attempt < policyMaxAttempts; attempt++ {
+		buf, err := pickPolicyRunes(src, p, classes, allowed, length)
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil && errors.Is(err, ctxErr) {
+				return "", fmt.Errorf("%w (after %d rejected attempts)", err, attempt)
+			}
+			return "", err
+		}
+
+		if buf != nil {
+			result := string(buf)
+			if p.validate(classes, result) == nil {
+				return result, nil
+			}
+		}
+		if attempt == 0 {
+			if err := p.checkSatisfiable(classes); err != nil {
+				return "", err
+			}
+		}
+	}
+	return "", fmt.Errorf("%w: rejected %d candidates in a row", ErrPolicyUnsatisfiable, policyMaxAttempts)
+}
+
+// policyToken stands for the allowed characters that are interchangeable
+// when searching for a password satisfying a policy: those in the same
+// classes and in no blocklist entry. A character in a blocklist entry is a
+// token of its own.
+type policyToken struct {
+	classes []int  // indexes of the classes containing the characters
+	size    int    // number of characters
+	lower   string // lowercase character if it is in a blocklist entry
+}
+
+// satisfiable searches for a password of the given length satisfying p.
+// Rather than characters, it places tokens, which keeps the search small for
+// large alphabets, and remembers the states known to lead nowhere. decided
+// is false if the search exceeded policySearchLimit.
+func (p *Policy) satisfiable(classes []policyClass, length int) (ok, decided bool) {
+	entries := make([]string, len(p.Blocklist))
+	longest := 0
+	for i, entry := range p.Blocklist {
+		entries[i] = strings.ToLower(entry)
+		if len(entries[i]) > longest {
+			longest = len(entries[i])
+		}
+	}
+
+	var tokens []policyToken
+	groups := make(map[string]int)
+	var seen runeSet
+	for _, c := range classes {
+		for _, r := range c.runes {
+			if seen.contains(r) {
+				continue
+			}
+			seen.add(r)
+
+			var in []int
+			for i, d := range classes {
+				if d.charset.Contains(r) {
+					in = append(in, i)
+				}
+			}
+
+			lower := strings.ToLower(string(r))
+			blocked := false
+			for _, entry := range entries {
+				blocked = blocked || strings.Contains(entry, lower)
+			}
+			if blocked {
+				tokens = append(tokens, policyToken{classes: in, size: 1, lower: lower})
+				continue
+			}
+
+			key := fmt.Sprint(in)
+			if j, ok := groups[key]; ok {
+				tokens[j].size++
+				continue
+			}
+			groups[key] = len(tokens)
+			tokens = append(tokens, policyToken{classes: in, size: 1})
+		}
+	}
+
+	counts := make([]int, len(classes))
+	used := make([]int, len(tokens))
+	failed := make(map[string]bool)
+	last, run, suffix := -1, 0, ""
+	visited := 0
+
+	var search func(pos int) bool
+	search = func(pos int) bool {
+		remaining := length - pos
+		for i, c := range classes {
+			if c.rule.Min-counts[i] > remaining {
+				return false
+			}
+		}
+		if remaining == 0 {
+			return true
+		}
+
+		// Runs only matter for single characters, and the tokens used only
+		// when repeats are not allowed.
+		key := fmt.Sprint(pos, counts, suffix)
+		if p.MaxConsecutive > 0 && last >= 0 && tokens[last].size == 1 {
+			key += fmt.Sprint(last, run)
+		}
+		if p.NoRepeat {
+			key += fmt.Sprint(used)
+		}
+		if failed[key] {
+			return false
+		}
+		if visited++; visited > policySearchLimit {
+			return false
+		}
+
+		for t := range tokens {
+			tok := &tokens[t]
+			if p.NoRepeat && used[t] >= tok.size {
+				continue
+			}
+
+			// Tokens of several characters alternate between them.
+			newRun := 1
+			if t == last && tok.size == 1 {
+				newRun = run + 1
+			}
+			if p.MaxConsecutive > 0 && newRun > p.MaxConsecutive {
+				continue
+			}
+
+			full := false
+			for _, i := range tok.classes {
+				full = full || classes[i].rule.Max > 0 && counts[i] >= classes[i].rule.Max
+			}
+			if full {
+				continue
+			}
+
+			newSuffix := ""
+			if tok.lower != "" {
+				newSuffix = suffix + tok.lower
+				for _, entry := range entries {
+					full = full || strings.HasSuffix(newSuffix, entry)
+				}
+				if full {
+					continue
+				}
+				if len(newSuffix) >= longest {
+					newSuffix = newSuffix[len(newSuffix)-longest+1:]
+				}
+			}
+
+			prevLast, prevRun, prevSuffix := last, run, suffix
+			for _, i := range tok.classes {
+				counts[i]++
+			}
+			used[t]++
+			last, run, suffix = t, newRun, newSuffix
+
+			found := search(pos + 1)
+
+			for _, i := range tok.classes {
+				counts[i]--
+			}
+			used[t]--
+			last, run, suffix = prevLast, prevRun, prevSuffix
+
+			if found {
+				return true
+			}
+		}
+
+		if visited <= policySearchLimit {
+			failed[key] = true
+		}
+		return false
+	}
+
+	ok = search(0)
+	return ok, ok || visited <= policySearchLimit
+}
+
+// pickPolicyRunes draws one candidate password for a policy. Every draw is
+// uniform over the characters that can still be added without exceeding a
+// class maximum or repeating a character when repeats are not allowed. A nil
+// buffer without error means the draws ran into a dead end and the caller
+// should try again.
+func pickPolicyRunes(src *randomSource, p *Policy, classes []policyClass, allowed []rune, length int) ([]rune, error) {
+	buf := make([]rune, 0, length)
+	counts := make([]int, len(classes))
+	candidates := make([]rune, 0, len(allowed))
+	var seen runeSet
+
+	addable := func(r rune) bool {
+		if p.NoRepeat && seen.contains(r) {
+			return false
+		}
+		for i, c := range classes {
+			if c.rule.Max > 0 && counts[i] >= c.rule.Max && c.charset.Contains(r) {
+				return false
+			}
+		}
+		return true
+	}
+
+	pick := func(from []rune) (bool, error) {
+		candidates = candidates[:0]
+		for _, r := range from {
+			if addable(r) {
+				candidates = append(candidates, r)
+			}
+		}
+		if len(candidates) == 0 {
+			return false, nil
+		}
+
+		j, err := src.intn(len(candidates))
+		if err != nil {
+			return false, progressError(readerError(err, "characters"), len(buf), length)
+		}
+		r := candidates[j]
+		for i, c := range classes {
+			if c.charset.Contains(r) {
+				counts[i]++
+			}
+		}
+		seen.add(r)
+		buf = append(buf, r)
+		return true, nil
+	}
+
+	for i, c := range classes {
+		for counts[i] < c.rule.Min {
+			if ok, err := pick(c.runes); !ok {
+				return nil, err
+			}
+		}
+	}
+
+	for len(buf) < length {
+		if ok, err := pick(allowed); !ok {
+			return nil, err
+		}
+	}
+
+	if p.MaxConsecutive > 0 && !p.NoRepeat {
+		ok, err := arrangePolicyRunes(src, buf, p.MaxConsecutive)
+		if !ok {
+			return nil, err
+		}
+		return buf, nil
+	}
+
+	if err := shuffle(src, buf); err != nil {
+		return nil, progressError(readerError(err, "insert"), length, length)
+	}
+	return buf, nil
+}
+
+// arrangePolicyRunes orders buf randomly such that no character appears more
+// than max times in a row. Every position is drawn uniformly from the
+// remaining characters that can be placed there without leaving the rest
+// impossible to arrange. It returns false without error if buf cannot be
+// arranged.
+func arrangePolicyRunes(src *randomSource, buf []rune, max int) (bool, error) {
+	counts := make(map[rune]int)
+	for _, r := range buf {
+		counts[r]++
+	}
+
+	rest := append([]rune(nil), buf...)
+	candidates := make([]int, 0, len(rest))
+	placeable := make(map[rune]bool, len(counts))
+	var last rune
+	run := 0
+
+	for pos := range buf {
+		for r := range placeable {
+			delete(placeable, r)
+		}
+		for r, n := range counts {
+			if n == 0 {
+				continue
+			}
+			newRun := 1
+			if pos > 0 && r == last {
+				newRun = run + 1
+			}
+			counts[r]--
+			placeable[r] = newRun <= max && arrangeable(counts, len(rest)-1, r, newRun, max)
+			counts[r]++
+		}
+
+		candidates = candidates[:0]
+		for i, r := range rest {
+			if placeable[r] {
+				candidates = append(candidates, i)
+			}
+		}
+		if len(candidates) == 0 {
+			return false, nil
+		}
+
+		j, err := src.intn(len(candidates))
+		if err != nil {
+			return false, progressError(readerError(err, "insert"), len(buf), len(buf))
+		}
+		i := candidates[j]
+		r := rest[i]
+		rest = append(rest[:i], rest[i+1:]...)
+		counts[r]--
+		buf[pos] = r
+
+		if pos > 0 && r == last {
+			run++
+		} else {
+			last, run = r, 1
+		}
+	}
+	return true, nil
+}
+
+// arrangeable reports whether n characters with the given counts can follow
+// a run of length run of last without any character appearing more than max
+// times in a row. Each character must fit into the gaps between the others.
+func arrangeable(counts map[rune]int, n int, last rune, run, max int) bool {
+	for r, f := range counts {
+		if f == 0 {
+			continue
+		}
+		limit := max * (n - f + 1)
+		if r == last {
+			limit -= run
+		}
+		if f > limit {
+			return false
+		}
+	}
+	return true
+}
+
+// PolicyGenerator generates and validates passwords for a policy. It is safe
+// for concurrent use.
+type PolicyGenerator struct {
+	gen    *StatefulGenerator
+	policy *Policy
+}
+
+// NewPolicyGenerator creates a PolicyGenerator for p, drawing randomness from
+// reader, or rand.Reader if reader is nil. The underlying StatefulGenerator
+// uses the policy's lower, upper, digit and symbol classes as its character
+// sets, so its class checks agree with the policy.
+func NewPolicyGenerator(p *Policy, reader io.Reader) (*PolicyGenerator, error) {
+	if err := p.Check(); err != nil {
+		return nil, err
+	}
+
+	input := &GeneratorInput{Reader: reader}
+	for _, c := range p.Classes {
+		chars := c.Chars
+		switch c.Name {
+		case ClassLower:
+			input.LowerLetters = chars
+		case ClassUpper:
+			input.UpperLetters = chars
+		case ClassDigit:
+			input.Digits = chars
+		case ClassSymbol:
+			input.Symbols = chars
+		}
+	}
+
+	gen, err := NewStatefulGenerator(input)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PolicyGenerator{gen: gen, policy: p}, nil
+}
+
+// Generator returns the underlying StatefulGenerator.
+func (g *PolicyGenerator) Generator() *StatefulGenerator {
+	return g.gen
+}
+
+// Policy returns the policy. It must not be modified.
+func (g *PolicyGenerator) Policy() *Policy {
+	return g.policy
+}
+
+// Generate generates a password satisfying the policy.
+func (g *PolicyGenerator) Generate() (string, error) {
+	return g.gen.GenerateForPolicy(g.policy)
+}
+
+// GenerateContext is the same as Generate, but stops once ctx is done.
+func (g *PolicyGenerator) GenerateContext(ctx context.Context) (string, error) {
+	return g.gen.GenerateForPolicyContext(ctx, g.policy)
+}
+
+// Validate checks password against the policy. PolicyGenerator implements
+// Validator.
+func (g *PolicyGenerator) Validate(password string) error {
+	return g.policy.Validate(password)
+}
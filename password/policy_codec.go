@@ -0,0 +1,743 @@
+package password
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// PolicyFormat is a file format for policies.
+type PolicyFormat int
+
+// Supported policy formats. YAML is decoded with gopkg.in/yaml.v3 and TOML
+// with github.com/BurntSushi/toml, so both are accepted in full, with one
+// exception: gopkg.in/yaml.v3 does not implement the "\/" escape of YAML 1.2
+// and reports it as a syntax error.
+const (
+	PolicyJSON PolicyFormat = iota
+	PolicyYAML
+	PolicyTOML
+)
+
+var policyFormatNames = map[PolicyFormat]string{
+	PolicyJSON: "json",
+	PolicyYAML: "yaml",
+	PolicyTOML: "toml",
+}
+
+var policyFormatExtensions = map[string]PolicyFormat{
+	".json": PolicyJSON,
+	".yaml": PolicyYAML,
+	".yml":  PolicyYAML,
+	".toml": PolicyTOML,
+}
+
+// ErrPolicyFormatUnknown is the error returned for an unsupported policy
+// format or file extension.
+var ErrPolicyFormatUnknown = errors.New("unknown policy format")
+
+// String returns the name of the format.
+func (f PolicyFormat) String() string {
+	if name, ok := policyFormatNames[f]; ok {
+		return name
+	}
+	return "PolicyFormat(" + strconv.Itoa(int(f)) + ")"
+}
+
+// ParsePolicy decodes a policy in the given format and checks it. Syntax
+// errors, unknown fields, mistyped values and failed checks are reported as
+// a *PolicyError carrying the line of the offending field.
+func ParsePolicy(data []byte, format PolicyFormat) (*Policy, error) {
+	var (
+		doc *policyValue
+		err error
+	)
+	switch format {
+	case PolicyJSON:
+		doc, err = parseJSONPolicy(data)
+	case PolicyYAML:
+		doc, err = parseYAMLPolicy(data)
+	case PolicyTOML:
+		doc, err = parseTOMLPolicy(data)
+	default:
+		return nil, ErrPolicyFormatUnknown
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	lines := make(map[string]int)
+	p, err := decodePolicy(doc, lines)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.Check(); err != nil {
+		var pe *PolicyError
+		if errors.As(err, &pe) {
+			pe.Line = lookupLine(lines, pe.Field)
+		}
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// LoadPolicyFile reads and parses a policy file. The format is chosen by the
+// file extension: .json, .yaml, .yml or .toml.
+func LoadPolicyFile(path string) (*Policy, error) {
+	format, ok := policyFormatExtensions[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrPolicyFormatUnknown, filepath.Ext(path))
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := ParsePolicy(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
+
+// LoadPolicyGenerator loads a policy file with LoadPolicyFile and creates a
+// PolicyGenerator for it.
+func LoadPolicyGenerator(path string, reader io.Reader) (*PolicyGenerator, error) {
+	p, err := LoadPolicyFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewPolicyGenerator(p, reader)
+}
+
+// Marshal encodes the policy in the given format. Zero-valued optional fields
+// are omitted. The output parses back into an equal policy.
+func (p *Policy) Marshal(format PolicyFormat) ([]byte, error) {
+	switch format {
+	case PolicyJSON:
+		b, err := json.MarshalIndent(p, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(b, '\n'), nil
+	case PolicyYAML:
+		return p.marshalText(": ", "- ", "  "), nil
+	case PolicyTOML:
+		return p.marshalText(" = ", "", ""), nil
+	default:
+		return nil, ErrPolicyFormatUnknown
+	}
+}
+
+// marshalText writes the policy as YAML or TOML, which differ only in the key
+// separator and in how classes are nested.
+func (p *Policy) marshalText(sep, item, indent string) []byte {
+	var b bytes.Buffer
+	toml := item == ""
+
+	writeInt := func(prefix, key string, v int) {
+		if v != 0 {
+			fmt.Fprintf(&b, "%s%s%s%d\n", prefix, key, sep, v)
+		}
+	}
+	writeString := func(prefix, key, v string) {
+		if v != "" {
+			fmt.Fprintf(&b, "%s%s%s%s\n", prefix, key, sep, quotePolicyString(v))
+		}
+	}
+
+	writeInt("", "length", p.Length)
+	writeInt("", "min_length", p.MinLength)
+	writeInt("", "max_length", p.MaxLength)
+	if p.NoRepeat {
+		fmt.Fprintf(&b, "no_repeat%strue\n", sep)
+	}
+	writeInt("", "max_consecutive", p.MaxConsecutive)
+	writeString("", "exclude", p.Exclude)
+
+	if len(p.Blocklist) > 0 {
+		quoted := make([]string, len(p.Blocklist))
+		for i, entry := range p.Blocklist {
+			quoted[i] = quotePolicyString(entry)
+		}
+		fmt.Fprintf(&b, "blocklist%s[%s]\n", sep, strings.Join(quoted, ", "))
+	}
+
+	if !toml {
+		b.WriteString("classes:\n")
+	}
+	for _, c := range p.Classes {
+		prefix := indent + indent
+		if toml {
+			b.WriteString("\n[[classes]]\n")
+		} else {
+			b.WriteString(indent + item)
+		}
+		fmt.Fprintf(&b, "name%s%s\n", sep, quotePolicyString(c.Name))
+		writeString(prefix, "chars", c.Chars)
+		writeInt(prefix, "min", c.Min)
+		writeInt(prefix, "max", c.Max)
+	}
+
+	return b.Bytes()
+}
+
+// quotePolicyString quotes s as a double-quoted string valid in JSON, YAML
+// and TOML.
+func quotePolicyString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, "\\u%04x", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// lookupLine returns the line of field, falling back to the enclosing field
+// for errors about elements that were not spelled out in the file.
+func lookupLine(lines map[string]int, field string) int {
+	for field != "" {
+		if line, ok := lines[field]; ok {
+			return line
+		}
+		i := strings.LastIndexAny(field, ".[")
+		if i < 0 {
+			break
+		}
+		field = field[:i]
+	}
+	return 0
+}
+
+// policyValueKind is the kind of a parsed value.
+type policyValueKind int
+
+const (
+	policyString policyValueKind = iota // quoted string
+	policyPlain                         // unquoted scalar: number, boolean or bare word
+	policyList
+	policyMap
+)
+
+// policyValue is a format-independent parse tree node.
+type policyValue struct {
+	line int
+	kind policyValueKind
+	text string
+	list []*policyValue
+	keys []string // map keys in file order
+	m    map[string]*policyValue
+}
+
+func newPolicyMap(line int) *policyValue {
+	return &policyValue{line: line, kind: policyMap, m: make(map[string]*policyValue)}
+}
+
+// set adds a map entry, rejecting duplicate keys.
+func (v *policyValue) set(key string, val *policyValue) error {
+	if _, ok := v.m[key]; ok {
+		return &PolicyError{Line: val.line, Field: key, Message: "duplicate key"}
+	}
+	v.keys = append(v.keys, key)
+	v.m[key] = val
+	return nil
+}
+
+func syntaxError(line int, format string, args ...interface{}) error {
+	return &PolicyError{Line: line, Message: "syntax error: " + fmt.Sprintf(format, args...)}
+}
+
+// decodePolicy converts a parse tree into a Policy, recording the line of
+// every field in lines.
+func decodePolicy(doc *policyValue, lines map[string]int) (*Policy, error) {
+	if doc.kind != policyMap {
+		return nil, &PolicyError{Line: doc.line, Message: "policy must be a mapping"}
+	}
+
+	p := &Policy{}
+	for _, key := range doc.keys {
+		v := doc.m[key]
+		lines[key] = v.line
+
+		var err error
+		switch key {
+		case "length":
+			p.Length, err = v.decodeInt(key)
+		case "min_length":
+			p.MinLength, err = v.decodeInt(key)
+		case "max_length":
+			p.MaxLength, err = v.decodeInt(key)
+		case "no_repeat":
+			p.NoRepeat, err = v.decodeBool(key)
+		case "max_consecutive":
+			p.MaxConsecutive, err = v.decodeInt(key)
+		case "exclude":
+			p.Exclude, err = v.decodeString(key)
+		case "blocklist":
+			p.Blocklist, err = v.decodeStrings(key, lines)
+		case "classes":
+			p.Classes, err = v.decodeClasses(key, lines)
+		default:
+			err = &PolicyError{Line: v.line, Field: key, Message: "unknown field"}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+func (v *policyValue) typeError(field, want string) error {
+	return &PolicyError{Line: v.line, Field: field, Message: "must be " + want}
+}
+
+func (v *policyValue) decodeInt(field string) (int, error) {
+	if v.kind != policyPlain {
+		return 0, v.typeError(field, "an integer")
+	}
+	n, err := strconv.Atoi(v.text)
+	if err != nil {
+		return 0, v.typeError(field, "an integer")
+	}
+	return n, nil
+}
+
+func (v *policyValue) decodeBool(field string) (bool, error) {
+	if v.kind == policyPlain {
+		switch v.text {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+	}
+	return false, v.typeError(field, "a boolean")
+}
+
+// decodeString accepts quoted strings and, for YAML, bare words.
+func (v *policyValue) decodeString(field string) (string, error) {
+	switch {
+	case v.kind == policyString:
+		return v.text, nil
+	case v.kind == policyPlain && v.text != "null" && v.text != "true" && v.text != "false":
+		return v.text, nil
+	}
+	return "", v.typeError(field, "a string")
+}
+
+func (v *policyValue) decodeStrings(field string, lines map[string]int) ([]string, error) {
+	if v.kind != policyList {
+		return nil, v.typeError(field, "a list of strings")
+	}
+
+	res := make([]string, len(v.list))
+	for i, item := range v.list {
+		name := fmt.Sprintf("%s[%d]", field, i)
+		lines[name] = item.line
+
+		s, err := item.decodeString(name)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = s
+	}
+	return res, nil
+}
+
+func (v *policyValue) decodeClasses(field string, lines map[string]int) ([]ClassRule, error) {
+	if v.kind != policyList {
+		return nil, v.typeError(field, "a list of classes")
+	}
+
+	res := make([]ClassRule, len(v.list))
+	for i, item := range v.list {
+		name := fmt.Sprintf("%s[%d]", field, i)
+		lines[name] = item.line
+		if item.kind != policyMap {
+			return nil, item.typeError(name, "a mapping")
+		}
+
+		for _, key := range item.keys {
+			kv := item.m[key]
+			sub := name + "." + key
+			lines[sub] = kv.line
+
+			var err error
+			switch key {
+			case "name":
+				res[i].Name, err = kv.decodeString(sub)
+			case "chars":
+				res[i].Chars, err = kv.decodeString(sub)
+			case "min":
+				res[i].Min, err = kv.decodeInt(sub)
+			case "max":
+				res[i].Max, err = kv.decodeInt(sub)
+			default:
+				err = &PolicyError{Line: kv.line, Field: sub, Message: "unknown field"}
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return res, nil
+}
+
+// parseJSONPolicy parses JSON into a parse tree, tracking the line of every
+// value through the decoder's input offset.
+func parseJSONPolicy(data []byte) (*policyValue, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	line := func() int {
+		return bytes.Count(data[:dec.InputOffset()], []byte("\n")) + 1
+	}
+	wrap := func(err error) error {
+		var se *json.SyntaxError
+		if errors.As(err, &se) {
+			return syntaxError(bytes.Count(data[:se.Offset], []byte("\n"))+1, "%s", se.Error())
+		}
+		if err == io.EOF {
+			return syntaxError(line(), "unexpected end of input")
+		}
+		return syntaxError(line(), "%s", err.Error())
+	}
+
+	var parse func(tok json.Token) (*policyValue, error)
+	parse = func(tok json.Token) (*policyValue, error) {
+		l := line()
+		switch t := tok.(type) {
+		case json.Delim:
+			var v *policyValue
+			if t == '[' {
+				v = &policyValue{line: l, kind: policyList}
+			} else {
+				v = newPolicyMap(l)
+			}
+
+			for dec.More() {
+				tok, err := dec.Token()
+				if err != nil {
+					return nil, wrap(err)
+				}
+
+				// Map entries take the line of their key.
+				key, keyLine := "", 0
+				if v.kind == policyMap {
+					key, keyLine = tok.(string), line()
+					if tok, err = dec.Token(); err != nil {
+						return nil, wrap(err)
+					}
+				}
+
+				item, err := parse(tok)
+				if err != nil {
+					return nil, err
+				}
+				if v.kind == policyList {
+					v.list = append(v.list, item)
+					continue
+				}
+				item.line = keyLine
+				if err := v.set(key, item); err != nil {
+					return nil, err
+				}
+			}
+
+			if _, err := dec.Token(); err != nil {
+				return nil, wrap(err)
+			}
+			return v, nil
+		case string:
+			return &policyValue{line: l, kind: policyString, text: t}, nil
+		case json.Number:
+			return &policyValue{line: l, kind: policyPlain, text: t.String()}, nil
+		case bool:
+			return &policyValue{line: l, kind: policyPlain, text: strconv.FormatBool(t)}, nil
+		default:
+			return &policyValue{line: l, kind: policyPlain, text: "null"}, nil
+		}
+	}
+
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, wrap(err)
+	}
+	doc, err := parse(tok)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, syntaxError(line(), "unexpected data after the policy")
+	}
+	return doc, nil
+}
+
+// parseYAMLPolicy parses YAML with gopkg.in/yaml.v3 into a parse tree,
+// taking the lines from the document nodes. Only a single document is
+// accepted.
+func parseYAMLPolicy(data []byte) (*policyValue, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+
+	var doc yaml.Node
+	if err := dec.Decode(&doc); err != nil && err != io.EOF {
+		return nil, yamlSyntaxError(err)
+	}
+	var extra yaml.Node
+	if err := dec.Decode(&extra); err != io.EOF {
+		if err != nil {
+			return nil, yamlSyntaxError(err)
+		}
+		return nil, syntaxError(extra.Line, "unexpected document after the policy")
+	}
+
+	if doc.Kind == 0 {
+		return newPolicyMap(1), nil
+	}
+	return convertYAMLNode(doc.Content[0])
+}
+
+// yamlSyntaxError converts an error of gopkg.in/yaml.v3, which only carries
+// the line in its message, into a *PolicyError.
+func yamlSyntaxError(err error) error {
+	msg := strings.TrimPrefix(err.Error(), "yaml: ")
+	line := 0
+	if n, _ := fmt.Sscanf(msg, "line %d: ", &line); n == 1 {
+		msg = msg[strings.Index(msg, ": ")+2:]
+	}
+	return syntaxError(line, "%s", msg)
+}
+
+// convertYAMLNode converts a YAML node into a parse tree. Map entries take the
+// line of their key.
+func convertYAMLNode(n *yaml.Node) (*policyValue, error) {
+	switch n.Kind {
+	case yaml.AliasNode:
+		return convertYAMLNode(n.Alias)
+	case yaml.ScalarNode:
+		if n.ShortTag() == "!!str" {
+			return &policyValue{line: n.Line, kind: policyString, text: n.Value}, nil
+		}
+		return &policyValue{line: n.Line, kind: policyPlain, text: n.Value}, nil
+	case yaml.SequenceNode:
+		v := &policyValue{line: n.Line, kind: policyList}
+		for _, c := range n.Content {
+			item, err := convertYAMLNode(c)
+			if err != nil {
+				return nil, err
+			}
+			v.list = append(v.list, item)
+		}
+		return v, nil
+	case yaml.MappingNode:
+		v := newPolicyMap(n.Line)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key := n.Content[i]
+			if key.Kind != yaml.ScalarNode {
+				return nil, syntaxError(key.Line, "mapping keys must be scalars")
+			}
+			item, err := convertYAMLNode(n.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			item.line = key.Line
+			if err := v.set(key.Value, item); err != nil {
+				return nil, err
+			}
+		}
+		return v, nil
+	}
+	return nil, syntaxError(n.Line, "unsupported node")
+}
+
+// parseTOMLPolicy parses TOML with github.com/BurntSushi/toml into a parse
+// tree. The decoder reports lines for syntax errors only, so the lines of
+// keys are located afterwards by tomlKeyLines. Keys are kept in the order of
+// the document.
+func parseTOMLPolicy(data []byte) (*policyValue, error) {
+	var doc map[string]interface{}
+	md, err := toml.Decode(string(data), &doc)
+	if err != nil {
+		var pe toml.ParseError
+		if errors.As(err, &pe) {
+			// Not every ParseError sets Message, so take it from Error.
+			prefix := fmt.Sprintf("toml: line %d", pe.Position.Line)
+			if pe.LastKey != "" {
+				prefix += fmt.Sprintf(" (last key %q)", pe.LastKey)
+			}
+			return nil, syntaxError(pe.Position.Line, "%s", strings.TrimPrefix(pe.Error(), prefix+": "))
+		}
+		return nil, syntaxError(0, "%s", err.Error())
+	}
+
+	order := make(map[string]int)
+	for i, key := range md.Keys() {
+		if _, ok := order[key[len(key)-1]]; !ok {
+			order[key[len(key)-1]] = i
+		}
+	}
+	return convertTOMLValue(doc, "", order, tomlKeyLines(data)), nil
+}
+
+// convertTOMLValue converts a decoded TOML value into a parse tree. field is
+// the name of the value, as used by lookupLine.
+func convertTOMLValue(val interface{}, field string, order, lines map[string]int) *policyValue {
+	line := lookupLine(lines, field)
+	switch val := val.(type) {
+	case string:
+		return &policyValue{line: line, kind: policyString, text: val}
+	case map[string]interface{}:
+		v := newPolicyMap(line)
+		keys := make([]string, 0, len(val))
+		for key := range val {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if order[keys[i]] != order[keys[j]] {
+				return order[keys[i]] < order[keys[j]]
+			}
+			return keys[i] < keys[j]
+		})
+		for _, key := range keys {
+			sub := key
+			if field != "" {
+				sub = field + "." + key
+			}
+			v.keys = append(v.keys, key)
+			v.m[key] = convertTOMLValue(val[key], sub, order, lines)
+		}
+		return v
+	case []map[string]interface{}:
+		v := &policyValue{line: line, kind: policyList}
+		for i, item := range val {
+			v.list = append(v.list, convertTOMLValue(item, fmt.Sprintf("%s[%d]", field, i), order, lines))
+		}
+		return v
+	case []interface{}:
+		v := &policyValue{line: line, kind: policyList}
+		for i, item := range val {
+			v.list = append(v.list, convertTOMLValue(item, fmt.Sprintf("%s[%d]", field, i), order, lines))
+		}
+		return v
+	default:
+		return &policyValue{line: line, kind: policyPlain, text: fmt.Sprint(val)}
+	}
+}
+
+var (
+	tomlArrayTable = regexp.MustCompile(`^\[\[\s*([^\]]*?)\s*\]\]`)
+	tomlTable      = regexp.MustCompile(`^\[\s*([^\]]*?)\s*\]`)
+	tomlKey        = regexp.MustCompile(`^("[^"]*"|'[^']*'|[A-Za-z0-9_-]+)\s*=`)
+)
+
+// tomlKeyLines returns the lines of the keys and tables of a TOML document
+// that was decoded successfully, named as for lookupLine. Only keys written
+// on a line of their own are found, not those inside inline tables or behind
+// dotted keys, and lookupLine falls back to the enclosing field for them.
+// Lines inside multi-line strings are skipped.
+func tomlKeyLines(data []byte) map[string]int {
+	lines := make(map[string]int)
+	record := func(field string, line int) {
+		if _, ok := lines[field]; !ok {
+			lines[field] = line
+		}
+	}
+
+	prefix := ""
+	tables := make(map[string]int)
+	open := ""
+	for i, text := range strings.Split(string(data), "\n") {
+		inString := open != ""
+		open = tomlOpenString(text, open)
+		if inString {
+			continue
+		}
+
+		text = strings.TrimSpace(text)
+		if m := tomlArrayTable.FindStringSubmatch(text); m != nil {
+			name := strings.Trim(m[1], `"'`)
+			record(name, i+1)
+			prefix = fmt.Sprintf("%s[%d]", name, tables[name])
+			tables[name]++
+			record(prefix, i+1)
+			prefix += "."
+			continue
+		}
+		if m := tomlTable.FindStringSubmatch(text); m != nil {
+			name := strings.Trim(m[1], `"'`)
+			record(name, i+1)
+			prefix = name + "."
+			continue
+		}
+		if m := tomlKey.FindStringSubmatch(text); m != nil {
+			record(prefix+strings.Trim(m[1], `"'`), i+1)
+		}
+	}
+	return lines
+}
+
+// tomlOpenString returns the delimiter of the multi-line string that is still
+// open at the end of line, three double or three single quotes, or the empty
+// string. open is the delimiter of the string open at the start of the line.
+func tomlOpenString(line, open string) string {
+	for i := 0; i < len(line); i++ {
+		if open != "" {
+			if open == `"""` && line[i] == '\\' {
+				i++
+				continue
+			}
+			if strings.HasPrefix(line[i:], open) {
+				// Up to two quotes right before the delimiter belong to
+				// the string.
+				for n := 0; n < 2 && i+3 < len(line) && line[i+3] == open[0]; n++ {
+					i++
+				}
+				i += 2
+				open = ""
+			}
+			continue
+		}
+
+		switch {
+		case line[i] == '#':
+			return ""
+		case strings.HasPrefix(line[i:], `"""`), strings.HasPrefix(line[i:], "'''"):
+			open = line[i : i+3]
+			i += 2
+		case line[i] == '"':
+			for i++; i < len(line) && line[i] != '"'; i++ {
+				if line[i] == '\\' {
+					i++
+				}
+			}
+		case line[i] == '\'':
+			for i++; i < len(line) && line[i] != '\''; i++ {
+			}
+		}
+	}
+	return open
+}
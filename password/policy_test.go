@@ -0,0 +1,741 @@
+package password
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// testPolicy is the policy spelled out by the policy files below.
+var testPolicy = &Policy{
+	Length:         20,
+	MinLength:      12,
+	MaxLength:      64,
+	NoRepeat:       true,
+	MaxConsecutive: 1,
+	Exclude:        "0O1lI",
+	Blocklist:      []string{"password", "qwerty"},
+	Classes: []ClassRule{
+		{Name: "lower", Min: 1},
+		{Name: "upper", Min: 1},
+		{Name: "digit", Min: 2, Max: 6},
+		{Name: "symbol", Chars: `!#$%&"'`, Min: 1},
+	},
+}
+
+const testPolicyJSON = `{
+  "length": 20,
+  "min_length": 12,
+  "max_length": 64,
+  "no_repeat": true,
+  "max_consecutive": 1,
+  "exclude": "0O1lI",
+  "blocklist": ["password", "qwerty"],
+  "classes": [
+    {"name": "lower", "min": 1},
+    {"name": "upper", "min": 1},
+    {"name": "digit", "min": 2, "max": 6},
+    {"name": "symbol", "chars": "!#$%&\"'", "min": 1}
+  ]
+}
+`
+
+const testPolicyYAML = `# Service accounts
+---
+length: 20
+min_length: 12
+max_length: 64
+no_repeat: true
+max_consecutive: 1
+exclude: 0O1lI # easily confused
+blocklist:
+  - password
+  - 'qwerty'
+classes:
+- name: lower
+  min: 1
+- name: upper
+  min: 1
+- name: digit
+  min: 2
+  max: 6
+-
+  name: symbol
+  chars: '!#$%&"'''
+  min: 1
+`
+
+const testPolicyTOML = `# Service accounts
+length = 20
+min_length = 12
+max_length = 64
+no_repeat = true
+max_consecutive = 1
+exclude = "0O1lI" # easily confused
+blocklist = [
+  "password",
+  'qwerty',
+]
+
+[[classes]]
+name = "lower"
+min = 1
+
+[[classes]]
+name = "upper"
+min = 1
+
+[[classes]]
+name = "digit"
+min = 2
+max = 6
+
+[[classes]]
+name = "symbol"
+chars = "!#$%&\"'"
+min = 1
+`
+
+func TestParsePolicy(t *testing.T) {
+	t.Parallel()
+
+	var TestCases = []struct {
+		Name   string
+		Format PolicyFormat
+		Data   string
+	}{
+		{Name: "json", Format: PolicyJSON, Data: testPolicyJSON},
+		{Name: "yaml", Format: PolicyYAML, Data: testPolicyYAML},
+		{Name: "toml", Format: PolicyTOML, Data: testPolicyTOML},
+	}
+
+	for _, tc := range TestCases {
+		tc := tc
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			p, err := ParsePolicy([]byte(tc.Data), tc.Format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(p, testPolicy) {
+				t.Errorf("expected %+v to be %+v", p, testPolicy)
+			}
+		})
+	}
+}
+
+func TestParsePolicy_syntax(t *testing.T) {
+	t.Parallel()
+
+	expected := &Policy{
+		Length:    12,
+		Exclude:   "/0O",
+		Blocklist: []string{"it's"},
+		Classes:   []ClassRule{{Name: "lower", Min: 1}, {Name: "digit", Max: 4}},
+	}
+
+	var TestCases = []struct {
+		Name   string
+		Format PolicyFormat
+		Data   string
+	}{
+		{
+			Name:   "yaml flow mappings",
+			Format: PolicyYAML,
+			Data:   "length: 12\nexclude: \"\\x2f0O\"\nblocklist: [\"it's\"]\nclasses:\n  - {name: lower, min: 1}\n  - {name: digit, max: 4}\n",
+		},
+		{
+			Name:   "yaml block scalars",
+			Format: PolicyYAML,
+			Data:   "length: 12\nexclude: |-\n  /0O\nblocklist:\n  - >-\n    it's\nclasses: [{name: lower, min: 1}, {name: digit, max: 4}]\n",
+		},
+		{
+			Name:   "toml inline tables",
+			Format: PolicyTOML,
+			Data:   "length = 12\nexclude = '/0O'\nblocklist = [\"it's\"]\nclasses = [{name = \"lower\", min = 1}, {name = \"digit\", max = 4}]\n",
+		},
+		{
+			Name:   "toml spaced array tables",
+			Format: PolicyTOML,
+			Data:   "length = 12\nexclude = \"\\u002f0O\"\nblocklist = [\"\"\"it's\"\"\"]\n\n[[ classes ]]\nname = \"lower\"\nmin = 1\n\n[[ \"classes\" ]]\nname = \"digit\"\nmax = 4\n",
+		},
+	}
+
+	for _, tc := range TestCases {
+		tc := tc
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			p, err := ParsePolicy([]byte(tc.Data), tc.Format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(p, expected) {
+				t.Errorf("expected %+v to be %+v", p, expected)
+			}
+		})
+	}
+}
+
+func TestParsePolicy_errors(t *testing.T) {
+	t.Parallel()
+
+	var TestCases = []struct {
+		Name     string
+		Format   PolicyFormat
+		Data     string
+		Expected string
+	}{
+		{
+			Name:     "json syntax",
+			Format:   PolicyJSON,
+			Data:     "{\n  \"length\": 20,\n  \"classes\": [}\n",
+			Expected: "line 3: syntax error",
+		},
+		{
+			Name:     "json unknown field",
+			Format:   PolicyJSON,
+			Data:     "{\n  \"length\": 20,\n  \"lenght\": 20\n}",
+			Expected: "line 3: lenght: unknown field",
+		},
+		{
+			Name:     "json type",
+			Format:   PolicyJSON,
+			Data:     "{\n  \"classes\": [\n    {\"name\": \"lower\"},\n    {\"name\": \"digit\",\n     \"min\": \"2\"}\n  ]\n}",
+			Expected: "line 5: classes[1].min: must be an integer",
+		},
+		{
+			Name:     "json check",
+			Format:   PolicyJSON,
+			Data:     "{\n  \"min_length\": 20,\n  \"max_length\": 10,\n  \"classes\": [{\"name\": \"lower\"}]\n}",
+			Expected: "line 3: max_length: must not be less than min_length 20",
+		},
+		{
+			Name:     "json duplicate",
+			Format:   PolicyJSON,
+			Data:     "{\n  \"length\": 20,\n  \"length\": 21\n}",
+			Expected: "line 3: length: duplicate key",
+		},
+		{
+			Name:     "yaml indentation",
+			Format:   PolicyYAML,
+			Data:     "length: 20\n  min_length: 12\n",
+			Expected: "line 2: syntax error: mapping values are not allowed in this context",
+		},
+		{
+			Name:     "yaml check",
+			Format:   PolicyYAML,
+			Data:     "classes:\n  - name: lower\n  - name: emoji\n    min: 1\n",
+			Expected: "line 3: classes[1].chars: must not be empty unless the class is one of lower, upper, digit or symbol",
+		},
+		{
+			Name:     "yaml bool",
+			Format:   PolicyYAML,
+			Data:     "classes:\n  - name: lower\nno_repeat: yes\n",
+			Expected: "line 3: no_repeat: must be a boolean",
+		},
+		{
+			Name:     "yaml unterminated",
+			Format:   PolicyYAML,
+			Data:     "exclude: \"0O\n",
+			Expected: "line 2: syntax error: found unexpected end of stream",
+		},
+		{
+			Name:     "toml table",
+			Format:   PolicyTOML,
+			Data:     "length = 20\n\n[limits]\nmin = 1\n",
+			Expected: "line 3: limits: unknown field",
+		},
+		{
+			Name:     "toml check",
+			Format:   PolicyTOML,
+			Data:     "length = 4\n\n[[classes]]\nname = \"lower\"\nmin = 3\n\n[[classes]]\nname = \"digit\"\nmin = 2\n",
+			Expected: "line 3: classes: minimum counts add up to 5, more than the password length 4",
+		},
+		{
+			Name:     "toml field",
+			Format:   PolicyTOML,
+			Data:     "[[classes]]\nname = \"lower\"\nmax = -1\n",
+			Expected: "line 3: classes[0].max: must not be negative",
+		},
+		{
+			Name:     "toml unknown class field",
+			Format:   PolicyTOML,
+			Data:     "length = 8\n\n[[classes]]\nname = \"lower\"\n\n[[classes]]\nname = \"digit\"\nnmae = \"x\"\n",
+			Expected: "line 8: classes[1].nmae: unknown field",
+		},
+		{
+			Name:     "toml after multi-line string",
+			Format:   PolicyTOML,
+			Data:     "[[classes]]\nname = \"symbol\"\nchars = \"\"\"\nmax = 1\n[limits]\n\\\"\"\"\"\"\nmax = -1\n",
+			Expected: "line 7: classes[0].max: must not be negative",
+		},
+		{
+			Name:     "toml after multi-line literal string",
+			Format:   PolicyTOML,
+			Data:     "[[classes]]\nname = \"symbol\"\nchars = '''# not a comment\nmax = 1 \"\n'''\nmax = -1\n",
+			Expected: "line 6: classes[0].max: must not be negative",
+		},
+		{
+			Name:     "toml literal string quote",
+			Format:   PolicyTOML,
+			Data:     "length = 8\nexclude = 'it''s'\n",
+			Expected: "line 2: syntax error",
+		},
+		{
+			Name:     "yaml documents",
+			Format:   PolicyYAML,
+			Data:     "classes:\n  - name: lower\n---\nlength: 8\n",
+			Expected: "line 3: syntax error: unexpected document after the policy",
+		},
+		{
+			Name:     "yaml solidus escape",
+			Format:   PolicyYAML,
+			Data:     "length: 8\nexclude: \"\\/\"\n",
+			Expected: "line 2: syntax error: found unknown escape character",
+		},
+		{
+			Name:     "toml syntax",
+			Format:   PolicyTOML,
+			Data:     "length 20\n",
+			Expected: "line 1: syntax error: expected",
+		},
+	}
+
+	for _, tc := range TestCases {
+		tc := tc
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := ParsePolicy([]byte(tc.Data), tc.Format)
+			var pe *PolicyError
+			if !errors.As(err, &pe) {
+				t.Fatalf("expected %v to be a *PolicyError", err)
+			}
+			if !strings.HasPrefix(err.Error(), tc.Expected) {
+				t.Errorf("expected %q to start with %q", err, tc.Expected)
+			}
+		})
+	}
+}
+
+func TestParsePolicy_unknownFormat(t *testing.T) {
+	t.Parallel()
+
+	if _, err := ParsePolicy([]byte("{}"), PolicyFormat(42)); err != ErrPolicyFormatUnknown {
+		t.Errorf("expected %q to be %q", err, ErrPolicyFormatUnknown)
+	}
+}
+
+func TestPolicy_Marshal(t *testing.T) {
+	t.Parallel()
+
+	for _, format := range []PolicyFormat{PolicyJSON, PolicyYAML, PolicyTOML} {
+		format := format
+
+		t.Run(format.String(), func(t *testing.T) {
+			t.Parallel()
+
+			p := *testPolicy
+			p.Blocklist = append(p.Blocklist, "tab\there", `back\slash`)
+			data, err := p.Marshal(format)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			res, err := ParsePolicy(data, format)
+			if err != nil {
+				t.Fatalf("%v in:\n%s", err, data)
+			}
+			if !reflect.DeepEqual(res, &p) {
+				t.Errorf("expected %+v to be %+v in:\n%s", res, &p, data)
+			}
+		})
+	}
+}
+
+func TestPolicy_Check(t *testing.T) {
+	t.Parallel()
+
+	var TestCases = []struct {
+		Name   string
+		Policy Policy
+		Field  string
+	}{
+		{
+			Name:   "no classes",
+			Policy: Policy{Length: 10},
+			Field:  "classes",
+		},
+		{
+			Name:   "all excluded",
+			Policy: Policy{Classes: []ClassRule{{Name: "x", Chars: "ab", Min: 1}}, Exclude: "ba"},
+			Field:  "classes[0].chars",
+		},
+		{
+			Name:   "duplicate class",
+			Policy: Policy{Classes: []ClassRule{{Name: "lower"}, {Name: "lower"}}},
+			Field:  "classes[1].name",
+		},
+		{
+			Name:   "max below min",
+			Policy: Policy{Classes: []ClassRule{{Name: "lower", Min: 3, Max: 2}}},
+			Field:  "classes[0].max",
+		},
+		{
+			Name:   "maximums too small",
+			Policy: Policy{Length: 8, Classes: []ClassRule{{Name: "lower", Max: 4}, {Name: "digit", Max: 3}}},
+			Field:  "classes",
+		},
+		{
+			Name:   "no repeat exceeds class",
+			Policy: Policy{Length: 8, NoRepeat: true, Classes: []ClassRule{{Name: "lower"}, {Name: "digit", Min: 11}}},
+			Field:  "classes[1].min",
+		},
+		{
+			Name:   "no repeat exceeds length",
+			Policy: Policy{Length: 11, NoRepeat: true, Classes: []ClassRule{{Name: "digit"}}},
+			Field:  "no_repeat",
+		},
+		{
+			Name:   "empty blocklist entry",
+			Policy: Policy{Classes: []ClassRule{{Name: "lower"}}, Blocklist: []string{"a", ""}},
+			Field:  "blocklist[1]",
+		},
+		{
+			Name:   "every character blocked",
+			Policy: Policy{Classes: []ClassRule{{Name: "a", Chars: "a"}}, Blocklist: []string{"A"}},
+		},
+		{
+			Name:   "single character with max consecutive",
+			Policy: Policy{Classes: []ClassRule{{Name: "a", Chars: "a"}}, MaxConsecutive: 1},
+		},
+		{
+			Name:   "blocklist and max consecutive",
+			Policy: Policy{Length: 4, Classes: []ClassRule{{Name: "ab", Chars: "ab"}}, MaxConsecutive: 2, Blocklist: []string{"ab", "ba"}},
+		},
+		{
+			Name: "overlapping maximums",
+			Policy: Policy{Length: 4, Classes: []ClassRule{
+				{Name: "abc", Chars: "abc", Max: 2},
+				{Name: "bcd", Chars: "bcd", Max: 2},
+				{Name: "bc", Chars: "bc", Min: 1},
+			}},
+		},
+		{
+			Name: "minimum forces runs",
+			Policy: Policy{Length: 12, MaxConsecutive: 2, Classes: []ClassRule{
+				{Name: "a", Chars: "a", Min: 10},
+				{Name: "b", Chars: "b", Max: 1},
+			}},
+		},
+	}
+
+	for _, tc := range TestCases {
+		tc := tc
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.Policy.Check()
+			var pe *PolicyError
+			if !errors.As(err, &pe) {
+				t.Fatalf("expected %v to be a *PolicyError", err)
+			}
+			if pe.Field != tc.Field {
+				t.Errorf("expected %q to be %q", pe.Field, tc.Field)
+			}
+			if unsatisfiable := errors.Is(err, ErrPolicyUnsatisfiable); unsatisfiable != (tc.Field == "") {
+				t.Errorf("expected errors.Is(%q, ErrPolicyUnsatisfiable) to be %t", err, !unsatisfiable)
+			}
+		})
+	}
+}
+
+func TestPolicy_Check_satisfiable(t *testing.T) {
+	t.Parallel()
+
+	var TestCases = []struct {
+		Name   string
+		Policy Policy
+	}{
+		{Name: "file", Policy: *testPolicy},
+		{
+			Name:   "alternating",
+			Policy: Policy{Classes: []ClassRule{{Name: "ab", Chars: "ab"}}, MaxConsecutive: 1},
+		},
+		{
+			Name:   "blocked pairs",
+			Policy: Policy{Length: 4, Classes: []ClassRule{{Name: "ab", Chars: "ab"}}, MaxConsecutive: 4, Blocklist: []string{"ab", "ba", "aaaa"}},
+		},
+		{
+			Name: "overlapping maximums",
+			Policy: Policy{Length: 4, Classes: []ClassRule{
+				{Name: "abc", Chars: "abc", Max: 2},
+				{Name: "bcd", Chars: "bcd", Max: 2},
+			}},
+		},
+		{
+			Name:   "every character once",
+			Policy: Policy{Length: 10, NoRepeat: true, Classes: []ClassRule{{Name: "digit", Min: 10}}, Blocklist: []string{"123"}},
+		},
+		{
+			Name:   "long blocklist",
+			Policy: Policy{Length: 64, Classes: []ClassRule{{Name: "lower", Min: 60}, {Name: "digit"}}, Blocklist: []string{"password", "qwerty", "letmein"}},
+		},
+	}
+
+	for _, tc := range TestCases {
+		tc := tc
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			if err := tc.Policy.Check(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestPolicy_Validate(t *testing.T) {
+	t.Parallel()
+
+	var TestCases = []struct {
+		Name     string
+		Password string
+		Rule     string
+	}{
+		{Name: "valid", Password: "ab3#Cd4efgh"},
+		{Name: "too short", Password: "ab3#Cd4", Rule: "min_length"},
+		{Name: "too long", Password: strings.Repeat("aB3#", 10), Rule: "max_length"},
+		{Name: "excluded", Password: "ab3#Cd4efgO", Rule: "exclude"},
+		{Name: "not allowed", Password: "ab3#Cd4efg;", Rule: "classes"},
+		{Name: "consecutive", Password: "ab3#Cd4eefg", Rule: "max_consecutive"},
+		{Name: "missing upper", Password: "ab3#cd4efgh", Rule: "classes[1].min"},
+		{Name: "missing digits", Password: "ab3#Cdxefgh", Rule: "classes[2].min"},
+		{Name: "too many digits", Password: "a23456789#C", Rule: "classes[2].max"},
+		{Name: "blocked", Password: "ab3#Cd4QwErTy", Rule: "blocklist[1]"},
+	}
+
+	p := &Policy{
+		MinLength:      11,
+		MaxLength:      32,
+		MaxConsecutive: 1,
+		Exclude:        "O",
+		Blocklist:      []string{"password", "qwerty"},
+		Classes: []ClassRule{
+			{Name: "lower", Min: 1},
+			{Name: "upper", Min: 1},
+			{Name: "digit", Min: 2, Max: 6},
+			{Name: "symbol", Chars: "#!"},
+		},
+	}
+
+	for _, tc := range TestCases {
+		tc := tc
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			err := p.Validate(tc.Password)
+			if tc.Rule == "" {
+				if err != nil {
+					t.Errorf("expected %q to be valid: %v", tc.Password, err)
+				}
+				return
+			}
+
+			var ve *ValidationError
+			if !errors.As(err, &ve) {
+				t.Fatalf("expected %v to be a *ValidationError", err)
+			}
+			if ve.Rule != tc.Rule {
+				t.Errorf("expected %q to be %q", ve.Rule, tc.Rule)
+			}
+		})
+	}
+}
+
+func TestPolicyGenerator_Generate(t *testing.T) {
+	t.Parallel()
+
+	var TestCases = []struct {
+		Name   string
+		Policy *Policy
+	}{
+		{Name: "file", Policy: testPolicy},
+		{
+			Name: "class maximums",
+			Policy: &Policy{
+				Length: 12,
+				Classes: []ClassRule{
+					{Name: "lower", Max: 3},
+					{Name: "upper", Max: 3},
+					{Name: "digit", Max: 3},
+					{Name: "symbol", Max: 3},
+				},
+			},
+		},
+		{
+			Name: "overlapping classes",
+			Policy: &Policy{
+				Length: 6,
+				Classes: []ClassRule{
+					{Name: "hex", Chars: "0123456789abcdef", Min: 2},
+					{Name: "digit", Max: 2},
+				},
+			},
+		},
+		{
+			Name: "unicode",
+			Policy: &Policy{
+				MinLength: 20,
+				NoRepeat:  true,
+				Classes: []ClassRule{
+					{Name: "greek", Chars: "αβγδεζηθικλμνξοπρστυφχψω", Min: 4},
+					{Name: "digit", Min: 4},
+				},
+			},
+		},
+	}
+
+	for _, tc := range TestCases {
+		tc := tc
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			gen, err := NewPolicyGenerator(tc.Policy, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i := 0; i < 200; i++ {
+				res, err := gen.Generate()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if n := utf8.RuneCountInString(res); n != tc.Policy.GenerateLength() {
+					t.Errorf("%q has length %d, want %d", res, n, tc.Policy.GenerateLength())
+				}
+				if err := gen.Validate(res); err != nil {
+					t.Errorf("%q: %v", res, err)
+				}
+			}
+		})
+	}
+}
+
+func TestPolicyGenerator_GenerateContext(t *testing.T) {
+	t.Parallel()
+
+	gen, err := NewPolicyGenerator(testPolicy, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := gen.GenerateContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %q to be %q", err, context.Canceled)
+	}
+}
+
+func TestStatefulGenerator_GenerateForPolicy_unsatisfiable(t *testing.T) {
+	t.Parallel()
+
+	var TestCases = []struct {
+		Name   string
+		Policy *Policy
+	}{
+		{
+			// Unchecked, so the first rejected candidate uncovers it.
+			Name:   "unchecked",
+			Policy: &Policy{Length: 4, Classes: []ClassRule{{Name: "a", Chars: "a"}}, Blocklist: []string{"aa"}},
+		},
+		{
+			// Satisfiable, but the reader only ever draws "aaaa".
+			Name:   "attempts exhausted",
+			Policy: &Policy{Length: 4, Classes: []ClassRule{{Name: "ab", Chars: "ab"}}, MaxConsecutive: 1},
+		},
+	}
+
+	gen, err := NewStatefulGenerator(&GeneratorInput{Reader: zeroReader{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range TestCases {
+		tc := tc
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := gen.GenerateForPolicy(tc.Policy); !errors.Is(err, ErrPolicyUnsatisfiable) {
+				t.Errorf("expected %q to be %q", err, ErrPolicyUnsatisfiable)
+			}
+		})
+	}
+}
+
+func TestNewPolicyGenerator_classes(t *testing.T) {
+	t.Parallel()
+
+	gen, err := NewPolicyGenerator(testPolicy, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !gen.Generator().ContainsSymbol(`"`) {
+		t.Errorf("expected the generator to use the policy's symbols")
+	}
+	if gen.Generator().ContainsSymbol("@") {
+		t.Errorf("expected the generator not to use the default symbols")
+	}
+}
+
+func TestLoadPolicyGenerator(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "policy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"policy.json": testPolicyJSON,
+		"policy.yml":  testPolicyYAML,
+		"policy.TOML": testPolicyTOML,
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		gen, err := LoadPolicyGenerator(path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(gen.Policy(), testPolicy) {
+			t.Errorf("%s: expected %+v to be %+v", name, gen.Policy(), testPolicy)
+		}
+	}
+
+	if _, err := LoadPolicyFile(filepath.Join(dir, "policy.ini")); !errors.Is(err, ErrPolicyFormatUnknown) {
+		t.Errorf("expected %q to be %q", err, ErrPolicyFormatUnknown)
+	}
+}
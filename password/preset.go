@@ -0,0 +1,240 @@
+package password
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var (
+	// ErrPresetUnknown is the error returned when looking up a preset that
+	// was not registered.
+	ErrPresetUnknown = errors.New("unknown preset")
+
+	// ErrPresetExists is the error returned when registering a preset under a
+	// name that is already taken.
+	ErrPresetExists = errors.New("preset already registered")
+
+	// ErrPresetInvalid is the error returned when registering a preset whose
+	// parameters can never produce a password.
+	ErrPresetInvalid = errors.New("invalid preset")
+)
+
+// Preset is a named set of generator parameters and policy requirements
+// suitable for a particular target system.
+type Preset struct {
+	Name        string
+	Description string
+
+	// Input configures the character sets. Its Reader is used as given, so it
+	// is usually left nil.
+	Input GeneratorInput
+
+	Length       int
+	NumDigits    int
+	NumSymbols   int
+	IncludeUpper bool
+	AllowRepeat  bool
+
+	NeedsLower  bool
+	NeedsUpper  bool
+	NeedsDigit  bool
+	NeedsSymbol bool
+
+	// Validator, if set, checks every password against the target system's
+	// own rules, as a ValidatingGenerator does.
+	Validator Validator
+}
+
+// NewGenerator creates a StatefulGenerator configured for the preset.
+func (p *Preset) NewGenerator() (*StatefulGenerator, error) {
+	input := p.Input
+	return NewStatefulGenerator(&input)
+}
+
+// Generate generates a password satisfying the preset.
+func (p *Preset) Generate() (string, error) {
+	return p.GenerateContext(context.Background())
+}
+
+// GenerateContext is the same as Generate, but stops once ctx is done.
+func (p *Preset) GenerateContext(ctx context.Context) (string, error) {
+	gen, err := p.NewGenerator()
+	if err != nil {
+		return "", err
+	}
+
+	var g Generator = gen
+	if p.Validator != nil {
+		g = NewValidatingGenerator(gen, p.Validator)
+	}
+	return g.GenerateWithPolicyContext(ctx, p.Length, p.NumDigits, p.NumSymbols, p.IncludeUpper, p.AllowRepeat,
+		p.NeedsLower, p.NeedsUpper, p.NeedsDigit, p.NeedsSymbol)
+}
+
+// validate checks that the preset's requirements can be met.
+func (p *Preset) validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("%w: name is empty", ErrPresetInvalid)
+	}
+
+	if err := checkCounts(p.Length, p.NumDigits, p.NumSymbols); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrPresetInvalid, p.Name, err)
+	}
+	if p.NumDigits > p.Length || p.NumSymbols > p.Length-p.NumDigits {
+		return fmt.Errorf("%w: %s: %v", ErrPresetInvalid, p.Name, ErrExceedsTotalLength)
+	}
+
+	gen, err := p.NewGenerator()
+	if err != nil {
+		return err
+	}
+
+	if !gen.canMeetPolicy(p.Length, p.NumDigits, p.NumSymbols, p.IncludeUpper, p.NeedsLower, p.NeedsUpper, p.NeedsDigit, p.NeedsSymbol) {
+		return fmt.Errorf("%w: %s: %v", ErrPresetInvalid, p.Name, ErrPolicyUnsatisfiable)
+	}
+	return nil
+}
+
+var presets = struct {
+	sync.RWMutex
+	m map[string]Preset
+}{
+	m: make(map[string]Preset),
+}
+
+func init() {
+	for _, p := range []Preset{
+		{
+			Name:         "aws-iam",
+			Description:  "AWS IAM user password, using the symbols IAM accepts",
+			Input:        GeneratorInput{Symbols: "!@#$%^&*()_+-=[]{}|'"},
+			Length:       20,
+			NumDigits:    3,
+			NumSymbols:   3,
+			IncludeUpper: true,
+			AllowRepeat:  true,
+			NeedsLower:   true,
+			NeedsUpper:   true,
+			NeedsDigit:   true,
+			NeedsSymbol:  true,
+		},
+		{
+			Name:         "active-directory",
+			Description:  "Active Directory password meeting the complexity requirements",
+			Input:        GeneratorInput{Symbols: ADSymbols},
+			Validator:    &ADComplexityPolicy{},
+			Length:       DefaultPolicyLength,
+			NumDigits:    3,
+			NumSymbols:   3,
+			IncludeUpper: true,
+			AllowRepeat:  true,
+			NeedsLower:   true,
+			NeedsUpper:   true,
+			NeedsDigit:   true,
+			NeedsSymbol:  true,
+		},
+		{
+			Name:         "postgresql",
+			Description:  "PostgreSQL role password, safe in connection strings and URIs",
+			Input:        GeneratorInput{SymbolProfile: SymbolProfileConnectionStringSafe},
+			Length:       32,
+			NumDigits:    4,
+			NumSymbols:   4,
+			IncludeUpper: true,
+			AllowRepeat:  true,
+			NeedsLower:   true,
+			NeedsUpper:   true,
+			NeedsDigit:   true,
+			NeedsSymbol:  true,
+		},
+		{
+			Name:         "mysql",
+			Description:  "MySQL account password, safe in option files and connection strings",
+			Input:        GeneratorInput{SymbolProfile: SymbolProfileConnectionStringSafe},
+			Length:       32,
+			NumDigits:    4,
+			NumSymbols:   4,
+			IncludeUpper: true,
+			AllowRepeat:  true,
+			NeedsLower:   true,
+			NeedsUpper:   true,
+			NeedsDigit:   true,
+			NeedsSymbol:  true,
+		},
+		{
+			Name:         "wifi-wpa2",
+			Description:  "Wi-Fi WPA2 pre-shared key (8-63 printable ASCII), easy to type on devices",
+			Length:       24,
+			NumDigits:    4,
+			IncludeUpper: true,
+			AllowRepeat:  true,
+			NeedsLower:   true,
+			NeedsUpper:   true,
+			NeedsDigit:   true,
+		},
+	} {
+		MustRegisterPreset(p)
+	}
+}
+
+// RegisterPreset adds a preset to the registry. It is meant to be called from
+// init functions and returns ErrPresetExists if the name is taken or
+// ErrPresetInvalid if the preset can never produce a password. This function
+// is safe for concurrent use.
+func RegisterPreset(p Preset) error {
+	if err := p.validate(); err != nil {
+		return err
+	}
+
+	presets.Lock()
+	defer presets.Unlock()
+
+	if _, ok := presets.m[p.Name]; ok {
+		return fmt.Errorf("%w: %s", ErrPresetExists, p.Name)
+	}
+	presets.m[p.Name] = p
+	return nil
+}
+
+// MustRegisterPreset is the same as RegisterPreset, but panics on error.
+func MustRegisterPreset(p Preset) {
+	if err := RegisterPreset(p); err != nil {
+		panic(err)
+	}
+}
+
+// LookupPreset returns the preset registered under name.
+func LookupPreset(name string) (Preset, bool) {
+	presets.RLock()
+	defer presets.RUnlock()
+
+	p, ok := presets.m[name]
+	return p, ok
+}
+
+// PresetNames returns the names of all registered presets in sorted order.
+func PresetNames() []string {
+	presets.RLock()
+	defer presets.RUnlock()
+
+	names := make([]string, 0, len(presets.m))
+	for name := range presets.m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GenerateFor generates a password with the preset registered under name,
+// such as "aws-iam".
+func GenerateFor(name string) (string, error) {
+	p, ok := LookupPreset(name)
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrPresetUnknown, name)
+	}
+
+	return p.Generate()
+}
@@ -0,0 +1,163 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestGenerateFor(t *testing.T) {
+	t.Parallel()
+
+	for _, name := range []string{"aws-iam", "active-directory", "postgresql", "mysql", "wifi-wpa2"} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			p, ok := LookupPreset(name)
+			if !ok {
+				t.Fatalf("preset %q is not registered", name)
+			}
+
+			gen, err := p.NewGenerator()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i := 0; i < 100; i++ {
+				res, err := GenerateFor(name)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if len(res) != p.Length {
+					t.Errorf("%q has length %d, want %d", res, len(res), p.Length)
+				}
+
+				if !gen.isLegalPassword(res, p.NeedsLower, p.NeedsUpper, p.NeedsDigit, p.NeedsSymbol) {
+					t.Errorf("%q does not satisfy the preset", res)
+				}
+
+				if p.Validator != nil {
+					if err := p.Validator.Validate(res); err != nil {
+						t.Errorf("%q: %v", res, err)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateFor_wifi(t *testing.T) {
+	t.Parallel()
+
+	res, err := GenerateFor("wifi-wpa2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res) < 8 || len(res) > 63 {
+		t.Errorf("%q is not a valid WPA2 passphrase length", res)
+	}
+
+	for _, r := range res {
+		if r < 0x20 || r > 0x7e {
+			t.Errorf("%q contains non-printable ASCII %q", res, r)
+		}
+	}
+}
+
+func TestGenerateFor_unknown(t *testing.T) {
+	t.Parallel()
+
+	if _, err := GenerateFor("nope"); !errors.Is(err, ErrPresetUnknown) {
+		t.Errorf("expected %v to be %v", err, ErrPresetUnknown)
+	}
+}
+
+func TestRegisterPreset(t *testing.T) {
+	t.Parallel()
+
+	p := Preset{
+		Name:         "test-register-preset",
+		Input:        GeneratorInput{Symbols: "#"},
+		Length:       10,
+		NumDigits:    2,
+		NumSymbols:   1,
+		IncludeUpper: true,
+		AllowRepeat:  true,
+		NeedsSymbol:  true,
+	}
+
+	if err := RegisterPreset(p); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RegisterPreset(p); !errors.Is(err, ErrPresetExists) {
+		t.Errorf("expected %v to be %v", err, ErrPresetExists)
+	}
+
+	found := false
+	for _, name := range PresetNames() {
+		found = found || name == p.Name
+	}
+	if !found {
+		t.Errorf("%q is missing from PresetNames()", p.Name)
+	}
+
+	res, err := GenerateFor(p.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(res, "#") {
+		t.Errorf("%q should contain the configured symbol", res)
+	}
+}
+
+func TestGenerateFor_activeDirectory(t *testing.T) {
+	t.Parallel()
+
+	for i := 0; i < 100; i++ {
+		res, err := GenerateFor("active-directory")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if ADCategories(res) != ADUpper|ADLower|ADDigit|ADSymbol {
+			t.Errorf("%q misses an Active Directory category", res)
+		}
+	}
+
+	p := Preset{
+		Name:       "test-preset-validator",
+		Length:     8,
+		NeedsLower: true,
+		Validator:  &ADComplexityPolicy{},
+	}
+
+	var ve *ValidationError
+	if _, err := p.Generate(); !errors.As(err, &ve) || ve.Rule != "categories" {
+		t.Errorf("expected %v to wrap the rejection", err)
+	}
+}
+
+func TestRegisterPreset_invalid(t *testing.T) {
+	t.Parallel()
+
+	for _, p := range []Preset{
+		{},
+		{Name: "invalid-length"},
+		{Name: "invalid-counts", Length: 4, NumDigits: 3, NumSymbols: 2},
+		{Name: "invalid-lower", Length: 2, NumDigits: 2, NeedsLower: true},
+		{Name: "invalid-upper", Length: 8, NeedsUpper: true},
+		{Name: "invalid-digits-and-upper", Length: 1, NumDigits: 1, IncludeUpper: true, NeedsUpper: true, NeedsDigit: true},
+		{Name: "invalid-digit", Length: 8, NeedsDigit: true},
+		{Name: "invalid-symbol", Length: 8, NeedsSymbol: true},
+		{Name: "invalid-profile", Length: 8, Input: GeneratorInput{SymbolProfile: 99}},
+	} {
+		if err := RegisterPreset(p); err == nil {
+			t.Errorf("preset %q should be rejected", p.Name)
+		}
+	}
+}
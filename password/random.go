@@ -0,0 +1,140 @@
+package password
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"math/bits"
+)
+
+// randomBufferSize is the number of bytes read from the underlying reader at
+// a time. Site passwords depend on it, see SiteVersion.
+const randomBufferSize = 128
+
+// randomSource draws unbiased bounded integers from an io.Reader. Reads are
+// buffered so that generating a password costs a handful of calls to the
+// reader instead of one per random draw. Before every draw the source checks
+// whether its context is done. A randomSource is not safe for concurrent use.
+type randomSource struct {
+	ctx    context.Context
+	done   <-chan struct{}
+	reader io.Reader
+	buf    [randomBufferSize]byte
+	off    int
+	end    int
+}
+
+// newRandomSource creates a randomSource reading from r until ctx is done.
+func newRandomSource(ctx context.Context, r io.Reader) *randomSource {
+	return &randomSource{
+		ctx:    ctx,
+		done:   ctx.Done(),
+		reader: r,
+	}
+}
+
+// contextError wraps the error of a done context so callers can tell it apart
+// from errors returned by the reader.
+type contextError struct {
+	err error
+}
+
+func (e *contextError) Error() string { return e.err.Error() }
+func (e *contextError) Unwrap() error { return e.err }
+
+// intn returns a uniformly distributed integer in [0, n). Like crypto/rand.Int
+// it reads just enough bytes to cover n-1, masks off the excess high bits and
+// rejects out-of-range values, so the result is free of modulo bias. n must be
+// positive. Site passwords depend on the exact bytes consumed, see
+// SiteVersion.
+func (s *randomSource) intn(n int) (int, error) {
+	if s.done != nil {
+		select {
+		case <-s.done:
+			return 0, &contextError{err: s.ctx.Err()}
+		default:
+		}
+	}
+
+	if n <= 1 {
+		return 0, nil
+	}
+
+	max := uint64(n - 1)
+	bitLen := bits.Len64(max)
+	size := (bitLen + 7) / 8
+	mask := uint64(1)<<uint(bitLen) - 1
+
+	var b [8]byte
+	for {
+		if err := s.read(b[8-size:]); err != nil {
+			return 0, err
+		}
+
+		v := binary.BigEndian.Uint64(b[:]) & mask
+		if v <= max {
+			return int(v), nil
+		}
+	}
+}
+
+// wipe overwrites the buffered random bytes with zeros. The bytes determine
+// everything drawn from them, so they are as secret as the password.
+func (s *randomSource) wipe() {
+	wipe(s.buf[:])
+	s.off, s.end = 0, 0
+}
+
+// read fills p from the buffer, refilling it from the reader as needed.
+func (s *randomSource) read(p []byte) error {
+	for len(p) > 0 {
+		if s.off == s.end {
+			n, err := io.ReadAtLeast(s.reader, s.buf[:], len(p))
+			if err != nil {
+				return err
+			}
+			s.off, s.end = 0, n
+		}
+
+		n := copy(p, s.buf[s.off:s.end])
+		s.off += n
+		p = p[n:]
+	}
+	return nil
+}
+
+// runeSet is a set of runes. ASCII runes are kept in a bitmap so the common
+// case does not allocate; other runes spill over into a map.
+type runeSet struct {
+	ascii [2]uint64
+	other map[rune]struct{}
+}
+
+// contains reports whether r is in the set.
+func (s *runeSet) contains(r rune) bool {
+	if r >= 0 && r < 128 {
+		return s.ascii[r>>6]&(1<<uint(r&63)) != 0
+	}
+	_, ok := s.other[r]
+	return ok
+}
+
+// reset removes every rune from the set.
+func (s *runeSet) reset() {
+	s.ascii = [2]uint64{}
+	for r := range s.other {
+		delete(s.other, r)
+	}
+}
+
+// add adds r to the set.
+func (s *runeSet) add(r rune) {
+	if r >= 0 && r < 128 {
+		s.ascii[r>>6] |= 1 << uint(r&63)
+		return
+	}
+	if s.other == nil {
+		s.other = make(map[rune]struct{})
+	}
+	s.other[r] = struct{}{}
+}
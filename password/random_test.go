@@ -0,0 +1,114 @@
+package password
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestRandomSource_intn(t *testing.T) {
+	t.Parallel()
+
+	src := newRandomSource(context.Background(), rand.Reader)
+	for _, n := range []int{1, 2, 7, 255, 256, 257, 1 << 20} {
+		for i := 0; i < 1000; i++ {
+			v, err := src.intn(n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v < 0 || v >= n {
+				t.Fatalf("intn(%d) returned %d", n, v)
+			}
+		}
+	}
+}
+
+func TestRandomSource_intn_uniform(t *testing.T) {
+	t.Parallel()
+
+	// Every byte value exactly once: values below 6*42=252 are accepted and
+	// must spread evenly over [0, 6) after masking, others are rejected.
+	data := make([]byte, 256)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	src := newRandomSource(context.Background(), bytes.NewReader(data))
+	counts := make([]int, 6)
+	for i := 0; i < 6*32; i++ {
+		v, err := src.intn(6)
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts[v]++
+	}
+
+	for v, c := range counts {
+		if c != 32 {
+			t.Errorf("value %d drawn %d times, want 32", v, c)
+		}
+	}
+}
+
+func TestRandomSource_readError(t *testing.T) {
+	t.Parallel()
+
+	src := newRandomSource(context.Background(), bytes.NewReader(nil))
+	if _, err := src.intn(10); !errors.Is(err, io.EOF) {
+		t.Errorf("expected %v to be %v", err, io.EOF)
+	}
+}
+
+func TestRuneSet(t *testing.T) {
+	t.Parallel()
+
+	var s runeSet
+	for _, r := range "aZ~é世" {
+		if s.contains(r) {
+			t.Errorf("empty set contains %q", r)
+		}
+		s.add(r)
+		if !s.contains(r) {
+			t.Errorf("set does not contain %q after add", r)
+		}
+	}
+
+	if s.contains('b') || s.contains('界') {
+		t.Errorf("set contains runes that were never added")
+	}
+}
+
+func TestGenerator_Generate_Unicode(t *testing.T) {
+	t.Parallel()
+
+	gen, err := NewStatefulGenerator(&GeneratorInput{
+		LowerLetters: "äöüß",
+		Digits:       "٠١٢٣",
+		Symbols:      "€£",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 100; i++ {
+		res, err := gen.Generate(8, 2, 2, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := len([]rune(res)); got != 8 {
+			t.Errorf("%q has %d characters, want 8", res, got)
+		}
+
+		if testHasDuplicates(t, res) {
+			t.Errorf("%q should not have duplicates", res)
+		}
+	}
+
+	if _, err := gen.Generate(9, 2, 2, false, false); !errors.Is(err, ErrLettersExceedsAvailable) {
+		t.Errorf("expected %q to be %q", err, ErrLettersExceedsAvailable)
+	}
+}
@@ -0,0 +1,298 @@
+package password
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"io"
+	"math"
+	"strings"
+	"unicode"
+)
+
+const (
+	// RecoveryCodeAlphabet is the default alphabet of recovery codes: lower
+	// case Crockford base32, which leaves out the easily confused i, l, o and
+	// u.
+	RecoveryCodeAlphabet = "0123456789abcdefghjkmnpqrstvwxyz"
+
+	// DefaultRecoveryCodeCount is the number of codes in a set unless
+	// configured otherwise.
+	DefaultRecoveryCodeCount = 10
+)
+
+var (
+	// ErrRecoveryCodeInputInvalid is the error returned when the count,
+	// group size or number of groups of a recovery code configuration is
+	// negative.
+	ErrRecoveryCodeInputInvalid = errors.New("recovery code count, group size and groups must not be negative")
+
+	// ErrRecoveryCodeAlphabetInvalid is the error returned when a recovery
+	// code alphabet has fewer than two characters or repeats a character.
+	ErrRecoveryCodeAlphabetInvalid = errors.New("recovery code alphabet must consist of at least two distinct characters")
+
+	// ErrRecoveryCodeSpaceExhausted is the error returned when the requested
+	// number of codes exceeds the number of distinct codes.
+	ErrRecoveryCodeSpaceExhausted = errors.New("number of recovery codes exceeds the number of distinct codes")
+
+	// ErrRecoveryCodeInvalid is the error returned when normalizing input that
+	// cannot be a recovery code.
+	ErrRecoveryCodeInvalid = errors.New("recovery code is invalid")
+)
+
+// confusables lists groups of characters that users mix up when typing codes.
+var confusables = []string{"0o", "1il", "2z", "5s", "8b", "uv"}
+
+// RecoveryCodeInput is used as input to the NewRecoveryCodeGenerator
+// function.
+type RecoveryCodeInput struct {
+	Count     int       // DefaultRecoveryCodeCount by default
+	GroupSize int       // 4 by default
+	Groups    int       // 2 by default
+	Separator string    // "-" by default
+	Alphabet  string    // RecoveryCodeAlphabet by default
+	Reader    io.Reader // rand.Reader by default
+}
+
+// RecoveryCodeGenerator generates sets of one-time recovery codes such as
+// "x7k2-9mqa". Codes are unique within a set. User input is normalized before
+// verification, and codes are meant to be stored hashed.
+type RecoveryCodeGenerator struct {
+	count     int
+	groupSize int
+	groups    int
+	separator string
+	alphabet  []rune
+	charset   *Charset
+	folds     map[rune]rune
+	reader    io.Reader
+}
+
+// NewRecoveryCodeGenerator creates a new RecoveryCodeGenerator from the
+// specified configuration. If no input is given, all the default values are
+// used.
+func NewRecoveryCodeGenerator(i *RecoveryCodeInput) (*RecoveryCodeGenerator, error) {
+	if i == nil {
+		i = new(RecoveryCodeInput)
+	}
+
+	if i.Count < 0 || i.GroupSize < 0 || i.Groups < 0 {
+		return nil, ErrRecoveryCodeInputInvalid
+	}
+
+	g := &RecoveryCodeGenerator{
+		count:     i.Count,
+		groupSize: i.GroupSize,
+		groups:    i.Groups,
+		separator: i.Separator,
+		reader:    i.Reader,
+	}
+
+	if g.count == 0 {
+		g.count = DefaultRecoveryCodeCount
+	}
+
+	if g.groupSize == 0 {
+		g.groupSize = 4
+	}
+
+	if g.groups == 0 {
+		g.groups = 2
+	}
+
+	if g.separator == "" {
+		g.separator = "-"
+	}
+
+	alphabet := i.Alphabet
+	if alphabet == "" {
+		alphabet = RecoveryCodeAlphabet
+	}
+
+	if g.reader == nil {
+		g.reader = rand.Reader
+	}
+
+	var seen runeSet
+	for _, r := range alphabet {
+		if seen.contains(r) {
+			return nil, ErrRecoveryCodeAlphabetInvalid
+		}
+		seen.add(r)
+		g.alphabet = append(g.alphabet, r)
+	}
+	if len(g.alphabet) < 2 {
+		return nil, ErrRecoveryCodeAlphabetInvalid
+	}
+	g.charset = NewCharset(alphabet)
+
+	space := float64(g.groupSize*g.groups) * math.Log2(float64(len(g.alphabet)))
+	if space < 63 && float64(g.count) > math.Exp2(space) {
+		return nil, ErrRecoveryCodeSpaceExhausted
+	}
+
+	g.folds = make(map[rune]rune)
+	for _, group := range confusables {
+		var target rune
+		n := 0
+		for _, r := range group {
+			if c, ok := g.foldCase(r); ok {
+				target = c
+				n++
+			}
+		}
+		if n != 1 {
+			continue
+		}
+		for _, r := range group {
+			if _, ok := g.foldCase(r); !ok {
+				g.folds[r] = target
+			}
+		}
+	}
+
+	return g, nil
+}
+
+// Generate generates a set of unique recovery codes.
+func (g *RecoveryCodeGenerator) Generate() ([]string, error) {
+	return g.GenerateContext(context.Background())
+}
+
+// GenerateContext is the same as Generate, but stops between random draws
+// once ctx is done.
+func (g *RecoveryCodeGenerator) GenerateContext(ctx context.Context) ([]string, error) {
+	src := newRandomSource(ctx, g.reader)
+	length := g.groupSize * g.groups
+
+	codes := make([]string, 0, g.count)
+	seen := make(map[string]struct{}, g.count)
+	buf := make([]rune, length)
+	for len(codes) < g.count {
+		for i := range buf {
+			j, err := src.intn(len(g.alphabet))
+			if err != nil {
+				return nil, progressError(readerError(err, "code"), len(codes)*length+i, g.count*length)
+			}
+			buf[i] = g.alphabet[j]
+		}
+
+		code := g.format(buf)
+		if _, ok := seen[code]; ok {
+			continue
+		}
+		seen[code] = struct{}{}
+		codes = append(codes, code)
+	}
+
+	return codes, nil
+}
+
+// Normalize converts user input into the canonical form of a code: case is
+// folded to the alphabet, separators, spaces and dashes are dropped, easily
+// confused characters such as O and 0 are mapped onto the alphabet, and the
+// groups are joined with the separator again. It returns
+// ErrRecoveryCodeInvalid if the result cannot be a code.
+func (g *RecoveryCodeGenerator) Normalize(code string) (string, error) {
+	code = strings.Replace(code, g.separator, "", -1)
+
+	buf := make([]rune, 0, g.groupSize*g.groups)
+	for _, r := range code {
+		if unicode.IsSpace(r) || r == '-' || r == '_' {
+			continue
+		}
+
+		if len(buf) == g.groupSize*g.groups {
+			return "", ErrRecoveryCodeInvalid
+		}
+
+		c, ok := g.fold(r)
+		if !ok {
+			return "", ErrRecoveryCodeInvalid
+		}
+		buf = append(buf, c)
+	}
+
+	if len(buf) != g.groupSize*g.groups {
+		return "", ErrRecoveryCodeInvalid
+	}
+
+	return g.format(buf), nil
+}
+
+// Hash normalizes code and hashes it with Argon2id and a random salt for
+// storage. Store the hashes, never the codes.
+func (g *RecoveryCodeGenerator) Hash(code string) (string, error) {
+	norm, err := g.Normalize(code)
+	if err != nil {
+		return "", err
+	}
+
+	return hashSecret(g.reader, []byte(norm))
+}
+
+// HashAll hashes every code of a set, preserving their order.
+func (g *RecoveryCodeGenerator) HashAll(codes []string) ([]string, error) {
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		h, err := g.Hash(code)
+		if err != nil {
+			return nil, err
+		}
+		hashes[i] = h
+	}
+	return hashes, nil
+}
+
+// Verify normalizes code and looks it up in hashes. It returns the index of
+// the matching hash, which the caller must then remove to make the code
+// single-use, or -1 if there is none. Input that cannot be a code is reported
+// as ErrRecoveryCodeInvalid.
+func (g *RecoveryCodeGenerator) Verify(code string, hashes []string) (int, error) {
+	norm, err := g.Normalize(code)
+	if err != nil {
+		return -1, err
+	}
+
+	for i, h := range hashes {
+		ok, err := verifySecret([]byte(norm), h)
+		if err != nil {
+			return -1, err
+		}
+		if ok {
+			return i, nil
+		}
+	}
+	return -1, nil
+}
+
+// fold maps r onto the alphabet, reporting whether that was possible.
+func (g *RecoveryCodeGenerator) fold(r rune) (rune, bool) {
+	if c, ok := g.foldCase(r); ok {
+		return c, true
+	}
+	c, ok := g.folds[unicode.ToLower(r)]
+	return c, ok
+}
+
+// foldCase returns r, or r in the other case, if it is in the alphabet.
+func (g *RecoveryCodeGenerator) foldCase(r rune) (rune, bool) {
+	for _, c := range []rune{r, unicode.ToLower(r), unicode.ToUpper(r)} {
+		if g.charset.Contains(c) {
+			return c, true
+		}
+	}
+	return 0, false
+}
+
+// format splits buf into groups joined by the separator.
+func (g *RecoveryCodeGenerator) format(buf []rune) string {
+	var b strings.Builder
+	for i, r := range buf {
+		if i > 0 && i%g.groupSize == 0 {
+			b.WriteString(g.separator)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,239 @@
+package password
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestRecoveryCodeGenerator_Generate(t *testing.T) {
+	t.Parallel()
+
+	gen, err := NewRecoveryCodeGenerator(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	codes, err := gen.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(codes) != DefaultRecoveryCodeCount {
+		t.Errorf("got %d codes, want %d", len(codes), DefaultRecoveryCodeCount)
+	}
+
+	format := regexp.MustCompile(`^[0-9a-hjkmnp-tv-z]{4}-[0-9a-hjkmnp-tv-z]{4}$`)
+	for _, code := range codes {
+		if !format.MatchString(code) {
+			t.Errorf("%q does not match %q", code, format)
+		}
+	}
+}
+
+func TestRecoveryCodeGenerator_Generate_unique(t *testing.T) {
+	t.Parallel()
+
+	// 16 distinct codes exist, so all of them must be produced.
+	gen, err := NewRecoveryCodeGenerator(&RecoveryCodeInput{
+		Count:     16,
+		GroupSize: 2,
+		Groups:    2,
+		Separator: " ",
+		Alphabet:  "ab",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	codes, err := gen.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		if seen[code] {
+			t.Errorf("%q was generated twice", code)
+		}
+		seen[code] = true
+	}
+
+	if len(seen) != 16 {
+		t.Errorf("got %d distinct codes, want 16", len(seen))
+	}
+}
+
+func TestRecoveryCodeGenerator_Normalize(t *testing.T) {
+	t.Parallel()
+
+	gen, err := NewRecoveryCodeGenerator(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var TestCases = []struct {
+		Name     string
+		Input    string
+		Expected string
+		Err      error
+	}{
+		{
+			Name:     "canonical",
+			Input:    "x7k2-9mqa",
+			Expected: "x7k2-9mqa",
+		},
+		{
+			Name:     "upper case and spaces",
+			Input:    " X7K2 9MQA ",
+			Expected: "x7k2-9mqa",
+		},
+		{
+			Name:     "no separator",
+			Input:    "x7k29mqa",
+			Expected: "x7k2-9mqa",
+		},
+		{
+			Name:     "confusables",
+			Input:    "OIL0-uUoo",
+			Expected: "0110-vv00",
+		},
+		{
+			Name:  "too short",
+			Input: "x7k2-9mq",
+			Err:   ErrRecoveryCodeInvalid,
+		},
+		{
+			Name:  "too long",
+			Input: "x7k2-9mqa-b",
+			Err:   ErrRecoveryCodeInvalid,
+		},
+		{
+			Name:  "outside alphabet",
+			Input: "x7k2-9mq!",
+			Err:   ErrRecoveryCodeInvalid,
+		},
+	}
+
+	for _, tc := range TestCases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			res, err := gen.Normalize(tc.Input)
+			if err != tc.Err {
+				t.Fatalf("expected %v to be %v", err, tc.Err)
+			}
+
+			if res != tc.Expected {
+				t.Errorf("Normalize(%q) = %q, want %q", tc.Input, res, tc.Expected)
+			}
+		})
+	}
+}
+
+func TestRecoveryCodeGenerator_Normalize_upperAlphabet(t *testing.T) {
+	t.Parallel()
+
+	gen, err := NewRecoveryCodeGenerator(&RecoveryCodeInput{
+		Alphabet: AlphabetBase32Crockford,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := gen.Normalize("b8ol-i0Ou")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "B801-100V"; res != want {
+		t.Errorf("got %q, want %q", res, want)
+	}
+}
+
+func TestRecoveryCodeGenerator_Verify(t *testing.T) {
+	t.Parallel()
+
+	gen, err := NewRecoveryCodeGenerator(&RecoveryCodeInput{Count: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	codes, err := gen.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hashes, err := gen.HashAll(codes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, h := range hashes {
+		if h == codes[i] || regexp.MustCompile(codes[i]).MatchString(h) {
+			t.Errorf("hash %q contains the code", h)
+		}
+	}
+
+	i, err := gen.Verify(" "+codes[2]+" ", hashes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i != 2 {
+		t.Errorf("Verify() = %d, want 2", i)
+	}
+
+	i, err = gen.Verify("0000-0000", hashes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i != -1 {
+		t.Errorf("Verify() = %d, want -1", i)
+	}
+
+	if _, err := gen.Verify("nope", hashes); err != ErrRecoveryCodeInvalid {
+		t.Errorf("expected %v to be %v", err, ErrRecoveryCodeInvalid)
+	}
+}
+
+func TestNewRecoveryCodeGenerator_errors(t *testing.T) {
+	t.Parallel()
+
+	var TestCases = []struct {
+		Name  string
+		Input RecoveryCodeInput
+		Err   error
+	}{
+		{
+			Name:  "negative count",
+			Input: RecoveryCodeInput{Count: -1},
+			Err:   ErrRecoveryCodeInputInvalid,
+		},
+		{
+			Name:  "single character alphabet",
+			Input: RecoveryCodeInput{Alphabet: "a"},
+			Err:   ErrRecoveryCodeAlphabetInvalid,
+		},
+		{
+			Name:  "repeated character",
+			Input: RecoveryCodeInput{Alphabet: "aba"},
+			Err:   ErrRecoveryCodeAlphabetInvalid,
+		},
+		{
+			Name:  "too many codes",
+			Input: RecoveryCodeInput{Count: 5, GroupSize: 1, Groups: 2, Alphabet: "ab"},
+			Err:   ErrRecoveryCodeSpaceExhausted,
+		},
+	}
+
+	for _, tc := range TestCases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := NewRecoveryCodeGenerator(&tc.Input); err != tc.Err {
+				t.Errorf("expected %v to be %v", err, tc.Err)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package password
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"unicode/utf8"
+)
+
+// redacted is what a SecretBuffer prints instead of its contents.
+const redacted = "[REDACTED]"
+
+var (
+	// ErrSecretDestroyed is the error returned when accessing a SecretBuffer
+	// after Destroy was called.
+	ErrSecretDestroyed = errors.New("secret buffer has been destroyed")
+
+	// ErrLockUnsupported is the error returned by SecretBuffer.Lock on
+	// platforms that cannot lock memory.
+	ErrLockUnsupported = errors.New("locking memory is not supported on this platform")
+)
+
+// SecretBuffer holds a password in a byte slice that can be wiped once it is
+// no longer needed, unlike a string which lingers in memory until it is
+// garbage collected. The contents are only accessible through Reveal; the
+// fmt, encoding/json and encoding packages see a redacted placeholder.
+//
+// A SecretBuffer is safe for concurrent use.
+type SecretBuffer struct {
+	mu        sync.RWMutex
+	data      []byte
+	locked    bool
+	destroyed bool
+}
+
+// NewSecretBuffer creates a SecretBuffer that takes ownership of b. The caller
+// must not use b afterwards. The buffer is destroyed when it is garbage
+// collected, but callers should call Destroy as soon as possible.
+func NewSecretBuffer(b []byte) *SecretBuffer {
+	s := &SecretBuffer{data: b}
+	runtime.SetFinalizer(s, (*SecretBuffer).Destroy)
+	return s
+}
+
+// Len returns the length of the secret in bytes, or 0 if it was destroyed.
+func (s *SecretBuffer) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.data)
+}
+
+// Reveal calls fn with the contents of the buffer. The slice is only valid for
+// the duration of the call: fn must neither retain nor modify it, and must not
+// call other methods of the buffer.
+func (s *SecretBuffer) Reveal(fn func(secret []byte)) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.destroyed {
+		return ErrSecretDestroyed
+	}
+
+	fn(s.data)
+	return nil
+}
+
+// Lock locks the buffer into memory so it cannot be swapped to disk. It
+// returns ErrLockUnsupported on platforms other than Linux. Locking may also
+// fail when the process exceeds RLIMIT_MEMLOCK.
+func (s *SecretBuffer) Lock() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.destroyed {
+		return ErrSecretDestroyed
+	}
+
+	if s.locked || len(s.data) == 0 {
+		return nil
+	}
+
+	if err := mlock(s.data); err != nil {
+		return err
+	}
+	s.locked = true
+	return nil
+}
+
+// Destroy overwrites the buffer with zeros and unlocks it. Calling Destroy
+// more than once is a no-op.
+func (s *SecretBuffer) Destroy() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.destroyed {
+		return
+	}
+
+	wipe(s.data)
+	if s.locked {
+		_ = munlock(s.data)
+		s.locked = false
+	}
+
+	s.data = nil
+	s.destroyed = true
+	runtime.SetFinalizer(s, nil)
+}
+
+// Destroyed reports whether Destroy was called.
+func (s *SecretBuffer) Destroyed() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.destroyed
+}
+
+// String returns a redacted placeholder.
+func (s *SecretBuffer) String() string {
+	return redacted
+}
+
+// GoString returns a redacted placeholder for the %#v verb.
+func (s *SecretBuffer) GoString() string {
+	return redacted
+}
+
+// Format writes a redacted placeholder for every verb, so %x and %q do not
+// leak the contents either.
+func (s *SecretBuffer) Format(f fmt.State, _ rune) {
+	_, _ = f.Write([]byte(redacted))
+}
+
+// MarshalJSON encodes a redacted placeholder.
+func (s *SecretBuffer) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + redacted + `"`), nil
+}
+
+// MarshalText encodes a redacted placeholder.
+func (s *SecretBuffer) MarshalText() ([]byte, error) {
+	return []byte(redacted), nil
+}
+
+// GenerateSecret is the same as Generate, but returns the password in a
+// SecretBuffer instead of a string. It is not part of the Generator interface.
+func (g *StatefulGenerator) GenerateSecret(length, numDigits, numSymbols int, includeUpper, allowRepeat bool) (*SecretBuffer, error) {
+	return g.GenerateSecretContext(context.Background(), length, numDigits, numSymbols, includeUpper, allowRepeat)
+}
+
+// GenerateSecretContext is the same as GenerateContext, but returns the
+// password in a SecretBuffer instead of a string.
+func (g *StatefulGenerator) GenerateSecretContext(ctx context.Context, length, numDigits, numSymbols int, includeUpper, allowRepeat bool) (*SecretBuffer, error) {
+	var b []byte
+	err := g.generate(ctx, length, numDigits, numSymbols, includeUpper, allowRepeat, func(runes []rune) {
+		n := 0
+		for _, r := range runes {
+			n += utf8.RuneLen(r)
+		}
+
+		b = make([]byte, n)
+		i := 0
+		for _, r := range runes {
+			i += utf8.EncodeRune(b[i:], r)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return NewSecretBuffer(b), nil
+}
+
+// GenerateSecret is the package shortcut for StatefulGenerator.GenerateSecret.
+func GenerateSecret(length, numDigits, numSymbols int, includeUpper, allowRepeat bool) (*SecretBuffer, error) {
+	gen, err := NewStatefulGenerator(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return gen.GenerateSecret(length, numDigits, numSymbols, includeUpper, allowRepeat)
+}
+
+// wipe overwrites b with zeros.
+func wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// wipeRunes overwrites r with zeros.
+func wipeRunes(r []rune) {
+	for i := range r {
+		r[i] = 0
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package password
+
+// mlock is not supported on this platform.
+func mlock([]byte) error {
+	return ErrLockUnsupported
+}
+
+// munlock is not supported on this platform.
+func munlock([]byte) error {
+	return ErrLockUnsupported
+}
@@ -0,0 +1,201 @@
+package password
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestSecretBuffer_Reveal(t *testing.T) {
+	t.Parallel()
+
+	s := NewSecretBuffer([]byte("hunter2"))
+	if got, want := s.Len(), 7; got != want {
+		t.Errorf("Len() = %d, want %d", got, want)
+	}
+
+	var got string
+	if err := s.Reveal(func(b []byte) { got = string(b) }); err != nil {
+		t.Fatal(err)
+	}
+
+	if got != "hunter2" {
+		t.Errorf("Reveal() = %q, want %q", got, "hunter2")
+	}
+}
+
+func TestSecretBuffer_Destroy(t *testing.T) {
+	t.Parallel()
+
+	b := []byte("hunter2")
+	s := NewSecretBuffer(b)
+	s.Destroy()
+	s.Destroy()
+
+	if !s.Destroyed() {
+		t.Errorf("expected buffer to be destroyed")
+	}
+
+	for i, c := range b {
+		if c != 0 {
+			t.Errorf("byte %d is %q, want 0", i, c)
+		}
+	}
+
+	if s.Len() != 0 {
+		t.Errorf("expected destroyed buffer to be empty")
+	}
+
+	if err := s.Reveal(func([]byte) {}); err != ErrSecretDestroyed {
+		t.Errorf("expected %q to be %q", err, ErrSecretDestroyed)
+	}
+
+	if err := s.Lock(); err != ErrSecretDestroyed {
+		t.Errorf("expected %q to be %q", err, ErrSecretDestroyed)
+	}
+}
+
+func TestSecretBuffer_Lock(t *testing.T) {
+	t.Parallel()
+
+	s := NewSecretBuffer([]byte("hunter2"))
+	defer s.Destroy()
+
+	err := s.Lock()
+	if runtime.GOOS != "linux" {
+		if err != ErrLockUnsupported {
+			t.Errorf("expected %q to be %q", err, ErrLockUnsupported)
+		}
+		return
+	}
+
+	// Locking can legitimately fail under a tight RLIMIT_MEMLOCK.
+	if err != nil {
+		t.Skipf("mlock: %v", err)
+	}
+}
+
+func TestSecretBuffer_redacted(t *testing.T) {
+	t.Parallel()
+
+	s := NewSecretBuffer([]byte("hunter2"))
+	defer s.Destroy()
+
+	for _, format := range []string{"%s", "%v", "%+v", "%#v", "%q", "%x", "%d"} {
+		if got := fmt.Sprintf(format, s); strings.Contains(got, "hunter2") || got != redacted {
+			t.Errorf("Sprintf(%q) = %q, want %q", format, got, redacted)
+		}
+	}
+
+	b, err := json.Marshal(struct {
+		Password *SecretBuffer
+	}{s})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := string(b), `{"Password":"[REDACTED]"}`; got != want {
+		t.Errorf("json.Marshal() = %s, want %s", got, want)
+	}
+}
+
+func TestGenerator_GenerateSecret(t *testing.T) {
+	t.Parallel()
+
+	gen, err := NewStatefulGenerator(&GeneratorInput{
+		Symbols: "€£¥",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := gen.GenerateSecret(16, 2, 2, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Destroy()
+
+	err = s.Reveal(func(b []byte) {
+		res := string(b)
+		if got := len([]rune(res)); got != 16 {
+			t.Errorf("%q has %d characters, want 16", res, got)
+		}
+
+		if got := gen.symbols.Count(res); got != 2 {
+			t.Errorf("%q has %d symbols, want 2", res, got)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestGenerator_GenerateSecret_wipe must not run in parallel, because it
+// sets testHookGenerated.
+func TestGenerator_GenerateSecret_wipe(t *testing.T) {
+	var calls int
+	var dirty []string
+	testHookGenerated = func(random []byte, runes []rune) {
+		calls++
+		for _, b := range random {
+			if b != 0 {
+				dirty = append(dirty, "random bytes")
+				break
+			}
+		}
+		for _, r := range runes {
+			if r != 0 {
+				dirty = append(dirty, "runes")
+				break
+			}
+		}
+	}
+	defer func() { testHookGenerated = nil }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var TestCases = []struct {
+		Name       string
+		Input      GeneratorInput
+		Ctx        context.Context
+		Length     int
+		NumSymbols int
+		Err        bool
+	}{
+		{Name: "success", Length: 16, NumSymbols: 2},
+		{Name: "reader error", Input: GeneratorInput{Reader: &shortReader{data: []byte{1, 2, 3, 4}}}, Length: 8, Err: true},
+		{Name: "canceled", Input: GeneratorInput{Reader: &cancelReader{cancel: cancel, reads: 1}}, Ctx: ctx, Length: 8, Err: true},
+		{Name: "count error", Input: GeneratorInput{LowerLetters: "abc", Symbols: "!a"}, Length: 5, NumSymbols: 2, Err: true},
+	}
+
+	for _, tc := range TestCases {
+		calls, dirty = 0, nil
+
+		gen, err := NewStatefulGenerator(&tc.Input)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if tc.Ctx == nil {
+			tc.Ctx = context.Background()
+		}
+		s, err := gen.GenerateSecretContext(tc.Ctx, tc.Length, 0, tc.NumSymbols, false, false)
+		if tc.Err != (err != nil) {
+			t.Errorf("%s: unexpected error %v", tc.Name, err)
+		}
+		if s != nil {
+			s.Destroy()
+		}
+
+		if calls != 1 {
+			t.Errorf("%s: expected %d calls to be 1", tc.Name, calls)
+		}
+		if dirty != nil {
+			t.Errorf("%s: expected %v to be wiped", tc.Name, dirty)
+		}
+	}
+}
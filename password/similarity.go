@@ -0,0 +1,210 @@
+package password
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Built-time checks that the validators implement the interface.
+var _ Validator = (*SimilarityValidator)(nil)
+
+const (
+	// DefaultSimilarityMinDistance is the edit distance below which
+	// SimilarityValidator rejects a password unless configured otherwise.
+	DefaultSimilarityMinDistance = 4
+
+	// DefaultSimilarityMaxCommonRatio is the share of a password that
+	// SimilarityValidator allows it to have in common with the previous one
+	// unless configured otherwise.
+	DefaultSimilarityMaxCommonRatio = 0.5
+)
+
+// leetFolds maps l33t substitutions onto the letters they stand for. Letters
+// that are commonly confused with each other, such as i and l, are folded
+// together.
+var leetFolds = map[rune]rune{
+	'0': 'o',
+	'1': 'i', '!': 'i', '|': 'i', 'l': 'i',
+	'2': 'z',
+	'3': 'e',
+	'4': 'a', '@': 'a',
+	'5': 's', '$': 's',
+	'6': 'g', '9': 'g',
+	'7': 't', '+': 't',
+	'8': 'b',
+}
+
+// normalizeLeet folds the case of s and undoes l33t substitutions, so that
+// "P@ssw0rd" and "password" compare equal.
+func normalizeLeet(s string) string {
+	return strings.Map(func(r rune) rune {
+		if f, ok := leetFolds[r]; ok {
+			return f
+		}
+		return r
+	}, strings.ToLower(s))
+}
+
+// SimilarityReport describes how similar two passwords are. Normalized
+// figures compare the passwords after folding case and undoing l33t
+// substitutions.
+type SimilarityReport struct {
+	// Distance is the Levenshtein distance: the number of characters to
+	// insert, delete or replace to turn one password into the other.
+	Distance int
+
+	// NormalizedDistance is the Levenshtein distance of the normalized
+	// passwords.
+	NormalizedDistance int
+
+	// CommonSubstring is the length of the longest run of characters the
+	// normalized passwords have in common.
+	CommonSubstring int
+
+	// CommonRatio is CommonSubstring relative to the length of the new
+	// password.
+	CommonRatio float64
+}
+
+// CompareSimilarity compares the new password with the previous one.
+func CompareSimilarity(previous, password string) SimilarityReport {
+	a, b := []rune(previous), []rune(password)
+	na, nb := []rune(normalizeLeet(previous)), []rune(normalizeLeet(password))
+
+	r := SimilarityReport{
+		Distance:           levenshtein(a, b),
+		NormalizedDistance: levenshtein(na, nb),
+		CommonSubstring:    longestCommonSubstring(na, nb),
+	}
+	if len(nb) > 0 {
+		r.CommonRatio = float64(r.CommonSubstring) / float64(len(nb))
+	}
+	return r
+}
+
+// levenshtein returns the edit distance of a and b.
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// longestCommonSubstring returns the length of the longest run of runes that
+// a and b have in common.
+func longestCommonSubstring(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+
+	best := 0
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cur[j] = 0
+			if a[i-1] == b[j-1] {
+				cur[j] = prev[j-1] + 1
+				if cur[j] > best {
+					best = cur[j]
+				}
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return best
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+// SimilarityError is the error returned by SimilarityValidator. It unwraps to
+// its ValidationError and carries the full comparison.
+type SimilarityError struct {
+	ValidationError
+	Report SimilarityReport
+}
+
+// Unwrap returns the embedded ValidationError.
+func (e *SimilarityError) Unwrap() error {
+	return &e.ValidationError
+}
+
+// SimilarityValidator rejects passwords that are too similar to the previous
+// password, such as "Summer2025!" after "Summer2024!". Comparisons ignore case
+// and l33t substitutions.
+type SimilarityValidator struct {
+	// Previous is the password being replaced. An empty Previous accepts
+	// every password.
+	Previous string
+
+	// MinDistance is the smallest accepted edit distance of the normalized
+	// passwords, DefaultSimilarityMinDistance if zero. A negative value
+	// disables the check.
+	MinDistance int
+
+	// MaxCommonRatio is the largest accepted share of the new password that
+	// may be a run of characters of the previous one,
+	// DefaultSimilarityMaxCommonRatio if zero. A negative value disables the
+	// check.
+	MaxCommonRatio float64
+}
+
+// Validate returns a *SimilarityError if password is too similar to the
+// previous password. Its rule is "edit_distance" or "common_substring".
+func (v *SimilarityValidator) Validate(password string) error {
+	if v.Previous == "" {
+		return nil
+	}
+
+	minDistance := v.MinDistance
+	if minDistance == 0 {
+		minDistance = DefaultSimilarityMinDistance
+	}
+	maxRatio := v.MaxCommonRatio
+	if maxRatio == 0 {
+		maxRatio = DefaultSimilarityMaxCommonRatio
+	}
+
+	r := CompareSimilarity(v.Previous, password)
+
+	if minDistance > 0 && r.NormalizedDistance < minDistance {
+		msg := fmt.Sprintf("differs from the previous password in %d characters, at least %d are required", r.NormalizedDistance, minDistance)
+		if r.NormalizedDistance < r.Distance {
+			msg = fmt.Sprintf("differs from the previous password in %d characters when ignoring case and l33t substitutions, at least %d are required", r.NormalizedDistance, minDistance)
+		}
+		return &SimilarityError{ValidationError: ValidationError{Rule: "edit_distance", Message: msg}, Report: r}
+	}
+
+	if maxRatio >= 0 && r.CommonRatio > maxRatio {
+		return &SimilarityError{
+			ValidationError: ValidationError{
+				Rule: "common_substring",
+				Message: fmt.Sprintf("shares a run of %d of its %d characters with the previous password, at most %.0f%% are allowed",
+					r.CommonSubstring, utf8.RuneCountInString(password), maxRatio*100),
+			},
+			Report: r,
+		}
+	}
+
+	return nil
+}
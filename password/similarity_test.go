@@ -0,0 +1,102 @@
+package password
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCompareSimilarity(t *testing.T) {
+	t.Parallel()
+
+	var TestCases = []struct {
+		Previous string
+		Password string
+		Expected SimilarityReport
+	}{
+		{
+			Previous: "Summer2024!", Password: "Summer2025!",
+			Expected: SimilarityReport{Distance: 1, NormalizedDistance: 1, CommonSubstring: 9, CommonRatio: 9.0 / 11},
+		},
+		{
+			Previous: "password", Password: "P@ssw0rd",
+			Expected: SimilarityReport{Distance: 3, NormalizedDistance: 0, CommonSubstring: 8, CommonRatio: 1},
+		},
+		{
+			Previous: "kitten", Password: "sitting",
+			Expected: SimilarityReport{Distance: 3, NormalizedDistance: 3, CommonSubstring: 3, CommonRatio: 3.0 / 7},
+		},
+		{
+			Previous: "", Password: "abc",
+			Expected: SimilarityReport{Distance: 3, NormalizedDistance: 3},
+		},
+		{
+			Previous: "日本語", Password: "日本人",
+			Expected: SimilarityReport{Distance: 1, NormalizedDistance: 1, CommonSubstring: 2, CommonRatio: 2.0 / 3},
+		},
+	}
+
+	for _, tc := range TestCases {
+		tc := tc
+
+		t.Run(tc.Previous+"/"+tc.Password, func(t *testing.T) {
+			t.Parallel()
+
+			if r := CompareSimilarity(tc.Previous, tc.Password); r != tc.Expected {
+				t.Errorf("expected %+v to be %+v", r, tc.Expected)
+			}
+		})
+	}
+}
+
+func TestSimilarityValidator(t *testing.T) {
+	t.Parallel()
+
+	var TestCases = []struct {
+		Name      string
+		Validator SimilarityValidator
+		Password  string
+		Rule      string
+	}{
+		{Name: "incremented year", Validator: SimilarityValidator{Previous: "Summer2024!"}, Password: "Summer2025!", Rule: "edit_distance"},
+		{Name: "l33t", Validator: SimilarityValidator{Previous: "correcthorse"}, Password: "C0rr3ctH0rs3", Rule: "edit_distance"},
+		{Name: "appended", Validator: SimilarityValidator{Previous: "Tr0ub4dor"}, Password: "xy!troub4dor&3", Rule: "common_substring"},
+		{Name: "unrelated", Validator: SimilarityValidator{Previous: "Summer2024!"}, Password: "k7#Qp9vLx2mZ"},
+		{Name: "no previous", Password: "Summer2025!"},
+		{Name: "lower threshold", Validator: SimilarityValidator{Previous: "Summer2024!", MinDistance: 1, MaxCommonRatio: -1}, Password: "Summer2025!"},
+		{Name: "higher ratio", Validator: SimilarityValidator{Previous: "Summer2024!", MaxCommonRatio: 0.9}, Password: "Summer2024!abcd"},
+		{Name: "lower ratio", Validator: SimilarityValidator{Previous: "Summer2024!", MaxCommonRatio: 0.2}, Password: "xyzwSummerqrstuvwxyz", Rule: "common_substring"},
+		{Name: "distance disabled", Validator: SimilarityValidator{Previous: "Summer2024!", MinDistance: -1}, Password: "summer2024!", Rule: "common_substring"},
+	}
+
+	for _, tc := range TestCases {
+		tc := tc
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			err := Validators{&tc.Validator}.Validate(tc.Password)
+			if tc.Rule == "" {
+				if err != nil {
+					t.Errorf("expected %q to be nil", err)
+				}
+				return
+			}
+
+			var se *SimilarityError
+			if !errors.As(err, &se) {
+				t.Fatalf("expected %v to be a *SimilarityError", err)
+			}
+			if se.Report != CompareSimilarity(tc.Validator.Previous, tc.Password) {
+				t.Errorf("expected %+v to be the comparison", se.Report)
+			}
+
+			var ve *ValidationError
+			if !errors.As(err, &ve) {
+				t.Fatalf("expected %v to be a *ValidationError", err)
+			}
+			if ve.Rule != tc.Rule {
+				t.Errorf("expected %q to be %q", ve.Rule, tc.Rule)
+			}
+		})
+	}
+}
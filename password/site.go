@@ -0,0 +1,132 @@
+package password
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// SiteVersion identifies the algorithm used to derive site passwords. Every
+// version is frozen once released: the same inputs keep producing the same
+// passwords, and algorithm changes get a new version.
+//
+// A derived password depends not only on the stream of NewSiteReader but also
+// on how StatefulGenerator turns random bytes into characters: the reads of
+// randomBufferSize bytes in randomSource, the masking and rejection in intn,
+// the order in which generate draws letters, digits and symbols, and the
+// Fisher-Yates shuffle. Changing any of them changes every derived password,
+// so it requires a new SiteVersion that keeps the old mapping for the old
+// versions. TestDeriveSitePassword pins the outputs of SiteV1.
+type SiteVersion int
+
+const (
+	// SiteV1 stretches the inputs with Argon2id (3 passes, 64 MiB, 4 lanes)
+	// into a 32-byte seed for an HMAC-DRBG, and maps its stream to
+	// characters the way StatefulGenerator did when SiteV1 was released.
+	SiteV1 SiteVersion = 1
+)
+
+var (
+	// ErrSiteVersionUnknown is the error returned when a SiteInput names a
+	// version this package does not implement.
+	ErrSiteVersionUnknown = errors.New("unknown site derivation version")
+
+	// ErrSiteMasterSecretEmpty is the error returned when a SiteInput has no
+	// master secret.
+	ErrSiteMasterSecretEmpty = errors.New("site derivation requires a master secret")
+
+	// ErrSiteEmpty is the error returned when a SiteInput has no site name.
+	ErrSiteEmpty = errors.New("site derivation requires a site name")
+)
+
+// SiteInput is used as input to the NewSiteReader and NewSiteGenerator
+// functions.
+type SiteInput struct {
+	MasterSecret []byte
+	Site         string      // case-insensitive, surrounding spaces are ignored
+	Login        string      // optional
+	Counter      uint32      // bump to rotate the password
+	Version      SiteVersion // SiteV1 by default
+}
+
+// NewSiteReader derives a deterministic random stream from the given inputs
+// using a memory-hard key derivation function. The same inputs always yield
+// the same stream, so passwords generated from it can be re-derived at any
+// time without storing them. Deriving is deliberately slow.
+func NewSiteReader(in *SiteInput) (io.Reader, error) {
+	if len(in.MasterSecret) == 0 {
+		return nil, ErrSiteMasterSecretEmpty
+	}
+
+	site := strings.ToLower(strings.TrimSpace(in.Site))
+	if site == "" {
+		return nil, ErrSiteEmpty
+	}
+
+	version := in.Version
+	if version == 0 {
+		version = SiteV1
+	}
+
+	switch version {
+	case SiteV1:
+		salt := siteSalt("github.com/tullo/password site v1", site, in.Login, in.Counter)
+		seed := argon2.IDKey(in.MasterSecret, salt, 3, 64*1024, 4, MinDRBGSeedSize)
+		defer wipe(seed)
+		return NewDRBG(seed, []byte("site v1"))
+	default:
+		return nil, ErrSiteVersionUnknown
+	}
+}
+
+// NewSiteGenerator creates a StatefulGenerator whose reader is derived from
+// the site inputs. The character sets are taken from i, which may be nil; its
+// Reader is ignored. Because the generator consumes its stream, create a new
+// generator for every derivation.
+func NewSiteGenerator(in *SiteInput, i *GeneratorInput) (*StatefulGenerator, error) {
+	reader, err := NewSiteReader(in)
+	if err != nil {
+		return nil, err
+	}
+
+	var gi GeneratorInput
+	if i != nil {
+		gi = *i
+	}
+	gi.Reader = reader
+
+	return NewStatefulGenerator(&gi)
+}
+
+// DeriveSitePassword derives the password for a site with the given
+// requirements, using the default character sets. See
+// StatefulGenerator.Generate for the meaning of the parameters.
+func DeriveSitePassword(in *SiteInput, length, numDigits, numSymbols int, includeUpper, allowRepeat bool) (string, error) {
+	gen, err := NewSiteGenerator(in, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return gen.Generate(length, numDigits, numSymbols, includeUpper, allowRepeat)
+}
+
+// siteSalt encodes the public derivation inputs unambiguously by prefixing
+// every field with its length.
+func siteSalt(domain, site, login string, counter uint32) []byte {
+	var b []byte
+	for _, s := range []string{domain, site, login} {
+		b = appendUint32(b, uint32(len(s)))
+		b = append(b, s...)
+	}
+	return appendUint32(b, counter)
+}
+
+// appendUint32 appends the big-endian encoding of v to b.
+func appendUint32(b []byte, v uint32) []byte {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], v)
+	return append(b, buf[:]...)
+}
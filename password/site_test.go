@@ -0,0 +1,159 @@
+package password
+
+import (
+	"encoding/hex"
+	"io"
+	"testing"
+)
+
+func TestDeriveSitePassword(t *testing.T) {
+	t.Parallel()
+
+	// These outputs are pinned: SiteV1 must keep deriving the same passwords
+	// for as long as it exists. If this test fails while
+	// TestNewSiteReader_stream passes, the mapping from random bytes to
+	// characters in random.go or generate.go changed, which needs a new
+	// SiteVersion rather than new expectations.
+	var TestCases = []struct {
+		Name     string
+		Input    SiteInput
+		Expected string
+	}{
+		{
+			Name: "site and login",
+			Input: SiteInput{
+				MasterSecret: []byte("master password"),
+				Site:         "example.com",
+				Login:        "alice@example.com",
+			},
+			Expected: "[C\\9pnJjBScEzWGq~43d",
+		},
+		{
+			Name: "counter",
+			Input: SiteInput{
+				MasterSecret: []byte("master password"),
+				Site:         "example.com",
+				Login:        "alice@example.com",
+				Counter:      1,
+			},
+			Expected: "5BJA`FlYH20XpI@kf(Nr",
+		},
+		{
+			Name: "site is normalized",
+			Input: SiteInput{
+				MasterSecret: []byte("master password"),
+				Site:         " EXAMPLE.com",
+				Login:        "alice@example.com",
+				Version:      SiteV1,
+			},
+			Expected: "[C\\9pnJjBScEzWGq~43d",
+		},
+		{
+			Name: "no login",
+			Input: SiteInput{
+				MasterSecret: []byte("another secret"),
+				Site:         "github.com",
+			},
+			Expected: "qHb4UMI1um-J3QcTx\\/A",
+		},
+	}
+
+	for _, tc := range TestCases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			res, err := DeriveSitePassword(&tc.Input, 20, 3, 3, true, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if res != tc.Expected {
+				t.Errorf("got %q, want %q", res, tc.Expected)
+			}
+		})
+	}
+}
+
+func TestNewSiteReader_stream(t *testing.T) {
+	t.Parallel()
+
+	// The stream SiteV1 derives, before StatefulGenerator maps it to
+	// characters.
+	r, err := NewSiteReader(&SiteInput{
+		MasterSecret: []byte("master password"),
+		Site:         "example.com",
+		Login:        "alice@example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := make([]byte, 32)
+	if _, err := io.ReadFull(r, b); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := hex.EncodeToString(b), "50821003196023e35ecd702c0f9c095b930469b5e0b3c28e8faee054a8a31681"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestNewSiteGenerator(t *testing.T) {
+	t.Parallel()
+
+	gen, err := NewSiteGenerator(&SiteInput{
+		MasterSecret: []byte("master password"),
+		Site:         "example.com",
+	}, &GeneratorInput{
+		Symbols: "!#",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := gen.GenerateWithPolicy(12, 1, 1, true, false, true, true, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if gen.symbols.Count(res) != 1 {
+		t.Errorf("%q should contain exactly one of the configured symbols", res)
+	}
+}
+
+func TestNewSiteReader_errors(t *testing.T) {
+	t.Parallel()
+
+	var TestCases = []struct {
+		Name  string
+		Input SiteInput
+		Err   error
+	}{
+		{
+			Name:  "no master secret",
+			Input: SiteInput{Site: "example.com"},
+			Err:   ErrSiteMasterSecretEmpty,
+		},
+		{
+			Name:  "no site",
+			Input: SiteInput{MasterSecret: []byte("secret"), Site: " "},
+			Err:   ErrSiteEmpty,
+		},
+		{
+			Name:  "unknown version",
+			Input: SiteInput{MasterSecret: []byte("secret"), Site: "example.com", Version: 99},
+			Err:   ErrSiteVersionUnknown,
+		},
+	}
+
+	for _, tc := range TestCases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := NewSiteReader(&tc.Input); err != tc.Err {
+				t.Errorf("expected %q to be %q", err, tc.Err)
+			}
+		})
+	}
+}
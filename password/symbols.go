@@ -0,0 +1,216 @@
+package password
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"net/url"
+	"strings"
+)
+
+const (
+	// ShellSafeSymbols are symbols that have no special meaning to POSIX
+	// shells, bash or zsh, even unquoted or inside heredocs. "=" is left out
+	// because zsh expands a word starting with it to the path of a command.
+	ShellSafeSymbols = "%+,-./:@_"
+
+	// URLSafeSymbols are the symbols of the RFC 3986 unreserved set, which
+	// never need percent-encoding.
+	URLSafeSymbols = "-._~"
+
+	// YAMLSafeSymbols are symbols that keep a YAML plain scalar intact
+	// wherever they appear.
+	YAMLSafeSymbols = "()+.;=^_"
+
+	// XMLSafeSymbols are the default symbols without those XML escapes:
+	// < > & ' and ".
+	XMLSafeSymbols = "~!@#$%^*()_+`-={}|[]\\:?,./"
+
+	// ConnectionStringSafeSymbols are symbols that are safe both in
+	// keyword=value connection strings and in the userinfo of URL-style
+	// DSNs.
+	ConnectionStringSafeSymbols = "!*+-._~"
+)
+
+// SymbolProfile selects a predefined list of symbols that is safe to use in a
+// particular context.
+type SymbolProfile int
+
+const (
+	// SymbolProfileDefault uses Symbols.
+	SymbolProfileDefault SymbolProfile = iota
+
+	// SymbolProfileShellSafe uses ShellSafeSymbols.
+	SymbolProfileShellSafe
+
+	// SymbolProfileURLSafe uses URLSafeSymbols.
+	SymbolProfileURLSafe
+
+	// SymbolProfileYAMLSafe uses YAMLSafeSymbols.
+	SymbolProfileYAMLSafe
+
+	// SymbolProfileXMLSafe uses XMLSafeSymbols.
+	SymbolProfileXMLSafe
+
+	// SymbolProfileConnectionStringSafe uses ConnectionStringSafeSymbols.
+	SymbolProfileConnectionStringSafe
+)
+
+// ErrSymbolProfileUnknown is the error returned for an unknown symbol
+// profile.
+var ErrSymbolProfileUnknown = errors.New("unknown symbol profile")
+
+var symbolProfiles = []struct {
+	name    string
+	symbols string
+}{
+	SymbolProfileDefault:              {"default", Symbols},
+	SymbolProfileShellSafe:            {"shell-safe", ShellSafeSymbols},
+	SymbolProfileURLSafe:              {"url-safe", URLSafeSymbols},
+	SymbolProfileYAMLSafe:             {"yaml-safe", YAMLSafeSymbols},
+	SymbolProfileXMLSafe:              {"xml-safe", XMLSafeSymbols},
+	SymbolProfileConnectionStringSafe: {"connection-string-safe", ConnectionStringSafeSymbols},
+}
+
+// ParseSymbolProfile returns the profile with the given name, such as
+// "shell-safe".
+func ParseSymbolProfile(name string) (SymbolProfile, error) {
+	for p, sp := range symbolProfiles {
+		if sp.name == name {
+			return SymbolProfile(p), nil
+		}
+	}
+	return 0, ErrSymbolProfileUnknown
+}
+
+// String returns the name of the profile.
+func (p SymbolProfile) String() string {
+	if p < 0 || int(p) >= len(symbolProfiles) {
+		return "unknown"
+	}
+	return symbolProfiles[p].name
+}
+
+// Symbols returns the symbols of the profile.
+func (p SymbolProfile) Symbols() (string, error) {
+	if p < 0 || int(p) >= len(symbolProfiles) {
+		return "", ErrSymbolProfileUnknown
+	}
+	return symbolProfiles[p].symbols, nil
+}
+
+// EncodingTarget is a context a password can be embedded in.
+type EncodingTarget int
+
+const (
+	// TargetShell is a POSIX shell word. The password is single-quoted.
+	TargetShell EncodingTarget = iota
+
+	// TargetURL is a URL component such as the userinfo of a DSN. Every
+	// character outside the RFC 3986 unreserved set is percent-encoded.
+	TargetURL
+
+	// TargetJSON is a JSON string, including the quotes.
+	TargetJSON
+
+	// TargetYAML is a double-quoted YAML scalar.
+	TargetYAML
+
+	// TargetXML is XML character data or an attribute value.
+	TargetXML
+
+	// TargetSQL is an ANSI SQL string literal. Servers that treat backslashes
+	// as escapes, such as MySQL by default, need them doubled on top.
+	TargetSQL
+
+	// TargetConnectionString is a value in a keyword=value connection string
+	// as used by ADO.NET and ODBC. The value is quoted only when needed.
+	TargetConnectionString
+)
+
+// ErrEncodingTargetUnknown is the error returned for an unknown encoding
+// target.
+var ErrEncodingTargetUnknown = errors.New("unknown encoding target")
+
+// Encode returns the representation of password that can be pasted verbatim
+// into the given target context.
+func Encode(password string, target EncodingTarget) (string, error) {
+	switch target {
+	case TargetShell:
+		return "'" + strings.Replace(password, "'", `'\''`, -1) + "'", nil
+
+	case TargetURL:
+		return strings.Replace(url.QueryEscape(password), "+", "%20", -1), nil
+
+	case TargetJSON:
+		var b bytes.Buffer
+		enc := json.NewEncoder(&b)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(password); err != nil {
+			return "", err
+		}
+		return strings.TrimSuffix(b.String(), "\n"), nil
+
+	case TargetYAML:
+		return encodeYAML(password), nil
+
+	case TargetXML:
+		var b bytes.Buffer
+		if err := xml.EscapeText(&b, []byte(password)); err != nil {
+			return "", err
+		}
+		return b.String(), nil
+
+	case TargetSQL:
+		return "'" + strings.Replace(password, "'", "''", -1) + "'", nil
+
+	case TargetConnectionString:
+		return encodeConnectionString(password), nil
+
+	default:
+		return "", ErrEncodingTargetUnknown
+	}
+}
+
+// encodeYAML returns password as a double-quoted YAML scalar.
+func encodeYAML(password string) string {
+	const hex = "0123456789ABCDEF"
+
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range password {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r < 0x20 || r == 0x7f:
+			b.WriteString(`\x`)
+			b.WriteByte(hex[r>>4])
+			b.WriteByte(hex[r&15])
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// encodeConnectionString quotes password following the ADO.NET rules: values
+// containing a semicolon, quote, brace or surrounding spaces are enclosed in
+// double quotes, or in single quotes when they contain a double quote, and
+// the enclosing quote is doubled inside.
+func encodeConnectionString(password string) string {
+	if password != "" && !strings.ContainsAny(password, `;'"{}`) && strings.TrimSpace(password) == password {
+		return password
+	}
+
+	if !strings.Contains(password, `"`) {
+		return `"` + password + `"`
+	}
+	return "'" + strings.Replace(password, "'", "''", -1) + "'"
+}
@@ -0,0 +1,245 @@
+package password
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/url"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestSymbolProfile(t *testing.T) {
+	t.Parallel()
+
+	var TestCases = []struct {
+		Profile SymbolProfile
+		Name    string
+		Unsafe  string
+	}{
+		{Profile: SymbolProfileShellSafe, Name: "shell-safe", Unsafe: "`$\\\"'!*?[]{}()<>|&;#~= "},
+		{Profile: SymbolProfileURLSafe, Name: "url-safe", Unsafe: ":/?#[]@!$&'()*+,;=% "},
+		{Profile: SymbolProfileYAMLSafe, Name: "yaml-safe", Unsafe: ":#'\"&*!|>%@`-?[]{},\\ "},
+		{Profile: SymbolProfileXMLSafe, Name: "xml-safe", Unsafe: "<>&'\""},
+		{Profile: SymbolProfileConnectionStringSafe, Name: "connection-string-safe", Unsafe: ";='\"{}@:/?#%& "},
+	}
+
+	for _, tc := range TestCases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tc.Profile.String(); got != tc.Name {
+				t.Errorf("String() = %q, want %q", got, tc.Name)
+			}
+
+			p, err := ParseSymbolProfile(tc.Name)
+			if err != nil || p != tc.Profile {
+				t.Errorf("ParseSymbolProfile(%q) = %v, %v", tc.Name, p, err)
+			}
+
+			symbols, err := tc.Profile.Symbols()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if strings.ContainsAny(symbols, tc.Unsafe) {
+				t.Errorf("%q contains unsafe symbols", symbols)
+			}
+
+			gen, err := NewStatefulGenerator(&GeneratorInput{SymbolProfile: tc.Profile})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i := 0; i < 100; i++ {
+				res := gen.MustGenerate(16, 2, 3, true, true)
+				if strings.ContainsAny(res, tc.Unsafe) {
+					t.Errorf("%q contains unsafe symbols", res)
+				}
+			}
+		})
+	}
+}
+
+func TestSymbolProfile_shellLeadingEquals(t *testing.T) {
+	t.Parallel()
+
+	gen, err := NewStatefulGenerator(&GeneratorInput{SymbolProfile: SymbolProfileShellSafe})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// zsh runs "=cmd" expansion on words starting with "=".
+	for i := 0; i < 1000; i++ {
+		if res := gen.MustGenerate(2, 0, 2, false, true); strings.HasPrefix(res, "=") {
+			t.Fatalf("%q starts with =", res)
+		}
+	}
+}
+
+func TestSymbolProfile_precedence(t *testing.T) {
+	t.Parallel()
+
+	gen, err := NewStatefulGenerator(&GeneratorInput{
+		Symbols:       "!",
+		SymbolProfile: SymbolProfileURLSafe,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := gen.symbols.String(); got != "!" {
+		t.Errorf("symbols = %q, want %q", got, "!")
+	}
+}
+
+func TestSymbolProfile_unknown(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewStatefulGenerator(&GeneratorInput{SymbolProfile: 99}); err != ErrSymbolProfileUnknown {
+		t.Errorf("expected %v to be %v", err, ErrSymbolProfileUnknown)
+	}
+
+	if _, err := ParseSymbolProfile("emoji-safe"); err != ErrSymbolProfileUnknown {
+		t.Errorf("expected %v to be %v", err, ErrSymbolProfileUnknown)
+	}
+
+	if got := SymbolProfile(-1).String(); got != "unknown" {
+		t.Errorf("String() = %q, want %q", got, "unknown")
+	}
+}
+
+func TestEncode(t *testing.T) {
+	t.Parallel()
+
+	const password = `a'b"c\d$e<f>&g;h{i} j%+/`
+
+	var TestCases = []struct {
+		Target   EncodingTarget
+		Expected string
+	}{
+		{Target: TargetShell, Expected: `'a'\''b"c\d$e<f>&g;h{i} j%+/'`},
+		{Target: TargetURL, Expected: `a%27b%22c%5Cd%24e%3Cf%3E%26g%3Bh%7Bi%7D%20j%25%2B%2F`},
+		{Target: TargetJSON, Expected: `"a'b\"c\\d$e<f>&g;h{i} j%+/"`},
+		{Target: TargetYAML, Expected: `"a'b\"c\\d$e<f>&g;h{i} j%+/"`},
+		{Target: TargetXML, Expected: `a&#39;b&#34;c\d$e&lt;f&gt;&amp;g;h{i} j%+/`},
+		{Target: TargetSQL, Expected: `'a''b"c\d$e<f>&g;h{i} j%+/'`},
+		{Target: TargetConnectionString, Expected: `'a''b"c\d$e<f>&g;h{i} j%+/'`},
+	}
+
+	for _, tc := range TestCases {
+		got, err := Encode(password, tc.Target)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got != tc.Expected {
+			t.Errorf("Encode(%d) = %s, want %s", tc.Target, got, tc.Expected)
+		}
+	}
+
+	if _, err := Encode(password, 99); err != ErrEncodingTargetUnknown {
+		t.Errorf("expected %v to be %v", err, ErrEncodingTargetUnknown)
+	}
+}
+
+func TestEncode_connectionString(t *testing.T) {
+	t.Parallel()
+
+	for password, want := range map[string]string{
+		"plain":   "plain",
+		"a;b":     `"a;b"`,
+		" a":      `" a"`,
+		"":        `""`,
+		`a"b;'c`:  `'a"b;''c'`,
+		"a=b":     "a=b",
+		"{braced": `"{braced"`,
+	} {
+		got, err := Encode(password, TargetConnectionString)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got != want {
+			t.Errorf("Encode(%q) = %s, want %s", password, got, want)
+		}
+	}
+}
+
+func TestEncode_roundTrip(t *testing.T) {
+	t.Parallel()
+
+	gen, err := NewStatefulGenerator(&GeneratorInput{
+		Symbols: Symbols + "' \t",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 100; i++ {
+		password := gen.MustGenerate(32, 4, 12, true, true)
+
+		enc, err := Encode(password, TargetURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if dec, err := url.PathUnescape(enc); err != nil || dec != password {
+			t.Errorf("URL round trip of %q gave %q, %v", password, dec, err)
+		}
+
+		enc, err = Encode(password, TargetJSON)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var dec string
+		if err := json.Unmarshal([]byte(enc), &dec); err != nil || dec != password {
+			t.Errorf("JSON round trip of %q gave %q, %v", password, dec, err)
+		}
+
+		enc, err = Encode(password, TargetXML)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var v struct {
+			Value string `xml:",chardata"`
+		}
+		if err := xml.Unmarshal([]byte("<v>"+enc+"</v>"), &v); err != nil || v.Value != password {
+			t.Errorf("XML round trip of %q gave %q, %v", password, v.Value, err)
+		}
+	}
+}
+
+func TestEncode_shell(t *testing.T) {
+	t.Parallel()
+
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not available")
+	}
+
+	gen, err := NewStatefulGenerator(&GeneratorInput{
+		Symbols: Symbols + "' \t\n",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 10; i++ {
+		password := gen.MustGenerate(32, 4, 12, true, true)
+
+		enc, err := Encode(password, TargetShell)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		out, err := exec.Command(sh, "-c", "printf '%s' "+enc).Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(out) != password {
+			t.Errorf("shell round trip of %q gave %q", password, out)
+		}
+	}
+}
@@ -0,0 +1,372 @@
+package password
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"hash/crc32"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// AlphabetBase62 is the alphabet of digits and upper and lower case
+	// letters.
+	AlphabetBase62 = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+	// AlphabetBase58 is the Bitcoin base58 alphabet, which leaves out the
+	// easily confused 0, O, I and l.
+	AlphabetBase58 = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+	// AlphabetBase32Crockford is Douglas Crockford's base32 alphabet, which
+	// leaves out I, L, O and U.
+	AlphabetBase32Crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+	// DefaultTokenEntropyBits is the amount of randomness in a token unless
+	// configured otherwise.
+	DefaultTokenEntropyBits = 160
+)
+
+// ChecksumAlgorithm is the algorithm used to compute the checksum appended to
+// a token.
+type ChecksumAlgorithm int
+
+const (
+	// ChecksumCRC32 is CRC-32 with the IEEE polynomial.
+	ChecksumCRC32 ChecksumAlgorithm = iota
+
+	// ChecksumCRC32C is CRC-32 with the Castagnoli polynomial.
+	ChecksumCRC32C
+)
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// String returns the name of the algorithm.
+func (a ChecksumAlgorithm) String() string {
+	switch a {
+	case ChecksumCRC32:
+		return "crc32"
+	case ChecksumCRC32C:
+		return "crc32c"
+	default:
+		return "unknown"
+	}
+}
+
+// sum computes the checksum of s.
+func (a ChecksumAlgorithm) sum(s string) uint32 {
+	if a == ChecksumCRC32C {
+		return crc32.Checksum([]byte(s), crc32cTable)
+	}
+	return crc32.ChecksumIEEE([]byte(s))
+}
+
+var (
+	// ErrTokenAlphabetInvalid is the error returned when a token alphabet has
+	// fewer than two characters, repeats a character or contains non-ASCII
+	// characters.
+	ErrTokenAlphabetInvalid = errors.New("token alphabet must consist of at least two distinct ASCII characters")
+
+	// ErrTokenEntropyInvalid is the error returned when the number of entropy
+	// bits of a token is negative.
+	ErrTokenEntropyInvalid = errors.New("token entropy bits must not be negative")
+
+	// ErrTokenChecksumUnknown is the error returned for an unknown checksum
+	// algorithm.
+	ErrTokenChecksumUnknown = errors.New("unknown token checksum algorithm")
+
+	// ErrTokenMalformed is the error returned when parsing a string that does
+	// not have the prefix, length or alphabet of the generator's tokens.
+	ErrTokenMalformed = errors.New("token is malformed")
+
+	// ErrTokenChecksumMismatch is the error returned when parsing a token whose
+	// checksum does not match.
+	ErrTokenChecksumMismatch = errors.New("token checksum does not match")
+)
+
+// Token is a parsed token.
+type Token struct {
+	Prefix   string
+	Random   string
+	Checksum string
+}
+
+// String returns the token as generated.
+func (t *Token) String() string {
+	return t.Prefix + t.Random + t.Checksum
+}
+
+// TokenInput is used as input to the NewTokenGenerator function.
+type TokenInput struct {
+	Prefix      string            // e.g. "acme_live_"
+	EntropyBits int               // DefaultTokenEntropyBits by default
+	Alphabet    string            // AlphabetBase62 by default
+	Checksum    ChecksumAlgorithm // ChecksumCRC32 by default
+	Reader      io.Reader         // rand.Reader by default
+}
+
+// TokenGenerator generates prefixed API tokens such as
+// "acme_live_<random><checksum>". The random part carries at least the
+// configured number of entropy bits. The checksum covers the prefix and the
+// random part and is encoded in the same alphabet with a fixed width, so
+// tokens can be verified offline and found by secret scanners.
+type TokenGenerator struct {
+	prefix      string
+	alphabet    string
+	randomLen   int
+	checksumLen int
+	checksum    ChecksumAlgorithm
+	reader      io.Reader
+	charset     *Charset
+	regexp      *regexp.Regexp
+}
+
+// NewTokenGenerator creates a new TokenGenerator from the specified
+// configuration. If no input is given, all the default values are used.
+func NewTokenGenerator(i *TokenInput) (*TokenGenerator, error) {
+	if i == nil {
+		i = new(TokenInput)
+	}
+
+	g := &TokenGenerator{
+		prefix:   i.Prefix,
+		alphabet: i.Alphabet,
+		checksum: i.Checksum,
+		reader:   i.Reader,
+	}
+
+	if g.alphabet == "" {
+		g.alphabet = AlphabetBase62
+	}
+
+	if g.reader == nil {
+		g.reader = rand.Reader
+	}
+
+	if len(g.alphabet) < 2 {
+		return nil, ErrTokenAlphabetInvalid
+	}
+
+	var seen runeSet
+	for _, r := range g.alphabet {
+		if r >= 128 || seen.contains(r) {
+			return nil, ErrTokenAlphabetInvalid
+		}
+		seen.add(r)
+	}
+
+	bits := i.EntropyBits
+	if bits < 0 {
+		return nil, ErrTokenEntropyInvalid
+	}
+	if bits == 0 {
+		bits = DefaultTokenEntropyBits
+	}
+
+	if g.checksum != ChecksumCRC32 && g.checksum != ChecksumCRC32C {
+		return nil, ErrTokenChecksumUnknown
+	}
+
+	bitsPerChar := math.Log2(float64(len(g.alphabet)))
+	g.randomLen = int(math.Ceil(float64(bits) / bitsPerChar))
+	g.checksumLen = int(math.Ceil(32 / bitsPerChar))
+	g.charset = NewCharset(g.alphabet)
+	g.regexp = regexp.MustCompile(g.pattern())
+
+	return g, nil
+}
+
+// Generate generates a new token.
+func (g *TokenGenerator) Generate() (string, error) {
+	return g.GenerateContext(context.Background())
+}
+
+// GenerateContext is the same as Generate, but stops between random draws
+// once ctx is done.
+func (g *TokenGenerator) GenerateContext(ctx context.Context) (string, error) {
+	src := newRandomSource(ctx, g.reader)
+
+	var b strings.Builder
+	b.Grow(len(g.prefix) + g.randomLen + g.checksumLen)
+	b.WriteString(g.prefix)
+	for i := 0; i < g.randomLen; i++ {
+		j, err := src.intn(len(g.alphabet))
+		if err != nil {
+			return "", progressError(readerError(err, "token"), i, g.randomLen)
+		}
+		b.WriteByte(g.alphabet[j])
+	}
+
+	s := b.String()
+	return s + g.encodeChecksum(g.checksum.sum(s)), nil
+}
+
+// MustGenerate is the same as Generate, but panics on error.
+func (g *TokenGenerator) MustGenerate() string {
+	res, err := g.Generate()
+	if err != nil {
+		panic(err)
+	}
+	return res
+}
+
+// ParseToken splits s into its parts and verifies its checksum. It returns
+// ErrTokenMalformed if s is not shaped like the generator's tokens and
+// ErrTokenChecksumMismatch if the checksum is wrong. A valid checksum only
+// shows the token was not mistyped or made up; it does not authenticate it.
+//
+// With AlphabetBase32Crockford, the part after the prefix is read the way
+// Crockford's base32 decodes: case-insensitively, with I and L read as 1 and
+// O as 0. The returned Token holds the canonical spelling.
+func (g *TokenGenerator) ParseToken(s string) (*Token, error) {
+	if len(s) != len(g.prefix)+g.randomLen+g.checksumLen || !strings.HasPrefix(s, g.prefix) {
+		return nil, ErrTokenMalformed
+	}
+
+	if g.alphabet == AlphabetBase32Crockford {
+		s = g.prefix + normalizeCrockford(s[len(g.prefix):])
+	}
+
+	for _, r := range s[len(g.prefix):] {
+		if !g.charset.Contains(r) {
+			return nil, ErrTokenMalformed
+		}
+	}
+
+	t := &Token{
+		Prefix:   g.prefix,
+		Random:   s[len(g.prefix) : len(s)-g.checksumLen],
+		Checksum: s[len(s)-g.checksumLen:],
+	}
+
+	if g.encodeChecksum(g.checksum.sum(t.Prefix+t.Random)) != t.Checksum {
+		return nil, ErrTokenChecksumMismatch
+	}
+
+	return t, nil
+}
+
+// VerifyChecksum reports whether s is a well-formed token with a valid
+// checksum.
+func (g *TokenGenerator) VerifyChecksum(s string) bool {
+	_, err := g.ParseToken(s)
+	return err == nil
+}
+
+// Pattern returns a regular expression matching the generator's tokens. It
+// is not anchored, so it can be used to configure secret scanners, but it
+// starts and ends with \b where the token's first or last character is a
+// word character, so it does not match inside a longer run of them.
+func (g *TokenGenerator) Pattern() string {
+	return g.regexp.String()
+}
+
+// Regexp returns the compiled form of Pattern.
+func (g *TokenGenerator) Regexp() *regexp.Regexp {
+	return g.regexp
+}
+
+// pattern builds the regular expression returned by Pattern.
+func (g *TokenGenerator) pattern() string {
+	var b strings.Builder
+	if g.prefix != "" && isWordChar(g.prefix[0]) || g.prefix == "" && isWordAlphabet(g.alphabet) {
+		b.WriteString(`\b`)
+	}
+	b.WriteString(regexp.QuoteMeta(g.prefix))
+	b.WriteString(charClassPattern(g.alphabet))
+	b.WriteString("{" + strconv.Itoa(g.randomLen+g.checksumLen) + "}")
+	if isWordAlphabet(g.alphabet) {
+		b.WriteString(`\b`)
+	}
+	return b.String()
+}
+
+// encodeChecksum encodes sum in the generator's alphabet, most significant
+// digit first, left-padded to the fixed checksum width.
+func (g *TokenGenerator) encodeChecksum(sum uint32) string {
+	b := make([]byte, g.checksumLen)
+	base := uint32(len(g.alphabet))
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i] = g.alphabet[sum%base]
+		sum /= base
+	}
+	return string(b)
+}
+
+// charClassPattern returns a regular expression character class matching
+// the ASCII characters of alphabet, collapsing runs into ranges.
+func charClassPattern(alphabet string) string {
+	chars := []byte(alphabet)
+	sort.Slice(chars, func(i, j int) bool { return chars[i] < chars[j] })
+
+	var b strings.Builder
+	b.WriteByte('[')
+	for i := 0; i < len(chars); {
+		j := i
+		for j+1 < len(chars) && chars[j+1] == chars[j]+1 {
+			j++
+		}
+
+		b.WriteString(classChar(chars[i]))
+		if j-i >= 2 {
+			b.WriteByte('-')
+			b.WriteString(classChar(chars[j]))
+		} else if j > i {
+			b.WriteString(classChar(chars[j]))
+		}
+		i = j + 1
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+// classChar escapes c for use inside a regular expression character class.
+func classChar(c byte) string {
+	if strings.IndexByte(`\]-^[`, c) >= 0 {
+		return `\` + string(c)
+	}
+	if c < 0x20 || c == 0x7f {
+		return `\x` + string("0123456789abcdef"[c>>4]) + string("0123456789abcdef"[c&15])
+	}
+	return string(c)
+}
+
+// isWordChar reports whether c is matched by \w in a regular expression.
+func isWordChar(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c == '_'
+}
+
+// isWordAlphabet reports whether every character of alphabet is a word
+// character.
+func isWordAlphabet(alphabet string) bool {
+	for i := 0; i < len(alphabet); i++ {
+		if !isWordChar(alphabet[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeCrockford converts s to the canonical spelling of Crockford's
+// base32: lower case letters are upper cased, I and L become 1 and O
+// becomes 0. Only ASCII letters are changed, so the length of s is kept.
+func normalizeCrockford(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if c >= 'a' && c <= 'z' {
+			c -= 'a' - 'A'
+		}
+		switch c {
+		case 'I', 'L':
+			c = '1'
+		case 'O':
+			c = '0'
+		}
+		b[i] = c
+	}
+	return string(b)
+}
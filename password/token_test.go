@@ -0,0 +1,335 @@
+package password
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestTokenGenerator_Generate(t *testing.T) {
+	t.Parallel()
+
+	var TestCases = []struct {
+		Name     string
+		Input    TokenInput
+		Length   int
+		Pattern  string
+		Alphabet string
+	}{
+		{
+			Name:     "defaults",
+			Input:    TokenInput{Prefix: "acme_live_"},
+			Length:   len("acme_live_") + 27 + 6,
+			Pattern:  `\bacme_live_[0-9A-Za-z]{33}\b`,
+			Alphabet: AlphabetBase62,
+		},
+		{
+			Name:     "base58",
+			Input:    TokenInput{Prefix: "key-", Alphabet: AlphabetBase58, EntropyBits: 128},
+			Length:   len("key-") + 22 + 6,
+			Pattern:  `\bkey-[1-9A-HJ-NP-Za-km-z]{28}\b`,
+			Alphabet: AlphabetBase58,
+		},
+		{
+			Name:     "base32 crockford",
+			Input:    TokenInput{Prefix: "t.", Alphabet: AlphabetBase32Crockford, Checksum: ChecksumCRC32C},
+			Length:   len("t.") + 32 + 7,
+			Pattern:  `\bt\.[0-9A-HJKMNP-TV-Z]{39}\b`,
+			Alphabet: AlphabetBase32Crockford,
+		},
+		{
+			Name:     "no prefix",
+			Input:    TokenInput{Alphabet: "ab", EntropyBits: 8},
+			Length:   8 + 32,
+			Pattern:  `\b[ab]{40}\b`,
+			Alphabet: "ab",
+		},
+		{
+			Name:     "punctuation",
+			Input:    TokenInput{Prefix: "-", Alphabet: "ab-", EntropyBits: 8},
+			Length:   len("-") + 6 + 21,
+			Pattern:  `-[\-ab]{27}`,
+			Alphabet: "ab-",
+		},
+	}
+
+	for _, tc := range TestCases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			gen, err := NewTokenGenerator(&tc.Input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := gen.Pattern(); got != tc.Pattern {
+				t.Errorf("Pattern() = %q, want %q", got, tc.Pattern)
+			}
+
+			for i := 0; i < 100; i++ {
+				token := gen.MustGenerate()
+				if len(token) != tc.Length {
+					t.Errorf("%q has length %d, want %d", token, len(token), tc.Length)
+				}
+
+				if !strings.HasPrefix(token, tc.Input.Prefix) {
+					t.Errorf("%q should start with %q", token, tc.Input.Prefix)
+				}
+
+				if strings.Trim(token[len(tc.Input.Prefix):], tc.Alphabet) != "" {
+					t.Errorf("%q should only use the alphabet %q", token, tc.Alphabet)
+				}
+
+				if !gen.Regexp().MatchString(token) {
+					t.Errorf("%q does not match %q", token, gen.Pattern())
+				}
+
+				if !gen.VerifyChecksum(token) {
+					t.Errorf("%q should have a valid checksum", token)
+				}
+			}
+		})
+	}
+}
+
+func TestTokenGenerator_ParseToken(t *testing.T) {
+	t.Parallel()
+
+	drbg, err := NewDRBG([]byte("token fixtures, not a real seed!"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gen, err := NewTokenGenerator(&TokenInput{
+		Prefix: "acme_live_",
+		Reader: drbg,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token := gen.MustGenerate()
+	parsed, err := gen.ParseToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if parsed.Prefix != "acme_live_" || len(parsed.Random) != 27 || len(parsed.Checksum) != 6 {
+		t.Errorf("unexpected parts %#v", parsed)
+	}
+
+	if parsed.String() != token {
+		t.Errorf("String() = %q, want %q", parsed.String(), token)
+	}
+
+	// Flip one character of the random part.
+	tampered := []byte(token)
+	if tampered[12] == 'a' {
+		tampered[12] = 'b'
+	} else {
+		tampered[12] = 'a'
+	}
+
+	var TestCases = []struct {
+		Name  string
+		Token string
+		Err   error
+	}{
+		{
+			Name:  "tampered",
+			Token: string(tampered),
+			Err:   ErrTokenChecksumMismatch,
+		},
+		{
+			Name:  "wrong prefix",
+			Token: "acme_test_" + token[len("acme_live_"):],
+			Err:   ErrTokenMalformed,
+		},
+		{
+			Name:  "too short",
+			Token: token[:len(token)-1],
+			Err:   ErrTokenMalformed,
+		},
+		{
+			Name:  "outside alphabet",
+			Token: token[:len(token)-1] + "-",
+			Err:   ErrTokenMalformed,
+		},
+	}
+
+	for _, tc := range TestCases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := gen.ParseToken(tc.Token); err != tc.Err {
+				t.Errorf("expected %v to be %v", err, tc.Err)
+			}
+		})
+	}
+}
+
+func TestTokenGenerator_Regexp(t *testing.T) {
+	t.Parallel()
+
+	gen, err := NewTokenGenerator(&TokenInput{Prefix: "acme_live_"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token := gen.MustGenerate()
+	text := fmt.Sprintf("export ACME_TOKEN=%s # do not commit", token)
+	if got := gen.Regexp().FindString(text); got != token {
+		t.Errorf("FindString() = %q, want %q", got, token)
+	}
+
+	var TestCases = []string{
+		"xacme_live_" + token[len("acme_live_"):],
+		token + "x",
+		"0" + token + "0",
+	}
+
+	for _, text := range TestCases {
+		if got := gen.Regexp().FindString(text); got != "" {
+			t.Errorf("FindString(%q) = %q, want no match", text, got)
+		}
+	}
+}
+
+func TestTokenGenerator_ParseToken_crockford(t *testing.T) {
+	t.Parallel()
+
+	drbg, err := NewDRBG([]byte("token fixtures, not a real seed!"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gen, err := NewTokenGenerator(&TokenInput{
+		Prefix:   "t_",
+		Alphabet: AlphabetBase32Crockford,
+		Reader:   drbg,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token := gen.MustGenerate()
+	misread := strings.NewReplacer("1", "l", "0", "O").Replace(token[len("t_"):])
+
+	var TestCases = []struct {
+		Name  string
+		Token string
+		Err   error
+	}{
+		{Name: "canonical", Token: token},
+		{Name: "lower case", Token: "t_" + strings.ToLower(token[len("t_"):])},
+		{Name: "misread", Token: "t_" + misread},
+		{Name: "lower case misread", Token: "t_" + strings.ToLower(misread)},
+		{Name: "prefix case", Token: "T_" + token[len("t_"):], Err: ErrTokenMalformed},
+		{Name: "U", Token: token[:len(token)-1] + "U", Err: ErrTokenMalformed},
+	}
+
+	for _, tc := range TestCases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			parsed, err := gen.ParseToken(tc.Token)
+			if err != tc.Err {
+				t.Fatalf("expected %v to be %v", err, tc.Err)
+			}
+			if err == nil && parsed.String() != token {
+				t.Errorf("String() = %q, want %q", parsed.String(), token)
+			}
+		})
+	}
+}
+
+func TestNewTokenGenerator_errors(t *testing.T) {
+	t.Parallel()
+
+	var TestCases = []struct {
+		Name  string
+		Input TokenInput
+		Err   error
+	}{
+		{
+			Name:  "single character alphabet",
+			Input: TokenInput{Alphabet: "a"},
+			Err:   ErrTokenAlphabetInvalid,
+		},
+		{
+			Name:  "repeated character",
+			Input: TokenInput{Alphabet: "abca"},
+			Err:   ErrTokenAlphabetInvalid,
+		},
+		{
+			Name:  "non-ASCII alphabet",
+			Input: TokenInput{Alphabet: "abc€"},
+			Err:   ErrTokenAlphabetInvalid,
+		},
+		{
+			Name:  "negative entropy",
+			Input: TokenInput{EntropyBits: -1},
+			Err:   ErrTokenEntropyInvalid,
+		},
+		{
+			Name:  "unknown checksum",
+			Input: TokenInput{Checksum: 42},
+			Err:   ErrTokenChecksumUnknown,
+		},
+	}
+
+	for _, tc := range TestCases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := NewTokenGenerator(&tc.Input); err != tc.Err {
+				t.Errorf("expected %v to be %v", err, tc.Err)
+			}
+		})
+	}
+}
+
+func TestCharClassPattern(t *testing.T) {
+	t.Parallel()
+
+	var TestCases = []struct {
+		Alphabet string
+		Expected string
+	}{
+		{Alphabet: "abc", Expected: "[a-c]"},
+		{Alphabet: "ab", Expected: "[ab]"},
+		{Alphabet: "ca-]", Expected: `[\-\]ac]`},
+		{Alphabet: "^\\", Expected: `[\\\^]`},
+	}
+
+	for _, tc := range TestCases {
+		if got := charClassPattern(tc.Alphabet); got != tc.Expected {
+			t.Errorf("charClassPattern(%q) = %q, want %q", tc.Alphabet, got, tc.Expected)
+		}
+	}
+}
+
+func TestNormalizeCrockford(t *testing.T) {
+	t.Parallel()
+
+	var TestCases = []struct {
+		Input    string
+		Expected string
+	}{
+		{Input: "0123ABCZ", Expected: "0123ABCZ"},
+		{Input: "abcz", Expected: "ABCZ"},
+		{Input: "IiLl", Expected: "1111"},
+		{Input: "Oo", Expected: "00"},
+		{Input: "uU-é", Expected: "UU-é"},
+	}
+
+	for _, tc := range TestCases {
+		if got := normalizeCrockford(tc.Input); got != tc.Expected {
+			t.Errorf("normalizeCrockford(%q) = %q, want %q", tc.Input, got, tc.Expected)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package password
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Built-time checks that the validators implement the interface.
+var _ Validator = (*UserContextValidator)(nil)
+
+// DefaultContextMinTokenLength is the length of the shortest token
+// UserContextValidator checks unless configured otherwise. Shorter tokens,
+// such as initials, would reject too many passwords by chance.
+const DefaultContextMinTokenLength = 3
+
+// UserContextValidator rejects passwords that contain words from the context
+// of the account, as NIST SP 800-63B recommends: the username, the local part
+// of the email address, the name of the service and of the company. Every
+// field is split into tokens at characters other than letters and digits, and
+// a password is rejected if it contains a token when both are compared
+// ignoring case and l33t substitutions, so "J0hn!" contains "john".
+type UserContextValidator struct {
+	Username string
+	Email    string // only the part before the last @ is used
+	Service  string // product or service name
+	Company  string
+	Words    []string // further context-specific words
+
+	// MinTokenLength is the length of the shortest token checked,
+	// DefaultContextMinTokenLength if zero.
+	MinTokenLength int
+}
+
+// ContextTokens splits s into tokens at characters other than letters and
+// digits, keeping those of at least minLength characters.
+func ContextTokens(s string, minLength int) []string {
+	var tokens []string
+	for _, token := range strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if utf8.RuneCountInString(token) >= minLength {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+// Validate returns a *ValidationError if password contains a token of a
+// context field. Its rule names the field: "username", "email", "service",
+// "company" or "words".
+func (v *UserContextValidator) Validate(password string) error {
+	minLength := v.MinTokenLength
+	if minLength == 0 {
+		minLength = DefaultContextMinTokenLength
+	}
+
+	email := v.Email
+	if i := strings.LastIndex(email, "@"); i >= 0 {
+		email = email[:i]
+	}
+
+	norm := normalizeLeet(password)
+	for _, field := range []struct {
+		rule, name string
+		values     []string
+	}{
+		{"username", "the username", []string{v.Username}},
+		{"email", "the email address", []string{email}},
+		{"service", "the service name", []string{v.Service}},
+		{"company", "the company name", []string{v.Company}},
+		{"words", "a blocked word", v.Words},
+	} {
+		for _, value := range field.values {
+			for _, token := range ContextTokens(value, minLength) {
+				if strings.Contains(norm, normalizeLeet(token)) {
+					return &ValidationError{Rule: field.rule, Message: "contains a part of " + field.name}
+				}
+			}
+		}
+	}
+
+	return nil
+}
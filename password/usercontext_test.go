@@ -0,0 +1,135 @@
+package password
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestContextTokens(t *testing.T) {
+	t.Parallel()
+
+	var TestCases = []struct {
+		Input    string
+		Expected []string
+	}{
+		{Input: "john.smith+billing", Expected: []string{"john", "smith", "billing"}},
+		{Input: "J. R. R. Tolkien", Expected: []string{"Tolkien"}},
+		{Input: "acme_corp-2024", Expected: []string{"acme", "corp", "2024"}},
+		{Input: "Müller & Söhne", Expected: []string{"Müller", "Söhne"}},
+		{Input: "ab", Expected: nil},
+	}
+
+	for _, tc := range TestCases {
+		if tokens := ContextTokens(tc.Input, 3); !reflect.DeepEqual(tokens, tc.Expected) {
+			t.Errorf("%q: expected %q to be %q", tc.Input, tokens, tc.Expected)
+		}
+	}
+}
+
+func TestUserContextValidator(t *testing.T) {
+	t.Parallel()
+
+	v := &UserContextValidator{
+		Username: "jsmith",
+		Email:    "john.smith@example.com",
+		Service:  "Billing API",
+		Company:  "Acme Corp",
+		Words:    []string{"hunter"},
+	}
+
+	var TestCases = []struct {
+		Password string
+		Rule     string
+	}{
+		{Password: "xx-JSMITH-42", Rule: "username"},
+		{Password: "J0hn!sGreat", Rule: "email"},
+		{Password: "5m1th&wesson", Rule: "email"},
+		{Password: "my-8ILLING-pw", Rule: "service"},
+		{Password: "corporate9", Rule: "company"},
+		{Password: "@cm3rocks", Rule: "company"},
+		{Password: "Hunt3r2", Rule: "words"},
+		{Password: "example.com"},
+		{Password: "k7#Qp9vLx2mZ"},
+		{Password: "API", Rule: "service"},
+		{Password: "A-P-I"},
+	}
+
+	for _, tc := range TestCases {
+		tc := tc
+
+		t.Run(tc.Password, func(t *testing.T) {
+			t.Parallel()
+
+			err := v.Validate(tc.Password)
+			if tc.Rule == "" {
+				if err != nil {
+					t.Errorf("expected %q to be nil", err)
+				}
+				return
+			}
+
+			var ve *ValidationError
+			if !errors.As(err, &ve) {
+				t.Fatalf("expected %v to be a *ValidationError", err)
+			}
+			if ve.Rule != tc.Rule {
+				t.Errorf("expected %q to be %q", ve.Rule, tc.Rule)
+			}
+		})
+	}
+
+	short := &UserContextValidator{Username: "al", MinTokenLength: 2}
+	if err := short.Validate("xA1x"); err == nil {
+		t.Errorf("expected short tokens to be checked")
+	}
+}
+
+func TestValidatingGenerator(t *testing.T) {
+	t.Parallel()
+
+	v := &UserContextValidator{Username: "abc"}
+
+	mock := NewMockPasswordGenerator("fine", nil).Queue("xabcx", nil).Queue("A8C", nil)
+	if res := NewValidatingGenerator(mock, v).MustGenerate(8, 0, 0, false, false); res != "fine" {
+		t.Errorf("expected %q to be %q", res, "fine")
+	}
+
+	stuck := NewValidatingGenerator(NewMockPasswordGenerator("abc", nil), v)
+	var ve *ValidationError
+	if _, err := stuck.Generate(3, 0, 0, false, true); !errors.As(err, &ve) || ve.Rule != "username" {
+		t.Errorf("expected %v to wrap the rejection", err)
+	}
+
+	failing := NewValidatingGenerator(NewMockPasswordGenerator("", ErrExceedsTotalLength), v)
+	if _, err := failing.GenerateWithPolicy(1, 2, 0, false, false, false, false, false, false); err != ErrExceedsTotalLength {
+		t.Errorf("expected %q to be %q", err, ErrExceedsTotalLength)
+	}
+
+	// Errors other than rejections end generation at once.
+	broken := errors.New("validator unavailable")
+	brokenMock := NewMockPasswordGenerator("fine", nil)
+	brokenGen := NewValidatingGenerator(brokenMock, ValidatorFunc(func(string) error { return broken }))
+	if _, err := brokenGen.Generate(4, 0, 0, false, false); err != broken {
+		t.Errorf("expected %q to be %q", err, broken)
+	}
+	if n := len(brokenMock.Calls()); n != 1 {
+		t.Errorf("expected %d calls to be 1", n)
+	}
+
+	// With four letters, about one in ten candidates contains the username.
+	gen, err := NewStatefulGenerator(&GeneratorInput{LowerLetters: "abcd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	validating := NewValidatingGenerator(gen, v)
+	for i := 0; i < 200; i++ {
+		res, err := validating.Generate(6, 0, 0, false, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := v.Validate(res); err != nil {
+			t.Errorf("%q: %v", res, err)
+		}
+	}
+}
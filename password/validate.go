@@ -0,0 +1,145 @@
+package password
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Built-time checks that the generators implement the interface.
+var _ Generator = (*ValidatingGenerator)(nil)
+
+// Validator checks candidate passwords. It returns nil for acceptable
+// passwords and, by convention, a *ValidationError otherwise.
+type Validator interface {
+	Validate(password string) error
+}
+
+// ValidatorFunc adapts an ordinary function to the Validator interface.
+type ValidatorFunc func(password string) error
+
+// Validate calls f(password).
+func (f ValidatorFunc) Validate(password string) error {
+	return f(password)
+}
+
+// Validators combines several validators into one that returns the first
+// error.
+type Validators []Validator
+
+// Validate runs every validator in order and returns the first error.
+func (vs Validators) Validate(password string) error {
+	for _, v := range vs {
+		if err := v.Validate(password); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidationError is the error returned when a password violates a rule.
+type ValidationError struct {
+	// Rule names the violated rule, such as "min_length" or
+	// "classes[1].min" for policies.
+	Rule string
+
+	// Message explains the violation without repeating the password.
+	Message string
+
+	err error
+}
+
+// Error implements the error interface.
+func (e *ValidationError) Error() string {
+	return "password rejected by " + e.Rule + ": " + e.Message
+}
+
+// Unwrap returns the underlying error, such as ErrPasswordReused for the
+// "history" rule, or nil.
+func (e *ValidationError) Unwrap() error {
+	return e.err
+}
+
+// validatingMaxAttempts is the number of candidates in a row
+// ValidatingGenerator rejects before giving up.
+const validatingMaxAttempts = 100
+
+// ValidatingGenerator is a Generator that only returns passwords its Validator
+// accepts, such as a UserContextValidator. It draws candidates from another
+// Generator until one passes and fails with an error wrapping the last
+// rejection if 100 candidates in a row were rejected, which only happens when
+// the validator rejects most passwords of the requested shape. Validator
+// errors that are not a *ValidationError, such as I/O errors, are returned at
+// once.
+type ValidatingGenerator struct {
+	gen       Generator
+	validator Validator
+}
+
+// NewValidatingGenerator creates a ValidatingGenerator drawing from gen and
+// checking with v.
+func NewValidatingGenerator(gen Generator, v Validator) *ValidatingGenerator {
+	return &ValidatingGenerator{gen: gen, validator: v}
+}
+
+// generate draws candidates with next until one passes the validator. It is
+// the retry loop of every generator that filters another one.
+func (g *ValidatingGenerator) generate(ctx context.Context, next func(context.Context) (string, error)) (string, error) {
+	var rejection error
+	for attempt := 0; attempt < validatingMaxAttempts; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return "", fmt.Errorf("%w (after %d rejected attempts)", err, attempt)
+		}
+
+		res, err := next(ctx)
+		if err != nil {
+			return "", err
+		}
+
+		rejection = g.validator.Validate(res)
+		if rejection == nil {
+			return res, nil
+		}
+		var ve *ValidationError
+		if !errors.As(rejection, &ve) {
+			return "", rejection
+		}
+	}
+	return "", fmt.Errorf("rejected %d candidates in a row: %w", validatingMaxAttempts, rejection)
+}
+
+// Generate generates a password that passes the validator. See
+// StatefulGenerator.Generate for the arguments.
+func (g *ValidatingGenerator) Generate(length, numDigits, numSymbols int, includeUpper, allowRepeat bool) (string, error) {
+	return g.GenerateContext(context.Background(), length, numDigits, numSymbols, includeUpper, allowRepeat)
+}
+
+// GenerateWithPolicy generates a password that meets the policy and passes
+// the validator. See StatefulGenerator.GenerateWithPolicy for the arguments.
+func (g *ValidatingGenerator) GenerateWithPolicy(length, numDigits, numSymbols int, includeUpper, allowRepeat, needsLower, needsUpper, needsDigit, needsSymbol bool) (string, error) {
+	return g.GenerateWithPolicyContext(context.Background(), length, numDigits, numSymbols, includeUpper, allowRepeat, needsLower, needsUpper, needsDigit, needsSymbol)
+}
+
+// MustGenerate is the same as Generate, but panics on error.
+func (g *ValidatingGenerator) MustGenerate(length, numDigits, numSymbols int, includeUpper, allowRepeat bool) string {
+	res, err := g.Generate(length, numDigits, numSymbols, includeUpper, allowRepeat)
+	if err != nil {
+		panic(err)
+	}
+	return res
+}
+
+// GenerateContext is the same as Generate, but stops once ctx is done.
+func (g *ValidatingGenerator) GenerateContext(ctx context.Context, length, numDigits, numSymbols int, includeUpper, allowRepeat bool) (string, error) {
+	return g.generate(ctx, func(ctx context.Context) (string, error) {
+		return g.gen.GenerateContext(ctx, length, numDigits, numSymbols, includeUpper, allowRepeat)
+	})
+}
+
+// GenerateWithPolicyContext is the same as GenerateWithPolicy, but stops
+// once ctx is done.
+func (g *ValidatingGenerator) GenerateWithPolicyContext(ctx context.Context, length, numDigits, numSymbols int, includeUpper, allowRepeat, needsLower, needsUpper, needsDigit, needsSymbol bool) (string, error) {
+	return g.generate(ctx, func(ctx context.Context) (string, error) {
+		return g.gen.GenerateWithPolicyContext(ctx, length, numDigits, numSymbols, includeUpper, allowRepeat, needsLower, needsUpper, needsDigit, needsSymbol)
+	})
+}
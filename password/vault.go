@@ -0,0 +1,537 @@
+package password
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrVaultPolicyUnsupported is the error returned when converting a policy
+// that a Vault password policy cannot express.
+var ErrVaultPolicyUnsupported = errors.New("policy cannot be expressed as a Vault password policy")
+
+// VaultCharsetRule is a Vault password policy rule "charset" block.
+type VaultCharsetRule struct {
+	Charset  string
+	MinChars int
+}
+
+// VaultPolicy is a HashiCorp Vault password policy:
+//
+//	length = 20
+//
+//	rule "charset" {
+//	  charset = "abcdefghijklmnopqrstuvwxyz"
+//	  min-chars = 1
+//	}
+//
+// A password is valid if it has the policy's length, only uses characters of
+// the charsets and meets every rule's min-chars, as in Vault.
+type VaultPolicy struct {
+	Length int
+	Rules  []VaultCharsetRule
+}
+
+// ParseVaultPolicy parses a Vault password policy written in HCL and checks
+// it. Errors are reported as a *PolicyError carrying the line of the
+// offending attribute or block.
+func ParseVaultPolicy(data []byte) (*VaultPolicy, error) {
+	tokens, err := scanHCL(data)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &VaultPolicy{}
+	lines := make(map[string]int)
+	p := &hclParser{tokens: tokens}
+
+	for !p.done() {
+		name := p.next()
+		if name.kind != hclIdent {
+			return nil, name.errorf("expected an attribute or block")
+		}
+
+		switch name.text {
+		case "length":
+			if _, ok := lines["length"]; ok {
+				return nil, &PolicyError{Line: name.line, Field: "length", Message: "duplicate attribute"}
+			}
+			lines["length"] = name.line
+			if v.Length, err = p.intAttribute("length"); err != nil {
+				return nil, err
+			}
+		case "rule":
+			label := p.next()
+			if label.kind != hclString {
+				return nil, label.errorf("expected a rule type")
+			}
+			if label.text != "charset" {
+				return nil, &PolicyError{Line: label.line, Field: "rule", Message: fmt.Sprintf("unknown rule type %q", label.text)}
+			}
+
+			field := fmt.Sprintf("rule[%d]", len(v.Rules))
+			lines[field] = name.line
+			rule, err := p.charsetRule(field, lines)
+			if err != nil {
+				return nil, err
+			}
+			v.Rules = append(v.Rules, rule)
+		default:
+			return nil, &PolicyError{Line: name.line, Field: name.text, Message: "unknown attribute"}
+		}
+	}
+
+	if err := v.Check(); err != nil {
+		var pe *PolicyError
+		if errors.As(err, &pe) {
+			pe.Line = lookupLine(lines, pe.Field)
+		}
+		return nil, err
+	}
+	return v, nil
+}
+
+// Check reports whether the policy is valid under Vault's rules. It returns
+// a *PolicyError describing the first problem found.
+func (v *VaultPolicy) Check() error {
+	switch {
+	case v.Length <= 0:
+		return &PolicyError{Field: "length", Message: "must be positive"}
+	case len(v.Rules) == 0:
+		return &PolicyError{Field: "rule", Message: "at least one charset rule is required"}
+	}
+
+	mins := 0
+	for i, rule := range v.Rules {
+		field := fmt.Sprintf("rule[%d]", i)
+		switch {
+		case rule.Charset == "":
+			return &PolicyError{Field: field + ".charset", Message: "must not be empty"}
+		case !utf8.ValidString(rule.Charset):
+			return &PolicyError{Field: field + ".charset", Message: "must be valid UTF-8"}
+		case rule.MinChars < 0:
+			return &PolicyError{Field: field + ".min-chars", Message: "must not be negative"}
+		}
+		mins += rule.MinChars
+	}
+
+	if mins > v.Length {
+		return &PolicyError{Field: "length", Message: fmt.Sprintf("is shorter than the %d characters required by the rules", mins)}
+	}
+	return nil
+}
+
+// MarshalHCL encodes the policy in Vault's HCL format.
+func (v *VaultPolicy) MarshalHCL() []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "length = %d\n", v.Length)
+	for _, rule := range v.Rules {
+		b.WriteString("\nrule \"charset\" {\n")
+		fmt.Fprintf(&b, "  charset = %s\n", quoteHCLString(rule.Charset))
+		if rule.MinChars > 0 {
+			fmt.Fprintf(&b, "  min-chars = %d\n", rule.MinChars)
+		}
+		b.WriteString("}\n")
+	}
+	return b.Bytes()
+}
+
+// Policy converts the Vault policy into a Policy with one class per rule,
+// named "rule[i]". The Policy accepts the same passwords.
+func (v *VaultPolicy) Policy() *Policy {
+	p := &Policy{Length: v.Length, MinLength: v.Length, MaxLength: v.Length}
+	for i, rule := range v.Rules {
+		p.Classes = append(p.Classes, ClassRule{
+			Name:  fmt.Sprintf("rule[%d]", i),
+			Chars: rule.Charset,
+			Min:   rule.MinChars,
+		})
+	}
+	return p
+}
+
+// NewVaultPolicy converts p into a Vault policy with one charset rule per
+// class, excluded characters removed. It returns an error wrapping
+// ErrVaultPolicyUnsupported if p uses rules Vault lacks: class maximums,
+// no_repeat, max_consecutive or blocklists. Vault policies have a fixed
+// length, p.GenerateLength.
+func NewVaultPolicy(p *Policy) (*VaultPolicy, error) {
+	classes, err := p.resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case p.NoRepeat:
+		return nil, fmt.Errorf("%w: no_repeat", ErrVaultPolicyUnsupported)
+	case p.MaxConsecutive > 0:
+		return nil, fmt.Errorf("%w: max_consecutive", ErrVaultPolicyUnsupported)
+	case len(p.Blocklist) > 0:
+		return nil, fmt.Errorf("%w: blocklist", ErrVaultPolicyUnsupported)
+	}
+
+	v := &VaultPolicy{Length: p.GenerateLength()}
+	for _, c := range classes {
+		if c.rule.Max > 0 {
+			return nil, fmt.Errorf("%w: %s.max", ErrVaultPolicyUnsupported, c.field)
+		}
+		if len(c.runes) == 0 {
+			continue
+		}
+		v.Rules = append(v.Rules, VaultCharsetRule{Charset: string(c.runes), MinChars: c.rule.Min})
+	}
+	return v, nil
+}
+
+// VaultGenerator generates and validates passwords for a Vault policy. It is
+// safe for concurrent use.
+type VaultGenerator struct {
+	policy  *VaultPolicy
+	chars   []rune
+	charset []*Charset
+	reader  io.Reader
+}
+
+// NewVaultGenerator creates a VaultGenerator for v, drawing randomness from
+// reader, or rand.Reader if reader is nil.
+func NewVaultGenerator(v *VaultPolicy, reader io.Reader) (*VaultGenerator, error) {
+	if err := v.Check(); err != nil {
+		return nil, err
+	}
+
+	g := &VaultGenerator{policy: v, reader: reader}
+	if g.reader == nil {
+		g.reader = rand.Reader
+	}
+
+	var seen runeSet
+	for _, rule := range v.Rules {
+		g.charset = append(g.charset, NewCharset(rule.Charset))
+		for _, r := range rule.Charset {
+			if !seen.contains(r) {
+				seen.add(r)
+				g.chars = append(g.chars, r)
+			}
+		}
+	}
+	return g, nil
+}
+
+// Policy returns the policy. It must not be modified.
+func (g *VaultGenerator) Policy() *VaultPolicy {
+	return g.policy
+}
+
+// Generate generates a password the way Vault does: every character is drawn
+// uniformly from the union of all charsets, and candidates that miss a rule's
+// min-chars are discarded, so every valid password is equally likely. Like
+// ValidatingGenerator, it fails with an error wrapping the last rejection if
+// 100 candidates in a row were rejected, which only happens when few
+// candidates meet the min-chars.
+func (g *VaultGenerator) Generate() (string, error) {
+	return g.GenerateContext(context.Background())
+}
+
+// GenerateContext is the same as Generate, but stops once ctx is done.
+func (g *VaultGenerator) GenerateContext(ctx context.Context) (string, error) {
+	src := newRandomSource(ctx, g.reader)
+	buf := make([]rune, g.policy.Length)
+	next := func(context.Context) (string, error) {
+		for i := range buf {
+			j, err := src.intn(len(g.chars))
+			if err != nil {
+				return "", progressError(readerError(err, "characters"), i, len(buf))
+			}
+			buf[i] = g.chars[j]
+		}
+		return string(buf), nil
+	}
+
+	return (&ValidatingGenerator{validator: g}).generate(ctx, next)
+}
+
+// Validate checks that password has the policy's length, only uses its
+// charsets and meets every rule's min-chars. VaultGenerator implements
+// Validator.
+func (g *VaultGenerator) Validate(password string) error {
+	if n := utf8.RuneCountInString(password); n != g.policy.Length {
+		return &ValidationError{Rule: "length", Message: fmt.Sprintf("must be exactly %d characters long", g.policy.Length)}
+	}
+
+	for i, r := range password {
+		allowed := false
+		for _, c := range g.charset {
+			allowed = allowed || c.Contains(r)
+		}
+		if !allowed {
+			return &ValidationError{Rule: "rule", Message: fmt.Sprintf("contains a character outside of the charsets at position %d", i)}
+		}
+	}
+
+	for i, c := range g.charset {
+		if min := g.policy.Rules[i].MinChars; c.Count(password) < min {
+			return &ValidationError{
+				Rule:    fmt.Sprintf("rule[%d].min-chars", i),
+				Message: fmt.Sprintf("needs at least %d characters of %q", min, c.String()),
+			}
+		}
+	}
+	return nil
+}
+
+// quoteHCLString quotes s as an HCL string, escaping template sequences.
+func quoteHCLString(s string) string {
+	s = strings.Replace(s, "${", "$${", -1)
+	s = strings.Replace(s, "%{", "%%{", -1)
+	return quotePolicyString(s)
+}
+
+// hclTokenKind is the kind of an HCL token.
+type hclTokenKind int
+
+const (
+	hclIdent hclTokenKind = iota
+	hclString
+	hclNumber
+	hclPunct
+)
+
+// hclToken is a token of the HCL subset used by Vault password policies.
+type hclToken struct {
+	kind hclTokenKind
+	text string
+	line int
+}
+
+func (t hclToken) errorf(format string, args ...interface{}) error {
+	if t.kind == hclPunct && t.text == "" {
+		return syntaxError(t.line, "unexpected end of input, "+format, args...)
+	}
+	return syntaxError(t.line, "unexpected %q, "+format, append([]interface{}{t.text}, args...)...)
+}
+
+// scanHCL splits data into tokens, skipping whitespace and #, // and /* */
+// comments.
+func scanHCL(data []byte) ([]hclToken, error) {
+	if !utf8.Valid(data) {
+		return nil, syntaxError(0, "policy is not valid UTF-8")
+	}
+
+	s := string(data)
+	line := 1
+	var tokens []hclToken
+
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == '#' || strings.HasPrefix(s[i:], "//"):
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(s[i:], "/*"):
+			end := strings.Index(s[i+2:], "*/")
+			if end < 0 {
+				return nil, syntaxError(line, "unterminated comment")
+			}
+			line += strings.Count(s[i:i+2+end], "\n")
+			i += end + 4
+		case c == '"':
+			end := i + 1
+			for ; end < len(s) && s[end] != '"' && s[end] != '\n'; end++ {
+				if s[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(s) || s[end] != '"' {
+				return nil, syntaxError(line, "unterminated string")
+			}
+			text, err := unquoteHCL(s[i+1 : end])
+			if err != nil {
+				return nil, syntaxError(line, "invalid string %s: %v", s[i:end+1], err)
+			}
+			tokens = append(tokens, hclToken{kind: hclString, text: text, line: line})
+			i = end + 1
+		case c == '=' || c == '{' || c == '}' || c == ',':
+			tokens = append(tokens, hclToken{kind: hclPunct, text: s[i : i+1], line: line})
+			i++
+		case c == '-' || c >= '0' && c <= '9':
+			end := i + 1
+			for end < len(s) && s[end] >= '0' && s[end] <= '9' {
+				end++
+			}
+			tokens = append(tokens, hclToken{kind: hclNumber, text: s[i:end], line: line})
+			i = end
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			end := i + 1
+			for end < len(s) && (s[end] == '_' || s[end] == '-' || s[end] >= 'a' && s[end] <= 'z' ||
+				s[end] >= 'A' && s[end] <= 'Z' || s[end] >= '0' && s[end] <= '9') {
+				end++
+			}
+			tokens = append(tokens, hclToken{kind: hclIdent, text: s[i:end], line: line})
+			i = end
+		default:
+			r, _ := utf8.DecodeRuneInString(s[i:])
+			return nil, syntaxError(line, "unexpected %q", r)
+		}
+	}
+
+	return append(tokens, hclToken{kind: hclPunct, line: line}), nil
+}
+
+// unquoteHCL decodes the body of an HCL quoted string. It supports the
+// escapes of the HCL specification, \n, \r, \t, \", \\, \uNNNN and
+// \UNNNNNNNN, and the $${ and %%{ escapes of template sequences. Other
+// escapes and template interpolations or directives, which a password policy
+// cannot evaluate, are errors.
+func unquoteHCL(s string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		switch {
+		case strings.HasPrefix(s[i:], "$${") || strings.HasPrefix(s[i:], "%%{"):
+			b.WriteString(s[i+1 : i+3])
+			i += 3
+		case strings.HasPrefix(s[i:], "${") || strings.HasPrefix(s[i:], "%{"):
+			return "", fmt.Errorf("template sequence %q is not supported, escape it as %q", s[i:i+2], s[i:i+1]+s[i:i+2])
+		case s[i] == '\\':
+			if i+1 == len(s) {
+				return "", errors.New("unterminated escape")
+			}
+			switch c := s[i+1]; c {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case '"', '\\':
+				b.WriteByte(c)
+			case 'u', 'U':
+				size := 4
+				if c == 'U' {
+					size = 8
+				}
+				if i+2+size > len(s) {
+					return "", fmt.Errorf("invalid escape %q", s[i:])
+				}
+				n, err := strconv.ParseUint(s[i+2:i+2+size], 16, 32)
+				if err != nil || !utf8.ValidRune(rune(n)) {
+					return "", fmt.Errorf("invalid escape %q", s[i:i+2+size])
+				}
+				b.WriteRune(rune(n))
+				i += size
+			default:
+				return "", fmt.Errorf("invalid escape %q", s[i:i+2])
+			}
+			i += 2
+		default:
+			b.WriteByte(s[i])
+			i++
+		}
+	}
+	return b.String(), nil
+}
+
+// hclParser parses a token stream ending in an empty punctuation token.
+type hclParser struct {
+	tokens []hclToken
+	pos    int
+}
+
+func (p *hclParser) done() bool {
+	return p.pos == len(p.tokens)-1
+}
+
+func (p *hclParser) next() hclToken {
+	t := p.tokens[p.pos]
+	if !p.done() {
+		p.pos++
+	}
+	return t
+}
+
+func (p *hclParser) expect(punct string) error {
+	if t := p.next(); t.kind != hclPunct || t.text != punct {
+		return t.errorf("expected %q", punct)
+	}
+	return nil
+}
+
+// value parses the value of an attribute whose name has been read.
+func (p *hclParser) value() (hclToken, error) {
+	if err := p.expect("="); err != nil {
+		return hclToken{}, err
+	}
+	t := p.next()
+	if t.kind != hclString && t.kind != hclNumber {
+		return t, t.errorf("expected a value")
+	}
+	return t, nil
+}
+
+func (p *hclParser) intAttribute(field string) (int, error) {
+	t, err := p.value()
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(t.text)
+	if t.kind != hclNumber || err != nil {
+		return 0, &PolicyError{Line: t.line, Field: field, Message: "must be an integer"}
+	}
+	return n, nil
+}
+
+// charsetRule parses the body of a rule "charset" block.
+func (p *hclParser) charsetRule(field string, lines map[string]int) (VaultCharsetRule, error) {
+	var rule VaultCharsetRule
+	if err := p.expect("{"); err != nil {
+		return rule, err
+	}
+
+	for {
+		name := p.next()
+		if name.kind == hclPunct && name.text == "}" {
+			return rule, nil
+		}
+		if name.kind != hclIdent {
+			return rule, name.errorf("expected an attribute")
+		}
+
+		sub := field + "." + name.text
+		lines[sub] = name.line
+		switch name.text {
+		case "charset":
+			t, err := p.value()
+			if err != nil {
+				return rule, err
+			}
+			if t.kind != hclString {
+				return rule, &PolicyError{Line: t.line, Field: sub, Message: "must be a string"}
+			}
+			rule.Charset = t.text
+		case "min-chars":
+			n, err := p.intAttribute(sub)
+			if err != nil {
+				return rule, err
+			}
+			rule.MinChars = n
+		default:
+			return rule, &PolicyError{Line: name.line, Field: sub, Message: "unknown attribute"}
+		}
+
+		// HCL 1 allows commas between attributes.
+		if t := p.tokens[p.pos]; t.kind == hclPunct && t.text == "," {
+			p.next()
+		}
+	}
+}
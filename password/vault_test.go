@@ -0,0 +1,388 @@
+package password
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testVaultPolicy is the example policy of Vault's documentation.
+const testVaultPolicy = `length = 20
+
+rule "charset" {
+  charset = "abcdefghijklmnopqrstuvwxyz"
+  min-chars = 1
+}
+
+rule "charset" {
+  charset = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+  min-chars = 1
+}
+
+rule "charset" {
+  charset = "0123456789"
+  min-chars = 1
+}
+
+rule "charset" {
+  charset = "!@#$%^&*"
+  min-chars = 1
+}
+`
+
+func TestParseVaultPolicy(t *testing.T) {
+	t.Parallel()
+
+	var TestCases = []struct {
+		Name     string
+		HCL      string
+		Expected *VaultPolicy
+	}{
+		{
+			Name: "documentation",
+			HCL:  testVaultPolicy,
+			Expected: &VaultPolicy{
+				Length: 20,
+				Rules: []VaultCharsetRule{
+					{Charset: LowerLetters, MinChars: 1},
+					{Charset: UpperLetters, MinChars: 1},
+					{Charset: Digits, MinChars: 1},
+					{Charset: "!@#$%^&*", MinChars: 1},
+				},
+			},
+		},
+		{
+			Name: "comments and commas",
+			HCL: `# Database credentials
+length = 12 // fixed by the driver
+/* lowercase
+   only */
+rule "charset" { charset = "abc\"\\$${x}", min-chars = 2 }`,
+			Expected: &VaultPolicy{
+				Length: 12,
+				Rules:  []VaultCharsetRule{{Charset: `abc"\${x}`, MinChars: 2}},
+			},
+		},
+		{
+			Name: "escapes",
+			HCL:  `length = 8` + "\n" + `rule "charset" { charset = "\u00e4\U0001F511\t%%{y}\n" }`,
+			Expected: &VaultPolicy{
+				Length: 8,
+				Rules:  []VaultCharsetRule{{Charset: "ä🔑\t%{y}\n"}},
+			},
+		},
+	}
+
+	for _, tc := range TestCases {
+		tc := tc
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			v, err := ParseVaultPolicy([]byte(tc.HCL))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(v, tc.Expected) {
+				t.Errorf("expected %+v to be %+v", v, tc.Expected)
+			}
+		})
+	}
+}
+
+func TestParseVaultPolicy_errors(t *testing.T) {
+	t.Parallel()
+
+	var TestCases = []struct {
+		Name     string
+		HCL      string
+		Expected string
+	}{
+		{
+			Name:     "unknown attribute",
+			HCL:      "length = 20\nsize = 3\n",
+			Expected: "line 2: size: unknown attribute",
+		},
+		{
+			Name:     "unknown rule",
+			HCL:      "length = 20\n\nrule \"regex\" {\n}\n",
+			Expected: `line 3: rule: unknown rule type "regex"`,
+		},
+		{
+			Name:     "string length",
+			HCL:      "length = \"20\"\n",
+			Expected: "line 1: length: must be an integer",
+		},
+		{
+			Name:     "missing brace",
+			HCL:      "length = 20\nrule \"charset\" {\n  charset = \"abc\"\n",
+			Expected: "line 4: syntax error: unexpected end of input, expected an attribute",
+		},
+		{
+			Name:     "empty charset",
+			HCL:      "length = 20\nrule \"charset\" {\n  min-chars = 1\n}\n",
+			Expected: "line 2: rule[0].charset: must not be empty",
+		},
+		{
+			Name:     "negative min-chars",
+			HCL:      "length = 20\nrule \"charset\" {\n  charset = \"abc\"\n  min-chars = -1\n}\n",
+			Expected: "line 4: rule[0].min-chars: must not be negative",
+		},
+		{
+			Name:     "too short",
+			HCL:      "length = 1\nrule \"charset\" {\n  charset = \"abc\"\n  min-chars = 2\n}\n",
+			Expected: "line 1: length: is shorter than the 2 characters required by the rules",
+		},
+		{
+			Name:     "go escape",
+			HCL:      "length = 20\nrule \"charset\" {\n  charset = \"\\x41\"\n}\n",
+			Expected: `line 3: syntax error: invalid string "\x41": invalid escape "\\x"`,
+		},
+		{
+			Name:     "short unicode escape",
+			HCL:      "length = 20\nrule \"charset\" {\n  charset = \"\\u12\"\n}\n",
+			Expected: `line 3: syntax error: invalid string "\u12": invalid escape "\\u12"`,
+		},
+		{
+			Name:     "surrogate escape",
+			HCL:      "length = 20\nrule \"charset\" {\n  charset = \"\\ud800\"\n}\n",
+			Expected: `line 3: syntax error: invalid string "\ud800": invalid escape "\\ud800"`,
+		},
+		{
+			Name:     "template",
+			HCL:      "length = 20\nrule \"charset\" {\n  charset = \"${var.chars}\"\n}\n",
+			Expected: `line 3: syntax error: invalid string "${var.chars}": template sequence "${" is not supported, escape it as "$${"`,
+		},
+		{
+			Name:     "unterminated comment",
+			HCL:      "length = 20\n/* rules",
+			Expected: "line 2: syntax error: unterminated comment",
+		},
+	}
+
+	for _, tc := range TestCases {
+		tc := tc
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := ParseVaultPolicy([]byte(tc.HCL))
+			var pe *PolicyError
+			if !errors.As(err, &pe) {
+				t.Fatalf("expected %v to be a *PolicyError", err)
+			}
+			if err.Error() != tc.Expected {
+				t.Errorf("expected %q to be %q", err, tc.Expected)
+			}
+		})
+	}
+}
+
+func TestVaultPolicy_MarshalHCL(t *testing.T) {
+	t.Parallel()
+
+	v := &VaultPolicy{
+		Length: 16,
+		Rules: []VaultCharsetRule{
+			{Charset: LowerLetters, MinChars: 1},
+			{Charset: `${}%{}"\`},
+		},
+	}
+
+	data := v.MarshalHCL()
+	if !strings.Contains(string(data), `charset = "$${}%%{}\"\\"`) {
+		t.Errorf("expected template sequences to be escaped in:\n%s", data)
+	}
+
+	res, err := ParseVaultPolicy(data)
+	if err != nil {
+		t.Fatalf("%v in:\n%s", err, data)
+	}
+	if !reflect.DeepEqual(res, v) {
+		t.Errorf("expected %+v to be %+v", res, v)
+	}
+}
+
+func TestNewVaultPolicy(t *testing.T) {
+	t.Parallel()
+
+	p := &Policy{
+		MinLength: 24,
+		Exclude:   "0O",
+		Classes: []ClassRule{
+			{Name: "upper", Min: 2},
+			{Name: "digit", Min: 1},
+		},
+	}
+
+	v, err := NewVaultPolicy(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &VaultPolicy{
+		Length: 24,
+		Rules: []VaultCharsetRule{
+			{Charset: strings.Replace(UpperLetters, "O", "", 1), MinChars: 2},
+			{Charset: Digits[1:], MinChars: 1},
+		},
+	}
+	if !reflect.DeepEqual(v, expected) {
+		t.Errorf("expected %+v to be %+v", v, expected)
+	}
+
+	for _, p := range []*Policy{
+		{Length: 8, NoRepeat: true, Classes: []ClassRule{{Name: "lower"}}},
+		{MaxConsecutive: 2, Classes: []ClassRule{{Name: "lower"}}},
+		{Blocklist: []string{"abc"}, Classes: []ClassRule{{Name: "lower"}}},
+		{Classes: []ClassRule{{Name: "lower"}, {Name: "digit", Max: 2}}},
+	} {
+		if _, err := NewVaultPolicy(p); !errors.Is(err, ErrVaultPolicyUnsupported) {
+			t.Errorf("expected %q to be %q", err, ErrVaultPolicyUnsupported)
+		}
+	}
+}
+
+func TestVaultGenerator_Generate(t *testing.T) {
+	t.Parallel()
+
+	v, err := ParseVaultPolicy([]byte(testVaultPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gen, err := NewVaultGenerator(v, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := v.Policy()
+
+	for i := 0; i < 200; i++ {
+		res, err := gen.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := gen.Validate(res); err != nil {
+			t.Errorf("%q: %v", res, err)
+		}
+		if err := p.Validate(res); err != nil {
+			t.Errorf("%q does not satisfy the converted policy: %v", res, err)
+		}
+	}
+}
+
+func TestVaultGenerator_Validate(t *testing.T) {
+	t.Parallel()
+
+	gen, err := NewVaultGenerator(&VaultPolicy{
+		Length: 4,
+		Rules: []VaultCharsetRule{
+			{Charset: "ab"},
+			{Charset: "01", MinChars: 2},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var TestCases = []struct {
+		Password string
+		Rule     string
+	}{
+		{Password: "a0b1"},
+		{Password: "a0b", Rule: "length"},
+		{Password: "a0bc", Rule: "rule"},
+		{Password: "a0bb", Rule: "rule[1].min-chars"},
+	}
+
+	for _, tc := range TestCases {
+		err := gen.Validate(tc.Password)
+		if tc.Rule == "" {
+			if err != nil {
+				t.Errorf("expected %q to be valid: %v", tc.Password, err)
+			}
+			continue
+		}
+
+		var ve *ValidationError
+		if !errors.As(err, &ve) || ve.Rule != tc.Rule {
+			t.Errorf("expected %v for %q to violate %q", err, tc.Password, tc.Rule)
+		}
+	}
+}
+
+func TestVaultGenerator_Generate_distribution(t *testing.T) {
+	t.Parallel()
+
+	drbg, err := NewDRBG([]byte("vault distribution, not a seed!!"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gen, err := NewVaultGenerator(&VaultPolicy{Length: 2, Rules: []VaultCharsetRule{
+		{Charset: "ab", MinChars: 1},
+		{Charset: "01"},
+	}}, drbg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every one of the 12 passwords with at least one letter is equally
+	// likely. Placing the letter first and filling from the union instead
+	// makes "aa", "ab", "ba" and "bb" half as likely again.
+	const samples = 12000
+	counts := make(map[string]int)
+	for i := 0; i < samples; i++ {
+		res, err := gen.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts[res]++
+	}
+
+	if len(counts) != 12 {
+		t.Errorf("expected %d passwords to be 12: %v", len(counts), counts)
+	}
+	for res, n := range counts {
+		if n < 850 || n > 1150 {
+			t.Errorf("%q: expected %d to be about %d", res, n, samples/12)
+		}
+	}
+}
+
+func TestVaultGenerator_Generate_demanding(t *testing.T) {
+	t.Parallel()
+
+	// Drawing whole candidates from the union practically never produces one
+	// made of a single letter.
+	gen, err := NewVaultGenerator(&VaultPolicy{Length: 30, Rules: []VaultCharsetRule{
+		{Charset: "a", MinChars: 30},
+		{Charset: LowerLetters[1:]},
+	}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ve *ValidationError
+	if _, err := gen.Generate(); !errors.As(err, &ve) || ve.Rule != "rule[0].min-chars" {
+		t.Errorf("expected %v to wrap the rejection", err)
+	}
+}
+
+func TestVaultGenerator_GenerateContext(t *testing.T) {
+	t.Parallel()
+
+	gen, err := NewVaultGenerator(&VaultPolicy{Length: 12, Rules: []VaultCharsetRule{{Charset: Digits}}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := gen.GenerateContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %q to be %q", err, context.Canceled)
+	}
+}